@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rocketblend/rocketblend/pkg/container"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
 	"github.com/rocketblend/rocketblend/pkg/logger"
 	"github.com/rocketblend/rocketblend/pkg/types"
 	"github.com/spf13/cobra"
@@ -19,6 +21,8 @@ type (
 		WorkingDirectory string
 		Verbose          bool
 		Level            string
+		Offline          bool
+		MaxRate          string
 	}
 
 	commandOpts struct {
@@ -27,13 +31,6 @@ type (
 		Global      *global
 	}
 
-	containerOpts struct {
-		AppName     string
-		Development bool
-		Level       string
-		Verbose     bool
-	}
-
 	RootCommandOpts struct {
 		Name    string
 		Version string
@@ -68,6 +65,12 @@ Documentation is available at https://docs.rocketblend.io/`,
 
 			global.WorkingDirectory = path
 
+			if global.MaxRate != "" {
+				if _, err := helpers.ParseSize(global.MaxRate); err != nil {
+					return fmt.Errorf("invalid --max-rate: %w", err)
+				}
+			}
+
 			return nil
 		},
 		SilenceUsage:  true,
@@ -84,22 +87,36 @@ Documentation is available at https://docs.rocketblend.io/`,
 		newRunCommand(commandOpts),
 		newRenderCommand(commandOpts),
 		newResolveCommand(commandOpts),
+		newDevicesCommand(commandOpts),
 		newDescribeCommand(commandOpts),
 		newInsertCommand(commandOpts),
+		newPruneCommand(commandOpts),
 	)
 
 	cc.PersistentFlags().StringVarP(&global.WorkingDirectory, "directory", "d", ".", "working directory for the command")
 	cc.PersistentFlags().BoolVarP(&global.Verbose, "verbose", "v", false, "enable verbose logging")
 	cc.PersistentFlags().StringVarP(&global.Level, "log-level", "l", "info", "log level (debug, info, warn, error)")
+	cc.PersistentFlags().BoolVar(&global.Offline, "offline", false, "never access the network, only use locally available packages")
+	cc.PersistentFlags().StringVar(&global.MaxRate, "max-rate", "", "cap the combined download speed in bytes per second, such as 10MB or 512KiB")
 
 	return cc
 }
 
-func getContainer(opts containerOpts) (types.Container, error) {
+// getContainer creates the container for a command, configured by the global flags shared by every command.
+func getContainer(opts commandOpts) (types.Container, error) {
+	// Without verbose logging the progress UI is shown, which needs frequent updates to move smoothly.
+	progressInterval := 5 * time.Second
+	if !opts.Global.Verbose {
+		progressInterval = 250 * time.Millisecond
+	}
+
 	container, err := container.New(
-		container.WithLogger(getLogger(opts.Level, opts.Verbose)),
+		container.WithLogger(getLogger(opts.Global.Level, opts.Global.Verbose)),
+		container.WithProgressInterval(progressInterval),
 		container.WithApplicationName(opts.AppName),
 		container.WithDevelopmentMode(opts.Development),
+		container.WithOffline(opts.Global.Offline),
+		container.WithMaxDownloadRate(opts.Global.MaxRate),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container: %w", err)
@@ -47,12 +47,7 @@ Use "::" for nested keys (e.g., "example::key").`,
 // manageConfig performs the configuration management: it sets a new value if provided,
 // or retrieves the current value(s) if no value is provided.
 func manageConfig(opts configOpts) error {
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
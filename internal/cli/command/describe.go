@@ -13,11 +13,19 @@ type (
 	describePackageOpts struct {
 		commandOpts
 		Reference string
+		Verify    bool
+	}
+
+	verifiedPackage struct {
+		Package *types.Package `json:"package"`
+		Signer  *types.Signer  `json:"signer"`
 	}
 )
 
 // newDescribeCommand creates a new cobra.Command that fetches a package definition.
 func newDescribeCommand(opts commandOpts) *cobra.Command {
+	var verify bool
+
 	cc := &cobra.Command{
 		Use:   "describe [reference]",
 		Short: "Fetches a package definition",
@@ -27,6 +35,7 @@ func newDescribeCommand(opts commandOpts) *cobra.Command {
 			if err := describePackage(cmd.Context(), describePackageOpts{
 				commandOpts: opts,
 				Reference:   args[0],
+				Verify:      verify,
 			}); err != nil {
 				return fmt.Errorf("failed to describe package: %w", err)
 			}
@@ -35,6 +44,7 @@ func newDescribeCommand(opts commandOpts) *cobra.Command {
 		},
 	}
 
+	cc.Flags().BoolVar(&verify, "verify", false, "require the package definition to be signed by a trusted key and show the signer")
 	return cc
 }
 
@@ -44,12 +54,7 @@ func describePackage(ctx context.Context, opts describePackageOpts) error {
 		return err
 	}
 
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -66,7 +71,20 @@ func describePackage(ctx context.Context, opts describePackageOpts) error {
 		return err
 	}
 
-	display, err := displayJSON(packages.Packs[ref])
+	var output interface{} = packages.Packs[ref]
+	if opts.Verify {
+		signer, ok := packages.Signers[ref]
+		if !ok {
+			return fmt.Errorf("package %s was not verified: local packages are never signed and trusted keys must be configured", ref.String())
+		}
+
+		output = &verifiedPackage{
+			Package: packages.Packs[ref],
+			Signer:  signer,
+		}
+	}
+
+	display, err := displayJSON(output)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type (
+	devicesOpts struct {
+		commandOpts
+	}
+)
+
+// newDevicesCommand creates a new cobra.Command that outputs the compute devices the project's build can render on.
+func newDevicesCommand(opts commandOpts) *cobra.Command {
+	cc := &cobra.Command{
+		Use:   "devices",
+		Short: "Lists the devices the build can render on",
+		Long:  `Asks the project's build which compute backends Cycles was compiled with, and prints them with their devices as JSON.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := listDevices(cmd.Context(), devicesOpts{
+				commandOpts: opts,
+			}); err != nil {
+				return fmt.Errorf("failed to list devices: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	return cc
+}
+
+func listDevices(ctx context.Context, opts devicesOpts) error {
+	container, err := getContainer(opts.commandOpts)
+	if err != nil {
+		return err
+	}
+
+	driver, err := container.GetDriver()
+	if err != nil {
+		return err
+	}
+
+	profiles, err := driver.LoadProfiles(ctx, &types.LoadProfilesOpts{
+		Paths: []string{opts.Global.WorkingDirectory},
+	})
+	if err != nil {
+		return err
+	}
+
+	resolve, err := driver.ResolveProfiles(ctx, &types.ResolveProfilesOpts{
+		Profiles: profiles.Profiles,
+	})
+	if err != nil {
+		return err
+	}
+
+	build := (&types.BlendFile{Dependencies: resolve.Installations[0]}).Build()
+	if build == nil {
+		return types.ErrMissingBlenderBuild
+	}
+
+	blend, err := container.GetBlender()
+	if err != nil {
+		return err
+	}
+
+	result, err := blend.Devices(ctx, &types.DevicesOpts{
+		Build: build,
+	})
+	if err != nil {
+		return err
+	}
+
+	display, err := displayJSON(result)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(display)
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/rocketblend/rocketblend/internal/cli/ui"
+	"github.com/rocketblend/rocketblend/pkg/types"
 )
 
 func findFilePathForExt(dir string, ext string) (string, error) {
@@ -32,6 +33,33 @@ func runWithProgressUI(ctx context.Context, verbose bool, work func(ctx context.
 	return ui.Run(ctx, work)
 }
 
+// installProfiles installs the profiles, forwarding the progress of every installation that is fetched to the
+// progress UI when there is one.
+func installProfiles(ctx context.Context, driver types.Driver, opts *types.InstallProfilesOpts, eventChan chan<- ui.ProgressEvent) error {
+	if eventChan == nil {
+		return driver.InstallProfiles(ctx, opts)
+	}
+
+	progress := make(chan types.InstallationProgress)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range progress {
+			select {
+			case eventChan <- ui.DownloadEvent{Progress: p}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	opts.Progress = progress
+	err := driver.InstallProfiles(ctx, opts)
+	close(progress)
+	<-done
+
+	return err
+}
+
 func displayJSON(v any) (string, error) {
 	display, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -64,12 +64,7 @@ func insertPackage(ctx context.Context, opts insertPackageOpts) error {
 		return err
 	}
 
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/rocketblend/rocketblend/internal/cli/ui"
 	"github.com/rocketblend/rocketblend/pkg/reference"
@@ -13,18 +14,27 @@ type installPackageOpts struct {
 	commandOpts
 	Reference    string
 	Pull         bool
+	Frozen       bool
 	ProgressChan chan<- ui.ProgressEvent
 }
 
 // newInstallCommand creates a new cobra command for installing project dependencies.
 func newInstallCommand(opts commandOpts) *cobra.Command {
 	var update bool
+	var frozen bool
 
 	cc := &cobra.Command{
 		Use:   "install [reference]",
 		Short: "Installs project dependencies",
-		Long:  `Adds the specified dependencies to the current project and installs them. If no reference is provided, all dependencies in the project are installed instead.`,
+		Long:  `Adds the specified dependencies to the current project and installs them. If no reference is provided, all dependencies in the project are installed instead. A reference may end in a version range, such as blender@^4.2, to install the highest matching version.`,
 		Args:  cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if frozen && (len(args) > 0 || update) {
+				return fmt.Errorf("dependencies cannot be added or updated when frozen")
+			}
+
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ref := ""
 			if len(args) > 0 {
@@ -39,6 +49,7 @@ func newInstallCommand(opts commandOpts) *cobra.Command {
 						commandOpts:  opts,
 						Reference:    ref,
 						Pull:         update,
+						Frozen:       frozen,
 						ProgressChan: eventChan,
 					})
 				})
@@ -46,6 +57,7 @@ func newInstallCommand(opts commandOpts) *cobra.Command {
 	}
 
 	cc.Flags().BoolVarP(&update, "update", "u", false, "updates to the latest package definitions before installing")
+	cc.Flags().BoolVar(&frozen, "frozen", false, "install exactly what the profile lock records, failing if it is missing or out of date")
 	return cc
 }
 
@@ -58,12 +70,7 @@ func installPackage(ctx context.Context, opts installPackageOpts) error {
 	}
 
 	emit(ui.StepEvent{Message: "Initialising..."})
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -103,21 +110,29 @@ func installPackage(ctx context.Context, opts installPackageOpts) error {
 		})
 	}
 
-	emit(ui.StepEvent{Message: "Tidying profiles..."})
-	if err := driver.TidyProfiles(ctx, &types.TidyProfilesOpts{
-		Profiles: profiles.Profiles,
-		Fetch:    opts.Pull,
-	}); err != nil {
-		return err
+	if !opts.Frozen {
+		emit(ui.StepEvent{Message: "Tidying profiles..."})
+		if err := driver.TidyProfiles(ctx, &types.TidyProfilesOpts{
+			Profiles: profiles.Profiles,
+			Fetch:    opts.Pull,
+		}); err != nil {
+			return err
+		}
 	}
 
 	emit(ui.StepEvent{Message: "Installing dependencies..."})
-	if err := driver.InstallProfiles(ctx, &types.InstallProfilesOpts{
+	if err := installProfiles(ctx, driver, &types.InstallProfilesOpts{
 		Profiles: profiles.Profiles,
-	}); err != nil {
+		Frozen:   opts.Frozen,
+	}, opts.ProgressChan); err != nil {
 		return err
 	}
 
+	if opts.Frozen {
+		emit(ui.CompletionEvent{Message: "Dependencies installed!"})
+		return nil
+	}
+
 	emit(ui.StepEvent{Message: "Saving profiles..."})
 	if err := driver.SaveProfiles(ctx, &types.SaveProfilesOpts{
 		Profiles: map[string]*types.Profile{
@@ -72,12 +72,7 @@ func createProject(ctx context.Context, opts createProjectOpts) error {
 	}
 
 	emit(ui.StepEvent{Message: "Initialising..."})
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -120,9 +115,9 @@ func createProject(ctx context.Context, opts createProjectOpts) error {
 	}
 
 	emit(ui.StepEvent{Message: "Installing dependencies..."})
-	if err := driver.InstallProfiles(ctx, &types.InstallProfilesOpts{
+	if err := installProfiles(ctx, driver, &types.InstallProfilesOpts{
 		Profiles: profiles.Profiles,
-	}); err != nil {
+	}, opts.ProgressChan); err != nil {
 		return err
 	}
 
@@ -0,0 +1,166 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type (
+	pruneOpts struct {
+		commandOpts
+		Paths  []string
+		DryRun bool
+	}
+)
+
+// newPruneCommand creates a new cobra.Command that removes installations and packages no project uses.
+func newPruneCommand(opts commandOpts) *cobra.Command {
+	var dryRun bool
+
+	cc := &cobra.Command{
+		Use:   "prune [paths...]",
+		Short: "Remove unused installations and packages",
+		Long: `Scans the given directories for projects and removes every installation and package library that none of them depend on.
+
+Without paths, the directories listed in the "projects" config value are scanned. Installations that are locked by another process, such as one that is still downloading, are skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := prune(cmd.Context(), pruneOpts{
+				commandOpts: opts,
+				Paths:       args,
+				DryRun:      dryRun,
+			}); err != nil {
+				return fmt.Errorf("failed to prune: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cc.Flags().BoolVar(&dryRun, "dry-run", false, "report what would be removed without removing anything")
+
+	return cc
+}
+
+func prune(ctx context.Context, opts pruneOpts) error {
+	container, err := getContainer(opts.commandOpts)
+	if err != nil {
+		return err
+	}
+
+	configurator, err := container.GetConfigurator()
+	if err != nil {
+		return err
+	}
+
+	config, err := configurator.Get()
+	if err != nil {
+		return err
+	}
+
+	paths := opts.Paths
+	if len(paths) == 0 {
+		paths = config.Projects
+	}
+
+	if len(paths) == 0 {
+		return errors.New("no directories to scan, pass them as arguments or set the projects config value")
+	}
+
+	projects, err := findProjects(paths)
+	if err != nil {
+		return err
+	}
+
+	// Without any project everything would be removed, which is never what was intended.
+	if len(projects) == 0 {
+		return fmt.Errorf("no projects found in %s", strings.Join(paths, ", "))
+	}
+
+	driver, err := container.GetDriver()
+	if err != nil {
+		return err
+	}
+
+	profiles, err := driver.LoadProfiles(ctx, &types.LoadProfilesOpts{
+		Paths: projects,
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := driver.PruneProfiles(ctx, &types.PruneProfilesOpts{
+		Profiles: profiles.Profiles,
+		DryRun:   opts.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	display, err := displayJSON(result)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(display)
+
+	action := "Removed"
+	if opts.DryRun {
+		action = "Would remove"
+	}
+
+	fmt.Printf("%s %d installations (%s) and %d package libraries (%s) not used by %d projects.\n",
+		action,
+		len(result.Installations.Removed),
+		helpers.FormatSize(result.Installations.Size),
+		len(result.Packages.Removed),
+		helpers.FormatSize(result.Packages.Size),
+		len(projects),
+	)
+
+	return nil
+}
+
+// findProjects returns every project directory found below the given paths.
+func findProjects(paths []string) ([]string, error) {
+	projects := []string{}
+	for _, root := range paths {
+		root, err := validatePath(root)
+		if err != nil {
+			return nil, err
+		}
+
+		err = filepath.WalkDir(root, func(dirPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !entry.IsDir() {
+				return nil
+			}
+
+			if helpers.FileExists(filepath.Join(dirPath, types.ProfileDirName, types.ProfileFileName)) == nil {
+				projects = append(projects, dirPath)
+			}
+
+			// Hidden directories, including the profile directory itself, never contain projects.
+			if dirPath != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+		}
+	}
+
+	return projects, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/rocketblend/rocketblend/pkg/helpers"
 	"github.com/rocketblend/rocketblend/pkg/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const DefaultOutputTemplate = "//output/" + blender.RevisionTempalteVariable + "/" + blender.NameTemplateVariable + "-#####"
@@ -24,14 +25,38 @@ type (
 		FrameEnd      int
 		FrameStep     int
 		Engine        string
+		Devices       []string
 
 		Output string
 		Format string
 
+		Frozen bool
+
+		Overrides renderOverridesOpts
+
 		EventChan chan types.BlenderEvent
 		commandOpts
 	}
 
+	// renderOverridesOpts are render settings that replace those stored in the blend file.
+	renderOverridesOpts struct {
+		ResolutionX          int
+		ResolutionY          int
+		ResolutionPercentage int
+		Samples              int
+		Denoiser             string
+		Scene                string
+		Camera               string
+		ViewLayer            string
+
+		ColorDepth    string
+		Codec         string
+		Compression   int
+		ViewTransform string
+		Container     string
+		Bitrate       int
+	}
+
 	displayRenderProjectOpts struct {
 		Verbose bool
 		renderProjectOpts
@@ -45,6 +70,7 @@ func newRenderCommand(opts commandOpts) *cobra.Command {
 	var frameStep int
 
 	var engine string
+	var devices []string
 
 	var revision int
 	var continueRendering bool
@@ -52,7 +78,10 @@ func newRenderCommand(opts commandOpts) *cobra.Command {
 	var output string
 	var format string
 
+	var overrides renderOverridesOpts
+
 	var autoConfirm bool
+	var frozen bool
 
 	cc := &cobra.Command{
 		Use:   "render",
@@ -96,16 +125,9 @@ func newRenderCommand(opts commandOpts) *cobra.Command {
 				return fmt.Errorf("failed to find blend file: %w", err)
 			}
 
-			// TODO: Switch to standard relative path formatting and just convert to // for Blender.
-			templatePath := strings.Replace(output, "//", fmt.Sprintf("%s/", opts.Global.WorkingDirectory), 1)
-			revision := calculateRevision(revision, templatePath, continueRendering)
-
-			outputPath, err := helpers.ParseTemplateWithData(templatePath, &blender.TemplatedOutputData{
-				Name:     helpers.ExtractName(blendFilePath),
-				Revision: helpers.PadWithZero(revision, 5),
-			})
+			outputPath, err := renderOutputPath(opts.Global.WorkingDirectory, blendFilePath, output, revision, continueRendering)
 			if err != nil {
-				return fmt.Errorf("failed to parse output template: %w", err)
+				return err
 			}
 
 			existingFrame, err := existingFrameNumber(outputPath)
@@ -138,8 +160,11 @@ func newRenderCommand(opts commandOpts) *cobra.Command {
 					FrameEnd:      frameEnd,
 					FrameStep:     frameStep,
 					Engine:        engine,
+					Devices:       devices,
 					Output:        outputPath,
 					Format:        format,
+					Frozen:        frozen,
+					Overrides:     overrides,
 					commandOpts:   opts,
 				},
 			})
@@ -155,10 +180,20 @@ func newRenderCommand(opts commandOpts) *cobra.Command {
 
 	cc.Flags().StringVarP(&engine, "engine", "g", "", "override render engine (cycles, eevee, workbench)")
 
+	cc.Flags().StringSliceVar(&devices, "device", nil, "render on these cycles devices (cpu, cuda, optix, hip, oneapi, metal), the cpu can be combined with one other")
+
 	cc.Flags().StringVarP(&output, "output", "o", DefaultOutputTemplate, "output path for the rendered frames")
 	cc.Flags().StringVarP(&format, "format", "f", "PNG", "output format for the rendered frames")
 
+	addRenderOverrideFlags(cc.Flags(), &overrides)
+
 	cc.Flags().BoolVarP(&autoConfirm, "auto-confirm", "y", false, "overwrite any existing files without requiring confirmation")
+	cc.Flags().BoolVar(&frozen, "frozen", false, "fail if the profile lock is missing or out of date")
+
+	cc.AddCommand(
+		newRenderCoordinatorCommand(opts),
+		newRenderWorkerCommand(opts),
+	)
 
 	return cc
 }
@@ -179,8 +214,11 @@ func renderInVerboseMode(ctx context.Context, opts displayRenderProjectOpts) err
 		FrameEnd:      opts.renderProjectOpts.FrameEnd,
 		FrameStep:     opts.renderProjectOpts.FrameStep,
 		Engine:        opts.renderProjectOpts.Engine,
+		Devices:       opts.renderProjectOpts.Devices,
 		Output:        opts.renderProjectOpts.Output,
 		Format:        opts.renderProjectOpts.Format,
+		Frozen:        opts.renderProjectOpts.Frozen,
+		Overrides:     opts.renderProjectOpts.Overrides,
 		EventChan:     nil,
 	})
 }
@@ -201,8 +239,11 @@ func renderWithUI(ctx context.Context, opts displayRenderProjectOpts) error {
 			FrameEnd:      opts.renderProjectOpts.FrameEnd,
 			FrameStep:     opts.renderProjectOpts.FrameStep,
 			Engine:        opts.renderProjectOpts.Engine,
+			Devices:       opts.renderProjectOpts.Devices,
 			Output:        opts.renderProjectOpts.Output,
 			Format:        opts.renderProjectOpts.Format,
+			Frozen:        opts.renderProjectOpts.Frozen,
+			Overrides:     opts.renderProjectOpts.Overrides,
 			EventChan:     eventChan,
 		}); err != nil {
 			if ctxRender.Err() == context.Canceled {
@@ -226,12 +267,7 @@ func renderWithUI(ctx context.Context, opts displayRenderProjectOpts) error {
 }
 
 func renderProject(ctx context.Context, opts renderProjectOpts) error {
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -250,6 +286,7 @@ func renderProject(ctx context.Context, opts renderProjectOpts) error {
 
 	resolve, err := driver.ResolveProfiles(ctx, &types.ResolveProfilesOpts{
 		Profiles: profiles.Profiles,
+		Frozen:   opts.Frozen,
 	})
 	if err != nil {
 		return err
@@ -260,13 +297,15 @@ func renderProject(ctx context.Context, opts renderProjectOpts) error {
 		return err
 	}
 
-	if err := blend.Render(ctx, &types.RenderOpts{
-		Start:  opts.FrameStart,
-		End:    opts.FrameEnd,
-		Step:   opts.FrameStep,
-		Output: opts.Output,
-		Format: opts.Format,
-		Engine: types.RenderEngine(opts.Engine),
+	renderOpts := &types.RenderOpts{
+		Start:   opts.FrameStart,
+		End:     opts.FrameEnd,
+		Step:    opts.FrameStep,
+		Output:  opts.Output,
+		Format:  opts.Format,
+		Engine:  types.RenderEngine(opts.Engine),
+		Devices: renderDevices(opts.Devices),
+
 		BlenderOpts: types.BlenderOpts{
 			BlendFile: &types.BlendFile{
 				Path:         opts.BlendFilePath,
@@ -276,13 +315,95 @@ func renderProject(ctx context.Context, opts renderProjectOpts) error {
 			Background: true,
 			EventChan:  opts.EventChan,
 		},
-	}); err != nil {
+	}
+	applyRenderOverrides(renderOpts, opts.Overrides)
+
+	if err := blend.Render(ctx, renderOpts); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// addRenderOverrideFlags adds the flags that override render settings stored in the blend file.
+func addRenderOverrideFlags(flags *pflag.FlagSet, overrides *renderOverridesOpts) {
+	flags.IntVar(&overrides.ResolutionX, "resolution-x", 0, "override horizontal resolution in pixels")
+	flags.IntVar(&overrides.ResolutionY, "resolution-y", 0, "override vertical resolution in pixels")
+	flags.IntVar(&overrides.ResolutionPercentage, "resolution-percentage", 0, "override percentage of the resolution to render at")
+	flags.IntVar(&overrides.Samples, "samples", 0, "override number of samples per pixel")
+	flags.StringVar(&overrides.Denoiser, "denoiser", "", "override denoiser used by cycles (off, optix, openimagedenoise)")
+	flags.StringVar(&overrides.Scene, "scene", "", "render the named scene instead of the active one")
+	flags.StringVar(&overrides.Camera, "camera", "", "render from the named camera instead of the active one")
+	flags.StringVar(&overrides.ViewLayer, "view-layer", "", "render only the named view layer")
+
+	flags.StringVar(&overrides.ColorDepth, "color-depth", "", "bits per channel of the output (8 or 16 for png, 16 or 32 for exr)")
+	flags.StringVar(&overrides.Codec, "codec", "", "exr codec, such as dwaa, or ffmpeg video codec, such as h264")
+	flags.IntVar(&overrides.Compression, "compression", -1, "png compression from 0 to 100, -1 to keep the blend file's")
+	flags.StringVar(&overrides.ViewTransform, "view-transform", "", "colour management view transform, such as AgX or Standard")
+	flags.StringVar(&overrides.Container, "container", "", "ffmpeg container, such as mpeg4 or mkv")
+	flags.IntVar(&overrides.Bitrate, "bitrate", 0, "ffmpeg video bitrate in kbit/s")
+}
+
+// applyRenderOverrides sets the render settings that are overridden.
+func applyRenderOverrides(renderOpts *types.RenderOpts, overrides renderOverridesOpts) {
+	renderOpts.ResolutionX = overrides.ResolutionX
+	renderOpts.ResolutionY = overrides.ResolutionY
+	renderOpts.ResolutionPercentage = overrides.ResolutionPercentage
+	renderOpts.Samples = overrides.Samples
+	renderOpts.Denoiser = types.Denoiser(overrides.Denoiser)
+	renderOpts.Scene = overrides.Scene
+	renderOpts.Camera = overrides.Camera
+	renderOpts.ViewLayer = overrides.ViewLayer
+	renderOpts.OutputSettings = outputSettings(overrides)
+}
+
+// renderOutputPath fills in the name and revision of the output template, picking the revision if it is 0.
+func renderOutputPath(workingDirectory string, blendFilePath string, output string, revision int, continueRendering bool) (string, error) {
+	// TODO: Switch to standard relative path formatting and just convert to // for Blender.
+	templatePath := strings.Replace(output, "//", fmt.Sprintf("%s/", workingDirectory), 1)
+	revision = calculateRevision(revision, templatePath, continueRendering)
+
+	outputPath, err := helpers.ParseTemplateWithData(templatePath, &blender.TemplatedOutputData{
+		Name:     helpers.ExtractName(blendFilePath),
+		Revision: helpers.PadWithZero(revision, 5),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to parse output template: %w", err)
+	}
+
+	return outputPath, nil
+}
+
+// outputSettings returns the output settings to override, or nil if there are none.
+func outputSettings(overrides renderOverridesOpts) *types.OutputSettings {
+	settings := &types.OutputSettings{
+		ColorDepth:    overrides.ColorDepth,
+		Codec:         overrides.Codec,
+		ViewTransform: overrides.ViewTransform,
+		Container:     overrides.Container,
+		Bitrate:       overrides.Bitrate,
+	}
+
+	if overrides.Compression >= 0 {
+		settings.Compression = &overrides.Compression
+	}
+
+	if *settings == (types.OutputSettings{}) {
+		return nil
+	}
+
+	return settings
+}
+
+func renderDevices(devices []string) []types.RenderDevice {
+	var result []types.RenderDevice
+	for _, device := range devices {
+		result = append(result, types.RenderDevice(strings.ToLower(strings.TrimSpace(device))))
+	}
+
+	return result
+}
+
 func calculateTotalFrames(frameStart, frameEnd, frameStep int) int {
 	if frameStep <= 0 {
 		frameStep = 1
@@ -0,0 +1,348 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/blender"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	DefaultCoordinatorAddress = "127.0.0.1:7070"
+	DefaultChunkSize          = 10
+)
+
+type (
+	coordinateRenderOpts struct {
+		FrameStart int
+		FrameEnd   int
+		FrameStep  int
+		ChunkSize  int
+		Address    string
+		Engine     string
+
+		Output string
+		Format string
+
+		Overrides renderOverridesOpts
+
+		commandOpts
+	}
+
+	workRenderOpts struct {
+		Name        string
+		Coordinator string
+		Devices     []string
+		Frozen      bool
+
+		commandOpts
+	}
+)
+
+// newRenderCoordinatorCommand creates a new cobra command that hands out chunks of a frame range to workers.
+func newRenderCoordinatorCommand(opts commandOpts) *cobra.Command {
+	var frameStart int
+	var frameEnd int
+	var frameStep int
+	var chunkSize int
+	var address string
+
+	var engine string
+	var revision int
+
+	var output string
+	var format string
+
+	var overrides renderOverridesOpts
+
+	cc := &cobra.Command{
+		Use:   "coordinator",
+		Short: "Distributes the render between workers",
+		Long: `Splits the frame range into chunks and hands them out to render workers over HTTP, until every chunk is rendered or has failed.
+
+Chunks of workers that stop reporting are handed to another worker. Relative output paths are resolved by each worker against its own copy of the project.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if frameStart < 1 {
+				return fmt.Errorf("frame start should be greater than 0")
+			}
+
+			if frameEnd == 0 {
+				frameEnd = frameStart
+			}
+
+			if frameEnd < frameStart {
+				return fmt.Errorf("frame end should be greater than or equal to frame start")
+			}
+
+			if frameStep < 1 {
+				return fmt.Errorf("frame step should be greater than 0")
+			}
+
+			if chunkSize < 1 {
+				return fmt.Errorf("chunk size should be greater than 0")
+			}
+
+			if revision < 0 {
+				return fmt.Errorf("revision should be greater than or equal to 0")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			blendFilePath, err := findFilePathForExt(opts.Global.WorkingDirectory, types.BlendFileExtension)
+			if err != nil {
+				return fmt.Errorf("failed to find blend file: %w", err)
+			}
+
+			outputPath, err := farmOutputPath(opts.Global.WorkingDirectory, blendFilePath, output, revision)
+			if err != nil {
+				return err
+			}
+
+			if err := coordinateRender(cmd.Context(), coordinateRenderOpts{
+				FrameStart:  frameStart,
+				FrameEnd:    frameEnd,
+				FrameStep:   frameStep,
+				ChunkSize:   chunkSize,
+				Address:     address,
+				Engine:      engine,
+				Output:      outputPath,
+				Format:      format,
+				Overrides:   overrides,
+				commandOpts: opts,
+			}); err != nil {
+				return fmt.Errorf("failed to coordinate render: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cc.Flags().IntVarP(&frameStart, "start", "s", 1, "frame to start rendering from")
+	cc.Flags().IntVarP(&frameEnd, "end", "e", 0, "frame to end rendering at, 0 for single frame")
+	cc.Flags().IntVarP(&frameStep, "jump", "j", 1, "number of frames to step forward after each rendered frame")
+	cc.Flags().IntVar(&chunkSize, "chunk-size", DefaultChunkSize, "number of frames handed to a worker at a time")
+	cc.Flags().StringVar(&address, "listen", DefaultCoordinatorAddress, "address to serve workers on")
+
+	cc.Flags().IntVarP(&revision, "revision", "r", 0, "revision number for the output directory, 0 for auto-increment")
+
+	cc.Flags().StringVarP(&engine, "engine", "g", "", "override render engine (cycles, eevee, workbench)")
+
+	cc.Flags().StringVarP(&output, "output", "o", DefaultOutputTemplate, "output path for the rendered frames")
+	cc.Flags().StringVarP(&format, "format", "f", "PNG", "output format for the rendered frames")
+
+	addRenderOverrideFlags(cc.Flags(), &overrides)
+
+	return cc
+}
+
+// newRenderWorkerCommand creates a new cobra command that renders chunks handed out by a coordinator.
+func newRenderWorkerCommand(opts commandOpts) *cobra.Command {
+	var name string
+	var coordinator string
+	var devices []string
+	var frozen bool
+
+	cc := &cobra.Command{
+		Use:   "worker",
+		Short: "Renders chunks handed out by a coordinator",
+		Long:  `Asks the coordinator for chunks of the frame range and renders them with the project's build, until none are left.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				hostname, err := os.Hostname()
+				if err != nil {
+					return fmt.Errorf("failed to get hostname: %w", err)
+				}
+
+				name = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+			}
+
+			if err := workRender(cmd.Context(), workRenderOpts{
+				Name:        name,
+				Coordinator: coordinator,
+				Devices:     devices,
+				Frozen:      frozen,
+				commandOpts: opts,
+			}); err != nil {
+				return fmt.Errorf("failed to work on render: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cc.Flags().StringVar(&name, "name", "", "name of the worker, defaults to the hostname and process id")
+	cc.Flags().StringVar(&coordinator, "coordinator", "http://"+DefaultCoordinatorAddress, "url of the coordinator")
+	cc.Flags().StringSliceVar(&devices, "device", nil, "render on these cycles devices (cpu, cuda, optix, hip, oneapi, metal), the cpu can be combined with one other")
+	cc.Flags().BoolVar(&frozen, "frozen", false, "fail if the profile lock is missing or out of date")
+
+	return cc
+}
+
+func coordinateRender(ctx context.Context, opts coordinateRenderOpts) error {
+	container, err := getContainer(opts.commandOpts)
+	if err != nil {
+		return err
+	}
+
+	coordinator, err := container.GetCoordinator()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", opts.Address)
+	if err != nil {
+		return err
+	}
+
+	renderOpts := types.RenderOpts{
+		Output: opts.Output,
+		Format: opts.Format,
+		Engine: types.RenderEngine(opts.Engine),
+	}
+	applyRenderOverrides(&renderOpts, opts.Overrides)
+
+	eventChan := make(chan types.BlenderEvent, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range eventChan {
+			printFarmEvent(event)
+		}
+	}()
+
+	fmt.Printf("Waiting for workers on %s\n", listener.Addr())
+
+	result, err := coordinator.Coordinate(ctx, &types.CoordinateOpts{
+		Listener:  listener,
+		Start:     opts.FrameStart,
+		End:       opts.FrameEnd,
+		Step:      opts.FrameStep,
+		ChunkSize: opts.ChunkSize,
+		Opts:      renderOpts,
+		EventChan: eventChan,
+	})
+	close(eventChan)
+	<-done
+
+	if result != nil {
+		display, displayErr := displayJSON(result)
+		if displayErr != nil {
+			return displayErr
+		}
+
+		fmt.Println(display)
+	}
+
+	return err
+}
+
+func workRender(ctx context.Context, opts workRenderOpts) error {
+	container, err := getContainer(opts.commandOpts)
+	if err != nil {
+		return err
+	}
+
+	blendFilePath, err := findFilePathForExt(opts.Global.WorkingDirectory, types.BlendFileExtension)
+	if err != nil {
+		return fmt.Errorf("failed to find blend file: %w", err)
+	}
+
+	driver, err := container.GetDriver()
+	if err != nil {
+		return err
+	}
+
+	profiles, err := driver.LoadProfiles(ctx, &types.LoadProfilesOpts{
+		Paths: []string{opts.Global.WorkingDirectory},
+	})
+	if err != nil {
+		return err
+	}
+
+	resolve, err := driver.ResolveProfiles(ctx, &types.ResolveProfilesOpts{
+		Profiles: profiles.Profiles,
+		Frozen:   opts.Frozen,
+	})
+	if err != nil {
+		return err
+	}
+
+	worker, err := container.GetWorker()
+	if err != nil {
+		return err
+	}
+
+	eventChan := make(chan types.BlenderEvent, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range eventChan {
+			printFarmEvent(event)
+		}
+	}()
+
+	err = worker.Work(ctx, &types.WorkOpts{
+		Name:        opts.Name,
+		Coordinator: opts.Coordinator,
+		BlendFile: &types.BlendFile{
+			Path:         blendFilePath,
+			Dependencies: resolve.Installations[0],
+			Strict:       profiles.Profiles[0].Strict,
+		},
+		Devices:   renderDevices(opts.Devices),
+		EventChan: eventChan,
+	})
+	close(eventChan)
+	<-done
+
+	return err
+}
+
+// printFarmEvent prints the events of a distributed render worth following without a UI.
+func printFarmEvent(event types.BlenderEvent) {
+	switch e := event.(type) {
+	case *types.ChunkEvent:
+		line := fmt.Sprintf("chunk %d (frames %d-%d) %s", e.Chunk.ID, e.Chunk.Start, e.Chunk.End, e.Chunk.State)
+		if e.Chunk.Worker != "" {
+			line += " by " + e.Chunk.Worker
+		}
+
+		if e.Chunk.Error != "" {
+			line += ": " + e.Chunk.Error
+		}
+
+		fmt.Println(line)
+	case *types.WorkerEvent:
+		if saved, ok := e.Event.(*types.SavedFileEvent); ok {
+			fmt.Printf("%s saved %s\n", e.Worker, saved.Path)
+		}
+	case *types.SavedFileEvent:
+		fmt.Printf("saved %s\n", e.Path)
+	}
+}
+
+// farmOutputPath fills in the name and revision of the output template, picking the revision if it is 0. Unlike
+// a local render, a relative path is left for Blender to resolve against each worker's blend file.
+func farmOutputPath(workingDirectory string, blendFilePath string, output string, revision int) (string, error) {
+	templatePath := strings.Replace(output, "//", fmt.Sprintf("%s/", workingDirectory), 1)
+	revision = calculateRevision(revision, templatePath, false)
+
+	outputPath, err := helpers.ParseTemplateWithData(output, &blender.TemplatedOutputData{
+		Name:     helpers.ExtractName(blendFilePath),
+		Revision: helpers.PadWithZero(revision, 5),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to parse output template: %w", err)
+	}
+
+	return outputPath, nil
+}
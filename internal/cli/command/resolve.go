@@ -43,12 +43,7 @@ func newResolveCommand(opts commandOpts) *cobra.Command {
 }
 
 func resolveProject(ctx context.Context, opts resolveProjectOpts) error {
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
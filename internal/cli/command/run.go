@@ -10,11 +10,14 @@ import (
 
 type runProjectOpts struct {
 	commandOpts
+	Frozen       bool
 	ProgressChan chan<- ui.ProgressEvent
 }
 
 // newRunCommand creates a new cobra command for running the project.
 func newRunCommand(opts commandOpts) *cobra.Command {
+	var frozen bool
+
 	cc := &cobra.Command{
 		Use:   "run",
 		Short: "Runs the project",
@@ -27,12 +30,15 @@ func newRunCommand(opts commandOpts) *cobra.Command {
 				func(ctx context.Context, eventChan chan<- ui.ProgressEvent) error {
 					return runProject(ctx, runProjectOpts{
 						commandOpts:  opts,
+						Frozen:       frozen,
 						ProgressChan: eventChan,
 					})
 				})
 		},
 	}
 
+	cc.Flags().BoolVar(&frozen, "frozen", false, "fail if the profile lock is missing or out of date")
+
 	return cc
 }
 
@@ -50,12 +56,7 @@ func runProject(ctx context.Context, opts runProjectOpts) error {
 		return err
 	}
 
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -76,6 +77,7 @@ func runProject(ctx context.Context, opts runProjectOpts) error {
 	emit(ui.StepEvent{Message: "Resolving dependencies..."})
 	resolve, err := driver.ResolveProfiles(ctx, &types.ResolveProfilesOpts{
 		Profiles: profiles.Profiles,
+		Frozen:   opts.Frozen,
 	})
 	if err != nil {
 		return err
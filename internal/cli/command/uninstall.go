@@ -48,12 +48,7 @@ func uninstallPackage(ctx context.Context, opts uninstallPackageOpts) error {
 	}
 
 	emit(ui.StepEvent{Message: "Initialising..."})
-	container, err := getContainer(containerOpts{
-		AppName:     opts.AppName,
-		Development: opts.Development,
-		Level:       opts.Global.Level,
-		Verbose:     opts.Global.Verbose,
-	})
+	container, err := getContainer(opts.commandOpts)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+type (
+	// DownloadEvent is sent when the progress of an installation that is being fetched changes.
+	DownloadEvent struct {
+		Progress types.InstallationProgress
+	}
+
+	// downloads tracks the installations being fetched, in the order they started.
+	downloads struct {
+		order    []string
+		progress map[string]types.InstallationProgress
+		sizes    map[string]int64 // Downloaded sizes, which extraction progress doesn't report.
+		bar      progress.Model
+	}
+)
+
+func newDownloads() downloads {
+	return downloads{
+		progress: make(map[string]types.InstallationProgress),
+		sizes:    make(map[string]int64),
+		bar: progress.New(
+			progress.WithGradient("#4E51D0", "#E06F5A"),
+			progress.WithWidth(25),
+		),
+	}
+}
+
+func (d *downloads) update(p types.InstallationProgress) {
+	name := p.Reference.String()
+	if _, ok := d.progress[name]; !ok {
+		d.order = append(d.order, name)
+	}
+
+	d.progress[name] = p
+	if p.Phase == types.InstallationPhaseDownloading || p.Phase == types.InstallationPhaseVerifying {
+		d.sizes[name] = p.Total
+	}
+}
+
+// view renders a bar for each artifact, followed by a bar for all of them together.
+func (d *downloads) view() string {
+	if len(d.order) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, name := range d.order {
+		width = max(width, len(shortName(name)))
+	}
+
+	var lines []string
+	var current, total int64
+	var speed float64
+	totalKnown := true
+	for _, name := range d.order {
+		p := d.progress[name]
+		lines = append(lines, fmt.Sprintf("%-*s %s", width, shortName(name), d.artifactView(p)))
+
+		// Artifacts past their download count as fully downloaded, extraction reports bytes of its own.
+		size, ok := d.sizes[name]
+		if !ok {
+			size = p.Total
+		}
+
+		if p.Phase == types.InstallationPhaseDownloading {
+			current += p.Current
+		} else {
+			current += max(size, 0)
+		}
+
+		if size < 0 {
+			totalKnown = false
+		} else {
+			total += size
+		}
+
+		if p.Phase == types.InstallationPhaseDownloading {
+			speed += p.Speed
+		}
+	}
+
+	summary := helpers.FormatSize(current)
+	if totalKnown && total > 0 {
+		summary = fmt.Sprintf("%s %s/%s", d.bar.ViewAs(float64(current)/float64(total)), helpers.FormatSize(current), helpers.FormatSize(total))
+		if speed > 0 {
+			eta := time.Duration(float64(total-current) / speed * float64(time.Second))
+			summary += fmt.Sprintf(" %s/s ETA %s", helpers.FormatSize(int64(speed)), eta.Truncate(time.Second))
+		}
+	}
+
+	lines = append(lines, "", fmt.Sprintf("%-*s %s", width, "Total", summary))
+	return strings.Join(lines, "\n")
+}
+
+func (d *downloads) artifactView(p types.InstallationProgress) string {
+	if p.Phase == types.InstallationPhaseComplete {
+		return checkMark.String() + " " + helpers.FormatSize(p.Current)
+	}
+
+	if p.Total <= 0 {
+		return fmt.Sprintf("%s %s", helpers.FormatSize(p.Current), p.Phase)
+	}
+
+	view := fmt.Sprintf("%s %s/%s", d.bar.ViewAs(float64(p.Current)/float64(p.Total)), helpers.FormatSize(p.Current), helpers.FormatSize(p.Total))
+	if p.Phase == types.InstallationPhaseExtracting {
+		return fmt.Sprintf("%s Extracting (%d files)", view, p.Entries)
+	}
+
+	if p.Phase != types.InstallationPhaseDownloading {
+		return view + " " + string(p.Phase)
+	}
+
+	if p.Speed > 0 {
+		view += fmt.Sprintf(" %s/s", helpers.FormatSize(int64(p.Speed)))
+	}
+
+	if p.ETA > 0 {
+		view += fmt.Sprintf(" ETA %s", p.ETA.Truncate(time.Second))
+	}
+
+	if p.Attempt > 1 {
+		view += fmt.Sprintf(" (attempt %d)", p.Attempt)
+	}
+
+	return view
+}
+
+// shortName returns the last two segments of a reference, such as "blender/4.2.2".
+func shortName(name string) string {
+	segments := strings.Split(name, "/")
+	if len(segments) <= 2 {
+		return name
+	}
+
+	return strings.Join(segments[len(segments)-2:], "/")
+}
+
+func (DownloadEvent) isProgressEvent() {}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+const (
+	mebibyte = 1 << 20
+
+	testBuild = "github.com/rocketblend/official-library/packages/v0/builds/blender/4.2.0"
+	testAddon = "github.com/rocketblend/official-library/packages/v0/addons/tools/1.0.0"
+)
+
+// totalLine returns the summary line of a downloads view.
+func totalLine(t *testing.T, view string) string {
+	t.Helper()
+
+	lines := strings.Split(view, "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "Total") {
+		t.Fatalf("view() = %q, want it to end with the total", view)
+	}
+
+	return last
+}
+
+func assertContains(t *testing.T, name string, got string, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("%s = %q, want it to contain %q", name, got, w)
+		}
+	}
+}
+
+func TestDownloadsView(t *testing.T) {
+	d := newDownloads()
+	if view := d.view(); view != "" {
+		t.Errorf("view() without downloads = %q, want empty", view)
+	}
+
+	d.update(types.InstallationProgress{Reference: testBuild, Phase: types.InstallationPhaseDownloading, Current: 10 * mebibyte, Total: 40 * mebibyte, Speed: 2 * mebibyte})
+	d.update(types.InstallationProgress{Reference: testAddon, Phase: types.InstallationPhaseDownloading, Current: 5 * mebibyte, Total: 20 * mebibyte, Speed: 3 * mebibyte})
+
+	view := d.view()
+	lines := strings.Split(view, "\n")
+	if !strings.HasPrefix(lines[0], "blender/4.2.0") || !strings.HasPrefix(lines[1], "tools/1.0.0") {
+		t.Errorf("view() = %q, want a line per artifact in the order they started", view)
+	}
+
+	// 15 of 60 MiB at 5 MiB/s leaves 45 MiB, or 9 seconds.
+	assertContains(t, "total", totalLine(t, view), "15.0 MiB/60.0 MiB", "5.0 MiB/s", "ETA 9s")
+
+	// A downloaded artifact counts as complete while it is verified and extracted.
+	d.update(types.InstallationProgress{Reference: testBuild, Phase: types.InstallationPhaseExtracting, Current: mebibyte, Total: 100 * mebibyte, Entries: 12})
+	assertContains(t, "total", totalLine(t, d.view()), "45.0 MiB/60.0 MiB", "3.0 MiB/s", "ETA 5s")
+}
+
+func TestDownloadsViewUnknownTotal(t *testing.T) {
+	d := newDownloads()
+	d.update(types.InstallationProgress{Reference: testBuild, Phase: types.InstallationPhaseDownloading, Current: 10 * mebibyte, Total: 40 * mebibyte, Speed: mebibyte})
+	d.update(types.InstallationProgress{Reference: testAddon, Phase: types.InstallationPhaseDownloading, Current: 5 * mebibyte, Total: -1, Speed: mebibyte})
+
+	// Without every size there is no bar or ETA, only the amount downloaded.
+	total := totalLine(t, d.view())
+	if strings.Contains(total, "/") || strings.Contains(total, "ETA") {
+		t.Errorf("total = %q, want no total size or ETA", total)
+	}
+
+	assertContains(t, "total", total, "15.0 MiB")
+}
+
+func TestArtifactView(t *testing.T) {
+	d := newDownloads()
+
+	tests := []struct {
+		name     string
+		progress types.InstallationProgress
+		want     []string
+		exclude  []string
+	}{
+		{
+			name:     "downloading",
+			progress: types.InstallationProgress{Phase: types.InstallationPhaseDownloading, Current: mebibyte, Total: 4 * mebibyte, Speed: 512 * 1024, ETA: 6500 * time.Millisecond},
+			want:     []string{"1.0 MiB/4.0 MiB", "512.0 KiB/s", "ETA 6s"},
+			exclude:  []string{"attempt"},
+		},
+		{
+			name:     "retrying",
+			progress: types.InstallationProgress{Phase: types.InstallationPhaseDownloading, Current: mebibyte, Total: 4 * mebibyte, Attempt: 2},
+			want:     []string{"(attempt 2)"},
+			exclude:  []string{"ETA", "/s"},
+		},
+		{
+			name:     "unknown total",
+			progress: types.InstallationProgress{Phase: types.InstallationPhaseDownloading, Current: 2048, Total: -1, Speed: 1024},
+			want:     []string{"2.0 KiB downloading"},
+			exclude:  []string{"/s"},
+		},
+		{
+			name:     "verifying",
+			progress: types.InstallationProgress{Phase: types.InstallationPhaseVerifying, Current: 4 * mebibyte, Total: 4 * mebibyte},
+			want:     []string{"4.0 MiB/4.0 MiB verifying"},
+		},
+		{
+			name:     "extracting",
+			progress: types.InstallationProgress{Phase: types.InstallationPhaseExtracting, Current: mebibyte, Total: 8 * mebibyte, Entries: 42},
+			want:     []string{"1.0 MiB/8.0 MiB", "Extracting (42 files)"},
+			exclude:  []string{"extracting"},
+		},
+		{
+			name:     "complete",
+			progress: types.InstallationProgress{Phase: types.InstallationPhaseComplete, Current: 4 * mebibyte, Total: 4 * mebibyte},
+			want:     []string{"✓", "4.0 MiB"},
+			exclude:  []string{"/"},
+		},
+	}
+
+	for _, test := range tests {
+		view := d.artifactView(test.progress)
+		assertContains(t, test.name, view, test.want...)
+		for _, exclude := range test.exclude {
+			if strings.Contains(view, exclude) {
+				t.Errorf("%s = %q, want it not to contain %q", test.name, view, exclude)
+			}
+		}
+	}
+}
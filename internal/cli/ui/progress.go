@@ -38,6 +38,7 @@ type (
 		eventChan  <-chan ProgressEvent
 		message    string
 		steps      []string
+		downloads  downloads
 		cancelFunc func()
 	}
 )
@@ -73,6 +74,7 @@ func newProgressModel(eventChan <-chan ProgressEvent, cancel func()) progressMod
 		spinner:    s,
 		eventChan:  eventChan,
 		steps:      []string{},
+		downloads:  newDownloads(),
 		cancelFunc: cancel,
 	}
 }
@@ -106,6 +108,10 @@ func (m *progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.steps = append(m.steps, msg.Message)
 		return m, waitForProgressEvent(m.eventChan)
 
+	case DownloadEvent:
+		m.downloads.update(msg.Progress)
+		return m, waitForProgressEvent(m.eventChan)
+
 	case CompletionEvent:
 		m.message = msg.Message
 		m.status = statusDone
@@ -138,10 +144,16 @@ func (m *progressModel) View() string {
 		}
 	}
 
-	view := fmt.Sprintf("%s%s %s\n\n%s",
+	downloadsView := ""
+	if view := m.downloads.view(); view != "" {
+		downloadsView = "\n" + view + "\n"
+	}
+
+	view := fmt.Sprintf("%s%s %s\n%s\n%s",
 		stepsView,
 		m.spinner.View(),
 		infoStyle.Render(m.message),
+		downloadsView,
 		renderLegend(),
 	)
 
@@ -96,6 +96,12 @@ func (m *renderProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleRenderEvent(renderEvent)
 		}
 
+		if startEvent, ok := msg.(*types.RenderStartEvent); ok {
+			m.currentFrame = startEvent.Frame
+			m.currentSample = 0
+			return m, waitForBlenderEvent(m.eventChan)
+		}
+
 		if savedFileEvent, ok := msg.(*types.SavedFileEvent); ok {
 			return m.handleSavedFileEvent(savedFileEvent)
 		}
@@ -19,6 +19,32 @@ type (
 		Devices []CyclesDevice
 		Engine  RenderEngine
 		Threads int
+		Scene   string
+	}
+
+	// renderOverrides are the render settings changed by the override script before rendering.
+	renderOverrides struct {
+		Engine               RenderEngine     `json:"engine,omitempty"`
+		ResolutionX          int              `json:"resolutionX,omitempty"`
+		ResolutionY          int              `json:"resolutionY,omitempty"`
+		ResolutionPercentage int              `json:"resolutionPercentage,omitempty"`
+		Samples              int              `json:"samples,omitempty"`
+		Denoiser             CyclesDenoiser   `json:"denoiser,omitempty"`
+		Camera               string           `json:"camera,omitempty"`
+		ViewLayer            string           `json:"viewLayer,omitempty"`
+		Output               *outputOverrides `json:"output,omitempty"`
+	}
+
+	// outputOverrides are the settings of the files written by a render, named as Blender's enums.
+	outputOverrides struct {
+		Format        RenderFormat `json:"format"`
+		ColorDepth    string       `json:"colorDepth,omitempty"`
+		ExrCodec      string       `json:"exrCodec,omitempty"`
+		Compression   *int         `json:"compression,omitempty"`
+		ViewTransform string       `json:"viewTransform,omitempty"`
+		Container     string       `json:"container,omitempty"`
+		VideoCodec    string       `json:"videoCodec,omitempty"`
+		Bitrate       int          `json:"bitrate,omitempty"`
 	}
 
 	rocketblendArguments struct {
@@ -29,7 +55,8 @@ type (
 	arguments struct {
 		Background    bool
 		BlendFilePath string
-		Script        string
+		Scripts       []string
+		ExitOnError   bool // Exit when a script raises instead of carrying on.
 		Render        *renderArguments
 		Rockeblend    *rocketblendArguments
 	}
@@ -65,15 +92,6 @@ func (a *renderArguments) ARGS() []string {
 		args = append(args, "--render-format", string(a.Format), "-x", "1")
 	}
 
-	if len(a.Devices) > 0 {
-		devices := []string{"--cycles-device"}
-		for _, device := range a.Devices {
-			devices = append(devices, string(device))
-		}
-
-		args = append(args, strings.Join(devices, "+"))
-	}
-
 	if a.Threads > 0 {
 		args = append(args, "-t", strconv.Itoa(a.Threads))
 	}
@@ -81,6 +99,20 @@ func (a *renderArguments) ARGS() []string {
 	return append(args, "-a")
 }
 
+// cyclesARGS returns the arguments read by the Cycles addon, which have to follow "--".
+func (a *renderArguments) cyclesARGS() []string {
+	if len(a.Devices) == 0 {
+		return nil
+	}
+
+	devices := []string{}
+	for _, device := range a.Devices {
+		devices = append(devices, string(device))
+	}
+
+	return []string{"--cycles-device", strings.Join(devices, "+")}
+}
+
 func (a *rocketblendArguments) ARGS() []string {
 	args := []string{}
 	if a.Addons != nil {
@@ -114,10 +146,19 @@ func (a *arguments) ARGS() []string {
 		args = append(args, a.BlendFilePath)
 	}
 
-	if a.Script != "" {
+	// The scene is selected before any script runs, so that they apply to it.
+	if a.Render != nil && a.Render.Scene != "" {
+		args = append(args, "--scene", a.Render.Scene)
+	}
+
+	if a.ExitOnError {
+		args = append(args, "--python-exit-code", "1")
+	}
+
+	for _, script := range a.Scripts {
 		args = append(args, []string{
 			"--python-expr",
-			a.Script,
+			script,
 		}...)
 	}
 
@@ -125,9 +166,19 @@ func (a *arguments) ARGS() []string {
 		args = append(args, a.Render.ARGS()...)
 	}
 
-	if a.Script != "" && a.Rockeblend != nil {
+	// Blender ignores everything after "--", leaving it to scripts and addons.
+	scriptArgs := []string{}
+	if len(a.Scripts) > 0 && a.Rockeblend != nil {
+		scriptArgs = append(scriptArgs, a.Rockeblend.ARGS()...)
+	}
+
+	if a.Render != nil {
+		scriptArgs = append(scriptArgs, a.Render.cyclesARGS()...)
+	}
+
+	if len(scriptArgs) > 0 {
 		args = append(args, "--")
-		args = append(args, a.Rockeblend.ARGS()...)
+		args = append(args, scriptArgs...)
 	}
 
 	return args
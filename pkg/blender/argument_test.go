@@ -0,0 +1,108 @@
+package blender
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestArgumentsSceneBeforeScripts(t *testing.T) {
+	args := (&arguments{
+		Background:    true,
+		BlendFilePath: "shot.blend",
+		Scripts:       []string{"events", "overrides"},
+		ExitOnError:   true,
+		Render: &renderArguments{
+			Start: 1,
+			End:   10,
+			Scene: "Lighting",
+		},
+	}).ARGS()
+
+	want := []string{
+		"-b", "shot.blend",
+		"--scene", "Lighting",
+		"--python-exit-code", "1",
+		"--python-expr", "events",
+		"--python-expr", "overrides",
+		"--frame-start", "1",
+		"--frame-end", "10",
+		"-a",
+	}
+
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("ARGS() = %q, want %q", args, want)
+	}
+}
+
+func TestRenderOverridesScript(t *testing.T) {
+	overrides := &renderOverrides{
+		ResolutionX: 1920,
+		ResolutionY: 1080,
+		Samples:     64,
+		Denoiser:    CyclesDenoiserNone,
+		Camera:      `Camera "close" \ up`,
+	}
+
+	script, err := renderOverridesScript(overrides)
+	if err != nil {
+		t.Fatalf("renderOverridesScript() returned unexpected error: %v", err)
+	}
+
+	_, rest, ok := strings.Cut(script, "settings = json.loads(")
+	if !ok {
+		t.Fatalf("renderOverridesScript() did not load settings:\n%s", script)
+	}
+
+	quoted, _, ok := strings.Cut(rest, ")\n")
+	if !ok {
+		t.Fatalf("renderOverridesScript() did not load settings:\n%s", script)
+	}
+
+	settings, err := strconv.Unquote(quoted)
+	if err != nil {
+		t.Fatalf("settings %s are not quoted: %v", quoted, err)
+	}
+
+	var got renderOverrides
+	if err := json.Unmarshal([]byte(settings), &got); err != nil {
+		t.Fatalf("settings %s are not valid JSON: %v", settings, err)
+	}
+
+	if got != *overrides {
+		t.Errorf("settings = %+v, want %+v", got, *overrides)
+	}
+}
+
+func TestArgumentsCyclesDevices(t *testing.T) {
+	args := (&arguments{
+		Background:    true,
+		BlendFilePath: "shot.blend",
+		Scripts:       []string{"startup"},
+		Render: &renderArguments{
+			Start:   1,
+			Devices: convertDevices([]types.RenderDevice{types.DeviceOptiX, types.DeviceCPU}),
+		},
+		Rockeblend: &rocketblendArguments{
+			Strict: true,
+		},
+	}).ARGS()
+
+	want := []string{
+		"-b", "shot.blend",
+		"--python-expr", "startup",
+		"--frame-start", "1",
+		"-a",
+		"--",
+		"-s",
+		"--cycles-device", "OPTIX+CPU",
+	}
+
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("ARGS() = %q, want %q", args, want)
+	}
+}
@@ -26,6 +26,7 @@ var (
 	// Never obfuscate these type (Garble)
 	_ = reflect.TypeOf(TemplatedOutputData{})
 	_ = reflect.TypeOf(CreateBlendFileData{})
+	_ = reflect.TypeOf(RenderOverridesData{})
 )
 
 func WithLogger(logger types.Logger) Option {
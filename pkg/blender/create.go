@@ -47,7 +47,7 @@ func (b *Blender) Create(ctx context.Context, opts *types.CreateOpts) error {
 	})
 
 	if err := b.execute(ctx, build.Path, &arguments{
-		Script:     script,
+		Scripts:    []string{script},
 		Background: opts.Background,
 	}, nil); err != nil {
 		b.logger.Error("blender", map[string]interface{}{
@@ -0,0 +1,26 @@
+package blender
+
+import "github.com/rocketblend/rocketblend/pkg/types"
+
+// CyclesDenoiser represents the available denoisers for rendering with the Cycles engine.
+type CyclesDenoiser string
+
+const (
+	CyclesDenoiserDefault          CyclesDenoiser = ""
+	CyclesDenoiserNone             CyclesDenoiser = "NONE"
+	CyclesDenoiserOptiX            CyclesDenoiser = "OPTIX"
+	CyclesDenoiserOpenImageDenoise CyclesDenoiser = "OPENIMAGEDENOISE"
+)
+
+func convertDenoiser(denoiser types.Denoiser) CyclesDenoiser {
+	switch denoiser {
+	case types.DenoiserOff:
+		return CyclesDenoiserNone
+	case types.DenoiserOptiX:
+		return CyclesDenoiserOptiX
+	case types.DenoiserOpenImageDenoise:
+		return CyclesDenoiserOpenImageDenoise
+	default:
+		return CyclesDenoiserDefault
+	}
+}
@@ -1,5 +1,11 @@
 package blender
 
+import (
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
 // CyclesDevice represents the available devices for rendering with the Cycles engine.
 type CyclesDevice string
 
@@ -11,3 +17,12 @@ const (
 	CyclesDeviceONEAPI CyclesDevice = "ONEAPI"
 	CyclesDeviceMETAL  CyclesDevice = "METAL"
 )
+
+func convertDevices(devices []types.RenderDevice) []CyclesDevice {
+	var result []CyclesDevice
+	for _, device := range devices {
+		result = append(result, CyclesDevice(strings.ToUpper(string(device))))
+	}
+
+	return result
+}
@@ -0,0 +1,61 @@
+package blender
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// Devices asks a build which compute backends Cycles was compiled with, and the devices it finds for each.
+func (b *Blender) Devices(ctx context.Context, opts *types.DevicesOpts) (*types.DevicesResult, error) {
+	if err := b.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	arguments := arguments{
+		Background:  true,
+		Scripts:     []string{devicesScript()},
+		ExitOnError: true,
+	}
+
+	outputChan := make(chan string, 100)
+	eventChan := make(chan types.BlenderEvent, 100)
+	go func() {
+		defer close(eventChan)
+		processChannel(outputChan, eventChan, b.outputProcessor())
+	}()
+
+	found := make(chan *types.DevicesEvent, 1)
+	go func() {
+		var devices *types.DevicesEvent
+		for event := range eventChan {
+			if e, ok := event.(*types.DevicesEvent); ok {
+				devices = e
+			}
+		}
+
+		found <- devices
+	}()
+
+	err := b.execute(ctx, opts.Build.Path, &arguments, outputChan)
+	close(outputChan)
+
+	devices := <-found
+	if err != nil {
+		return nil, err
+	}
+
+	if devices == nil {
+		return nil, errors.New("build did not report its devices")
+	}
+
+	b.logger.Debug("found devices", map[string]interface{}{
+		"build":    opts.Build.Path,
+		"backends": len(devices.Backends),
+	})
+
+	return &types.DevicesResult{
+		Backends: devices.Backends,
+	}, nil
+}
@@ -41,7 +41,7 @@ func (b *Blender) execute(ctx context.Context, name string, arguments *arguments
 		"rockeblend": arguments.Rockeblend,
 		"render":     arguments.Render,
 		"background": arguments.Background,
-		"script":     arguments.Script != "",
+		"scripts":    len(arguments.Scripts),
 	})
 
 	if err := Execute(ctx, &executable{
@@ -60,6 +60,8 @@ func Execute(ctx context.Context, executable types.Executable) error {
 	cmd := exec.CommandContext(ctx, executable.Name(), executable.ARGS()...)
 	helpers.SetupSysProcAttr(cmd)
 
+	// Output must be read completely before waiting, otherwise its last lines can be lost.
+	scanned := make(chan struct{})
 	outputChannel := executable.OutputChannel()
 	if outputChannel != nil {
 		cmdReader, err := cmd.StdoutPipe()
@@ -72,17 +74,20 @@ func Execute(ctx context.Context, executable types.Executable) error {
 
 		scanner := bufio.NewScanner(cmdReader)
 		go func() {
+			defer close(scanned)
 			for scanner.Scan() {
 				outputChannel <- scanner.Text()
 			}
-			//close(outputChannel)
 		}()
+	} else {
+		close(scanned)
 	}
 
 	if err := cmd.Start(); err != nil {
 		return err
 	}
 
+	<-scanned
 	return cmd.Wait()
 }
 
@@ -1,5 +1,11 @@
 package blender
 
+import (
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
 // RenderFormat represents the available formats for rendering.
 type RenderFormat string
 
@@ -22,3 +28,26 @@ const (
 	RenderFormatJP2                 RenderFormat = "JP2"
 	RenderFormatWEBP                RenderFormat = "WEBP"
 )
+
+func convertOutputSettings(format string, settings *types.OutputSettings) *outputOverrides {
+	if settings == nil {
+		return nil
+	}
+
+	output := &outputOverrides{
+		Format:        RenderFormat(strings.ToUpper(format)),
+		ColorDepth:    settings.ColorDepth,
+		Compression:   settings.Compression,
+		ViewTransform: settings.ViewTransform,
+	}
+
+	if output.Format == RenderFormatFFMPEG {
+		output.Container = strings.ToUpper(settings.Container)
+		output.VideoCodec = strings.ToUpper(settings.Codec)
+		output.Bitrate = settings.Bitrate
+	} else {
+		output.ExrCodec = strings.ToUpper(settings.Codec)
+	}
+
+	return output
+}
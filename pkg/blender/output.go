@@ -8,12 +8,20 @@ import (
 	"github.com/rocketblend/rocketblend/pkg/types"
 )
 
-func (b *Blender) processOutput(output string) types.BlenderEvent {
+// outputProcessor returns a function that turns the output of a single Blender process into events.
+func (b *Blender) outputProcessor() func(string) types.BlenderEvent {
+	p := parser.New()
+	return func(output string) types.BlenderEvent {
+		return b.processOutput(p, output)
+	}
+}
+
+func (b *Blender) processOutput(p *parser.Parser, output string) types.BlenderEvent {
 	if output == "" {
 		return nil
 	}
 
-	event, err := parser.ParseBlenderEvent(output)
+	event, err := p.Parse(output)
 	if err != nil {
 		trimmedOutput := strings.ToLower(strings.TrimSpace(output))
 		b.logger.Debug("blender", map[string]interface{}{
@@ -59,8 +67,20 @@ func convertEventToMap(event types.BlenderEvent) map[string]interface{} {
 	switch event.(type) {
 	case *types.QuitEvent:
 		result["event"] = "quit"
+	case *types.ReadyEvent:
+		result["event"] = "ready"
+	case *types.DevicesEvent:
+		result["event"] = "devices"
 	case *types.SavedFileEvent:
 		result["event"] = "saved"
+	case *types.BlendFileSavedEvent:
+		result["event"] = "blendFileSaved"
+	case *types.RenderStartEvent:
+		result["event"] = "renderStart"
+	case *types.RenderCompleteEvent:
+		result["event"] = "renderComplete"
+	case *types.RenderCancelEvent:
+		result["event"] = "renderCancel"
 	case *types.RenderingEvent:
 		result["event"] = "rendering"
 	case *types.SynchronizingEvent:
@@ -18,26 +18,65 @@ const (
 	eventPatternCycles = `Fra:(\d+) Mem:([0-9.]+[MK]?) \(Peak ([0-9.]+[MK]?)\) \| Time:([0-9:.]+) \| Mem:([0-9.]+[MK]?), Peak:([0-9.]+[MK]?) \| (.*)`
 )
 
+type (
+	// Parser parses the output of a single Blender process. Events written by the injected events script are
+	// preferred, Blender's own output is only parsed for what the build has no handler for.
+	Parser struct {
+		handlers map[string]bool
+	}
+)
+
+// New returns a parser for the output of a new Blender process.
+func New() *Parser {
+	return &Parser{
+		handlers: make(map[string]bool),
+	}
+}
+
 // ParseBlenderEvent parses Blender output and returns a BlenderEvent.
 func ParseBlenderEvent(output string) (types.BlenderEvent, error) {
+	return New().Parse(output)
+}
+
+// Parse parses a line of Blender output and returns a BlenderEvent.
+func (p *Parser) Parse(output string) (types.BlenderEvent, error) {
 	if output == "" {
 		return nil, fmt.Errorf("output is empty")
 	}
 
-	if event, err := parseQuitEvent(output); err == nil {
+	if data, ok := strings.CutPrefix(strings.TrimSpace(output), EventPrefix); ok {
+		event, err := parseStructuredEvent(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if ready, ok := event.(*types.ReadyEvent); ok {
+			for _, handler := range ready.Handlers {
+				p.handlers[handler] = true
+			}
+		}
+
 		return event, nil
 	}
 
-	if event, err := parseSavedFileEvent(output); err == nil {
+	if event, err := parseQuitEvent(output); err == nil {
 		return event, nil
 	}
 
-	if event, err := parseEeveeBlenderEvent(output); err == nil {
-		return event, nil
+	if !p.handlers[handlerRenderWrite] {
+		if event, err := parseSavedFileEvent(output); err == nil {
+			return event, nil
+		}
 	}
 
-	if event, err := parseCyclesBlenderEvent(output); err == nil {
-		return event, nil
+	if !p.handlers[handlerRenderStats] {
+		if event, err := parseEeveeBlenderEvent(output); err == nil {
+			return event, nil
+		}
+
+		if event, err := parseCyclesBlenderEvent(output); err == nil {
+			return event, nil
+		}
 	}
 
 	return nil, errors.New("could not parse output")
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestParseStructuredEvent(t *testing.T) {
+	tests := []struct {
+		output string
+		want   types.BlenderEvent
+	}{
+		{
+			`ROCKETBLEND_EVENT {"type": "ready", "version": "4.2.0", "handlers": ["render_pre", "render_stats"]}`,
+			&types.ReadyEvent{Version: "4.2.0", Handlers: []string{"render_pre", "render_stats"}},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_pre", "frame": 3}`,
+			&types.RenderStartEvent{Frame: 3},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_post", "frame": 3}`,
+			&types.RenderCompleteEvent{Frame: 3},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_cancel", "frame": 4}`,
+			&types.RenderCancelEvent{Frame: 4},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_write", "frame": 3, "path": "/output/shot-00003.png"}`,
+			&types.SavedFileEvent{Path: "/output/shot-00003.png", Frame: 3},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "save_post", "path": "/project/shot.blend"}`,
+			&types.BlendFileSavedEvent{Path: "/project/shot.blend"},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "devices", "backends": [{"type": "cpu", "devices": [{"id": "CPU", "name": "AMD Ryzen 9", "type": "CPU"}]}, {"type": "optix", "devices": []}]}`,
+			&types.DevicesEvent{Backends: []types.ComputeBackend{
+				{Type: types.DeviceCPU, Devices: []types.ComputeDevice{{ID: "CPU", Name: "AMD Ryzen 9", Type: "CPU"}}},
+				{Type: types.DeviceOptiX, Devices: []types.ComputeDevice{}},
+			}},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_stats", "frame": 3, "stats": "Fra:3 Mem:154.38M (Peak 155.13M) | Time:00:01.23 | Mem:30.18M, Peak:30.18M | Scene, ViewLayer | Sample 12/128"}`,
+			&types.RenderingEvent{
+				RenderBase: types.RenderBase{Frame: 3, Memory: "154.38m", PeakMemory: "155.13m", Time: "00:01.23"},
+				Current:    12,
+				Total:      128,
+				Operation:  "rendering",
+			},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_stats", "frame": 1, "stats": "Fra:1 Mem:120.50M (Peak 130.00M) | Time:00:02.11 | Rendering 12 / 64 samples"}`,
+			&types.RenderingEvent{
+				RenderBase: types.RenderBase{Frame: 1, Memory: "120.50m", PeakMemory: "130.00m", Time: "00:02.11"},
+				Current:    12,
+				Total:      64,
+				Operation:  "rendering",
+			},
+		},
+		{
+			`ROCKETBLEND_EVENT {"type": "render_stats", "frame": 1, "stats": "Fra:1 Mem:20.00M (Peak 20.00M) | Time:00:00.10 | Loading render kernels"}`,
+			&types.UpdatingEvent{
+				RenderBase: types.RenderBase{Frame: 1, Memory: "20.00m", PeakMemory: "20.00m", Time: "00:00.10"},
+				Details:    "loading render kernels",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := ParseBlenderEvent(test.output)
+		if err != nil {
+			t.Errorf("ParseBlenderEvent(%q) returned unexpected error: %v", test.output, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseBlenderEvent(%q) = %#v, want %#v", test.output, got, test.want)
+		}
+	}
+}
+
+func TestParseStructuredEventInvalid(t *testing.T) {
+	for _, output := range []string{
+		`ROCKETBLEND_EVENT {"type": "render_pre", "frame": `,
+		`ROCKETBLEND_EVENT {"type": "load_post"}`,
+		`ROCKETBLEND_EVENT {"frame": 1}`,
+	} {
+		if _, err := ParseBlenderEvent(output); err == nil {
+			t.Errorf("ParseBlenderEvent(%q) did not return an error as expected", output)
+		}
+	}
+}
+
+func TestParseFallback(t *testing.T) {
+	tests := []struct {
+		output string
+		want   types.BlenderEvent
+	}{
+		{
+			"Fra:1 Mem:154.38M (Peak 155.13M) | Time:00:01.23 | Mem:30.18M, Peak:30.18M | Sample 12/128",
+			&types.RenderingEvent{
+				RenderBase: types.RenderBase{Frame: 1, Memory: "154.38m", PeakMemory: "155.13m", Time: "00:01.23"},
+				Current:    12,
+				Total:      128,
+				Operation:  "rendering",
+			},
+		},
+		{
+			"Saved: '/output/shot-00001.png'",
+			&types.SavedFileEvent{Path: "/output/shot-00001.png"},
+		},
+		{
+			"Blender quit",
+			&types.QuitEvent{},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := ParseBlenderEvent(test.output)
+		if err != nil {
+			t.Errorf("ParseBlenderEvent(%q) returned unexpected error: %v", test.output, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseBlenderEvent(%q) = %#v, want %#v", test.output, got, test.want)
+		}
+	}
+}
+
+func TestParserSkipsHandledOutput(t *testing.T) {
+	p := New()
+	if _, err := p.Parse(`ROCKETBLEND_EVENT {"type": "ready", "handlers": ["render_pre", "render_stats", "render_write"]}`); err != nil {
+		t.Fatalf("Parse() returned unexpected error: %v", err)
+	}
+
+	for _, output := range []string{
+		"Fra:1 Mem:154.38M (Peak 155.13M) | Time:00:01.23 | Mem:30.18M, Peak:30.18M | Sample 12/128",
+		"Saved: '/output/shot-00001.png'",
+	} {
+		if event, err := p.Parse(output); err == nil {
+			t.Errorf("Parse(%q) = %#v, want it left to the handlers", output, event)
+		}
+	}
+
+	if event, err := p.Parse("Blender quit"); err != nil || !reflect.DeepEqual(event, &types.QuitEvent{}) {
+		t.Errorf("Parse(%q) = %#v, %v, want quit event", "Blender quit", event, err)
+	}
+}
+
+func TestParserFallsBackForMissingHandlers(t *testing.T) {
+	p := New()
+	if _, err := p.Parse(`ROCKETBLEND_EVENT {"type": "ready", "handlers": ["render_pre", "render_post"]}`); err != nil {
+		t.Fatalf("Parse() returned unexpected error: %v", err)
+	}
+
+	event, err := p.Parse("Saved: '/output/shot-00001.png'")
+	if err != nil {
+		t.Fatalf("Parse() returned unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(event, &types.SavedFileEvent{Path: "/output/shot-00001.png"}) {
+		t.Errorf("Parse() = %#v, want saved file event", event)
+	}
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// EventPrefix starts every line written by the injected events script.
+const EventPrefix = "ROCKETBLEND_EVENT "
+
+const (
+	handlerReady        = "ready"
+	handlerRenderPre    = "render_pre"
+	handlerRenderPost   = "render_post"
+	handlerRenderStats  = "render_stats"
+	handlerRenderWrite  = "render_write"
+	handlerRenderCancel = "render_cancel"
+	handlerSavePost     = "save_post"
+	handlerDevices      = "devices"
+)
+
+var (
+	statsMemoryPattern = regexp.MustCompile(`Mem:\s*([0-9.]+[MKG]?)`)
+	statsPeakPattern   = regexp.MustCompile(`Peak:?\s*([0-9.]+[MKG]?)`)
+	statsTimePattern   = regexp.MustCompile(`Time:\s*([0-9:.]+)`)
+)
+
+type (
+	// structuredEvent is a line written by the injected events script.
+	structuredEvent struct {
+		Type     string                 `json:"type"`
+		Frame    int                    `json:"frame"`
+		Path     string                 `json:"path"`
+		Stats    string                 `json:"stats"`
+		Version  string                 `json:"version"`
+		Handlers []string               `json:"handlers"`
+		Backends []types.ComputeBackend `json:"backends"`
+	}
+)
+
+func parseStructuredEvent(data string) (types.BlenderEvent, error) {
+	var event structuredEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return nil, fmt.Errorf("could not decode event: %w", err)
+	}
+
+	switch event.Type {
+	case handlerReady:
+		return &types.ReadyEvent{
+			Version:  event.Version,
+			Handlers: event.Handlers,
+		}, nil
+	case handlerRenderPre:
+		return &types.RenderStartEvent{Frame: event.Frame}, nil
+	case handlerRenderPost:
+		return &types.RenderCompleteEvent{Frame: event.Frame}, nil
+	case handlerRenderStats:
+		return parseStats(event.Frame, event.Stats), nil
+	case handlerRenderWrite:
+		return &types.SavedFileEvent{
+			Path:  event.Path,
+			Frame: event.Frame,
+		}, nil
+	case handlerRenderCancel:
+		return &types.RenderCancelEvent{Frame: event.Frame}, nil
+	case handlerSavePost:
+		return &types.BlendFileSavedEvent{Path: event.Path}, nil
+	case handlerDevices:
+		return &types.DevicesEvent{Backends: event.Backends}, nil
+	default:
+		return nil, fmt.Errorf("unknown event type: %q", event.Type)
+	}
+}
+
+// parseStats reads the statistics Blender reports while rendering a frame, such as
+// "Fra:1 Mem:154.38M (Peak 155.13M) | Time:00:01.23 | Mem:30.18M, Peak:30.18M | Scene, ViewLayer | Sample 12/128".
+// Fields are found by their labels, as which are present and their order differ between engines and versions,
+// and the operation is always the last one.
+func parseStats(frame int, stats string) types.BlenderEvent {
+	base := types.RenderBase{
+		Frame:      frame,
+		Memory:     findStat(statsMemoryPattern, stats),
+		PeakMemory: findStat(statsPeakPattern, stats),
+		Time:       findStat(statsTimePattern, stats),
+	}
+
+	segments := strings.Split(stats, "|")
+	operation := strings.ToLower(strings.TrimSpace(segments[len(segments)-1]))
+	if event, _ := createRenderEventFromOperation(operation, base); event != nil {
+		return event
+	}
+
+	return &types.UpdatingEvent{
+		RenderBase: base,
+		Details:    operation,
+	}
+}
+
+func findStat(pattern *regexp.Regexp, stats string) string {
+	match := pattern.FindStringSubmatch(stats)
+	if len(match) != 2 {
+		return ""
+	}
+
+	return strings.ToLower(match[1])
+}
@@ -20,6 +20,7 @@ func (b *Blender) Render(ctx context.Context, opts *types.RenderOpts) error {
 	arguments := arguments{
 		Background:    opts.Background,
 		BlendFilePath: opts.BlendFile.Path,
+		Scripts:       []string{eventsScript()},
 		Render: &renderArguments{
 			Start:   opts.Start,
 			End:     opts.End,
@@ -27,10 +28,34 @@ func (b *Blender) Render(ctx context.Context, opts *types.RenderOpts) error {
 			Output:  opts.Output,
 			Format:  RenderFormat(opts.Format),
 			Threads: opts.Threads,
+			Devices: convertDevices(opts.Devices),
 			Engine:  convertRenderEngine(opts.Engine),
+			Scene:   opts.Scene,
 		},
 	}
 
+	overrides := renderOverrides{
+		Engine:               convertRenderEngine(opts.Engine),
+		ResolutionX:          opts.ResolutionX,
+		ResolutionY:          opts.ResolutionY,
+		ResolutionPercentage: opts.ResolutionPercentage,
+		Samples:              opts.Samples,
+		Denoiser:             convertDenoiser(opts.Denoiser),
+		Camera:               opts.Camera,
+		ViewLayer:            opts.ViewLayer,
+		Output:               convertOutputSettings(opts.Format, opts.OutputSettings),
+	}
+
+	if overrides != (renderOverrides{Engine: overrides.Engine}) {
+		script, err := renderOverridesScript(&overrides)
+		if err != nil {
+			return err
+		}
+
+		arguments.Scripts = append(arguments.Scripts, script)
+		arguments.ExitOnError = true
+	}
+
 	b.logger.Info("rendering", map[string]interface{}{
 		"blendFile": opts.BlendFile.Path,
 		"output":    opts.Output,
@@ -39,11 +64,14 @@ func (b *Blender) Render(ctx context.Context, opts *types.RenderOpts) error {
 		"step":      opts.Step,
 		"format":    opts.Format,
 		"threads":   opts.Threads,
+		"devices":   opts.Devices,
 		"engine":    opts.Engine,
+		"overrides": overrides,
+		"scene":     opts.Scene,
 	})
 
 	if opts.BlendFile.Addons() != nil || opts.BlendFile.Strict {
-		arguments.Script = startupScript()
+		arguments.Scripts = append(arguments.Scripts, startupScript())
 		arguments.Rockeblend = &rocketblendArguments{
 			Addons: opts.BlendFile.Addons(),
 			Strict: opts.BlendFile.Strict,
@@ -53,7 +81,7 @@ func (b *Blender) Render(ctx context.Context, opts *types.RenderOpts) error {
 	outputChan := make(chan string, 100)
 	defer close(outputChan)
 
-	go processChannel(outputChan, opts.EventChan, b.processOutput)
+	go processChannel(outputChan, opts.EventChan, b.outputProcessor())
 
 	if err := b.execute(ctx, build.Path, &arguments, outputChan); err != nil {
 		return err
@@ -20,10 +20,11 @@ func (b *Blender) Run(ctx context.Context, opts *types.RunOpts) error {
 	arguments := arguments{
 		Background:    opts.Background,
 		BlendFilePath: opts.BlendFile.Path,
+		Scripts:       []string{eventsScript()},
 	}
 
 	if opts.BlendFile.Addons() != nil || opts.BlendFile.Strict {
-		arguments.Script = startupScript()
+		arguments.Scripts = append(arguments.Scripts, startupScript())
 		arguments.Rockeblend = &rocketblendArguments{
 			Addons: opts.BlendFile.Addons(),
 			Strict: opts.BlendFile.Strict,
@@ -33,7 +34,7 @@ func (b *Blender) Run(ctx context.Context, opts *types.RunOpts) error {
 	outputChan := make(chan string, 100)
 	defer close(outputChan)
 
-	go processChannel(outputChan, opts.EventChan, b.processOutput)
+	go processChannel(outputChan, opts.EventChan, b.outputProcessor())
 
 	if err := b.execute(ctx, build.Path, &arguments, outputChan); err != nil {
 		return err
@@ -1,6 +1,9 @@
 package blender
 
 import (
+	"encoding/json"
+	"strconv"
+
 	"github.com/rocketblend/rocketblend/pkg/helpers"
 	"github.com/rocketblend/rocketblend/pkg/python"
 )
@@ -9,6 +12,11 @@ type (
 	CreateBlendFileData struct {
 		FilePath string `json:"filePath"`
 	}
+
+	// RenderOverridesData holds the settings of the override script, as JSON quoted as a Python string.
+	RenderOverridesData struct {
+		Settings string `json:"settings"`
+	}
 )
 
 func createBlendFileScript(data *CreateBlendFileData) (string, error) {
@@ -20,6 +28,25 @@ func createBlendFileScript(data *CreateBlendFileData) (string, error) {
 	return result, nil
 }
 
+func renderOverridesScript(overrides *renderOverrides) (string, error) {
+	settings, err := json.Marshal(overrides)
+	if err != nil {
+		return "", err
+	}
+
+	return helpers.ParseTemplateWithData(python.OverridesScript, &RenderOverridesData{
+		Settings: strconv.Quote(string(settings)),
+	})
+}
+
 func startupScript() string {
 	return python.StartupScript
 }
+
+func eventsScript() string {
+	return python.EventsScript
+}
+
+func devicesScript() string {
+	return python.DevicesScript
+}
@@ -93,7 +93,11 @@ func New(opts ...Option) (*Configurator, error) {
 
 func (c *Configurator) Get() (*types.Config, error) {
 	var config types.Config
-	err := c.viper.Unmarshal(&config, viper.DecodeHook(platformHookFunc()))
+	err := c.viper.Unmarshal(&config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		platformHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)))
 	if err != nil {
 		return nil, err
 	}
@@ -181,6 +185,21 @@ func load(path string, name string, extension string) (*viper.Viper, error) {
 	v.SetDefault("installationsPath", filepath.Join(path, "installations"))
 	v.SetDefault("packagesPath", filepath.Join(path, "packages"))
 	v.SetDefault("aliases", types.DefaultAliases)
+	v.SetDefault("trustedKeys", []string{})
+	v.SetDefault("offline", false)
+	v.SetDefault("projects", []string{})
+	v.SetDefault("downloadChunks", 4)
+	v.SetDefault("maxConcurrentDownloads", 0)
+	v.SetDefault("maxDownloadRate", "")
+	v.SetDefault("retry::attempts", 3)
+	v.SetDefault("retry::backoff", "1s")
+	v.SetDefault("retry::maxBackoff", "30s")
+	v.SetDefault("retry::jitter", 0.2)
+	v.SetDefault("retry::statusCodes", []int{408, 425, 429, 500, 502, 503, 504})
+	v.SetDefault("packageSources", map[string]*types.PackageSourceConfig{})
+	v.SetDefault("network::proxy", "")
+	v.SetDefault("network::caBundle", "")
+	v.SetDefault("network::credentialsFile", filepath.Join(path, types.CredentialsFileName))
 
 	v.SetConfigName(name)      // Set the name of the configuration file
 	v.AddConfigPath(path)      // Look for the configuration file at the home directory
@@ -12,10 +12,15 @@ import (
 	"github.com/rocketblend/rocketblend/pkg/downloader"
 	"github.com/rocketblend/rocketblend/pkg/driver"
 	"github.com/rocketblend/rocketblend/pkg/extractor"
+	"github.com/rocketblend/rocketblend/pkg/farm"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/library"
 	"github.com/rocketblend/rocketblend/pkg/logger"
+	"github.com/rocketblend/rocketblend/pkg/network"
 	"github.com/rocketblend/rocketblend/pkg/repository"
 	"github.com/rocketblend/rocketblend/pkg/types"
 	"github.com/rocketblend/rocketblend/pkg/validator"
+	"github.com/rocketblend/rocketblend/pkg/verifier"
 )
 
 type (
@@ -31,24 +36,32 @@ type (
 		DownloadBuffer   int
 		ApplicationName  string
 		Development      bool
+		Offline          bool
+		MaxDownloadRate  string
 	}
 
 	Option func(*Options)
 
 	Container struct {
-		logger         types.Logger
-		validator      types.Validator
-		applicationDir string
+		logger          types.Logger
+		validator       types.Validator
+		applicationDir  string
+		offline         bool
+		maxDownloadRate string
 
 		downloadBuffer   int
 		progressInterval time.Duration
 
 		configuratorHolder *holder[configurator.Configurator]
+		networkHolder      *holder[network.Network]
 		downloaderHolder   *holder[downloader.Downloader]
 		extractorHolder    *holder[extractor.Extractor]
+		verifierHolder     *holder[verifier.Verifier]
 		repositoryHolder   *holder[repository.Repository]
 		driverHolder       *holder[driver.Driver]
 		blenderHolder      *holder[blender.Blender]
+		coordinatorHolder  *holder[farm.Coordinator]
+		workerHolder       *holder[farm.Worker]
 	}
 )
 
@@ -76,6 +89,20 @@ func WithDevelopmentMode(development bool) Option {
 	}
 }
 
+// WithOffline forces offline mode, regardless of the configuration.
+func WithOffline(offline bool) Option {
+	return func(o *Options) {
+		o.Offline = offline
+	}
+}
+
+// WithMaxDownloadRate caps the combined speed of all downloads, regardless of the configuration.
+func WithMaxDownloadRate(rate string) Option {
+	return func(o *Options) {
+		o.MaxDownloadRate = rate
+	}
+}
+
 func WithProgressInterval(interval time.Duration) Option {
 	return func(o *Options) {
 		o.ProgressInterval = interval
@@ -115,14 +142,20 @@ func New(opts ...Option) (*Container, error) {
 		logger:             options.Logger,
 		validator:          options.Validator,
 		applicationDir:     applicationDir,
+		offline:            options.Offline,
+		maxDownloadRate:    options.MaxDownloadRate,
 		downloadBuffer:     options.DownloadBuffer,
 		progressInterval:   options.ProgressInterval,
 		configuratorHolder: &holder[configurator.Configurator]{},
+		networkHolder:      &holder[network.Network]{},
 		downloaderHolder:   &holder[downloader.Downloader]{},
 		extractorHolder:    &holder[extractor.Extractor]{},
+		verifierHolder:     &holder[verifier.Verifier]{},
 		repositoryHolder:   &holder[repository.Repository]{},
 		driverHolder:       &holder[driver.Driver]{},
 		blenderHolder:      &holder[blender.Blender]{},
+		coordinatorHolder:  &holder[farm.Coordinator]{},
+		workerHolder:       &holder[farm.Worker]{},
 	}, nil
 }
 
@@ -142,6 +175,10 @@ func (f *Container) GetExtractor() (types.Extractor, error) {
 	return f.getExtractor()
 }
 
+func (f *Container) GetVerifier() (types.Verifier, error) {
+	return f.getVerifier()
+}
+
 func (f *Container) GetConfigurator() (types.Configurator, error) {
 	return f.getConfigurator()
 }
@@ -158,6 +195,14 @@ func (f *Container) GetBlender() (types.Blender, error) {
 	return f.getBlender()
 }
 
+func (f *Container) GetCoordinator() (types.Coordinator, error) {
+	return f.getCoordinator()
+}
+
+func (f *Container) GetWorker() (types.Worker, error) {
+	return f.getWorker()
+}
+
 func (f *Container) getConfigurator() (*configurator.Configurator, error) {
 	var err error
 	f.configuratorHolder.once.Do(func() {
@@ -174,13 +219,84 @@ func (f *Container) getConfigurator() (*configurator.Configurator, error) {
 	return f.configuratorHolder.instance, nil
 }
 
+func (f *Container) getNetwork() (*network.Network, error) {
+	var err error
+	f.networkHolder.once.Do(func() {
+		configurator, errConfig := f.getConfigurator()
+		if errConfig != nil {
+			err = errConfig
+			return
+		}
+
+		config, errConfig := configurator.Get()
+		if errConfig != nil {
+			err = errConfig
+			return
+		}
+
+		f.networkHolder.instance, err = network.New(
+			network.WithLogger(f.logger),
+			network.WithValidator(f.validator),
+			network.WithProxy(config.Network.Proxy),
+			network.WithCABundle(config.Network.CABundle),
+			network.WithCredentialsFile(config.Network.CredentialsFile),
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get/create network: %w", err)
+	}
+
+	return f.networkHolder.instance, nil
+}
+
 func (f *Container) getDownloader() (*downloader.Downloader, error) {
 	var err error
 	f.downloaderHolder.once.Do(func() {
+		configurator, errConfig := f.getConfigurator()
+		if errConfig != nil {
+			err = errConfig
+			return
+		}
+
+		config, errConfig := configurator.Get()
+		if errConfig != nil {
+			err = errConfig
+			return
+		}
+
+		rate := config.MaxDownloadRate
+		if f.maxDownloadRate != "" {
+			rate = f.maxDownloadRate
+		}
+
+		network, errNetwork := f.getNetwork()
+		if errNetwork != nil {
+			err = errNetwork
+			return
+		}
+
+		maxRate := int64(0)
+		if rate != "" {
+			if maxRate, err = helpers.ParseSize(rate); err != nil {
+				return
+			}
+		}
+
 		f.downloaderHolder.instance, err = downloader.New(
 			downloader.WithLogger(f.logger),
+			downloader.WithHTTPClient(network.Client()),
 			downloader.WithBufferSize(f.downloadBuffer),
 			downloader.WithUpdateInterval(f.progressInterval),
+			downloader.WithOffline(f.offline || config.Offline),
+			downloader.WithRetryPolicy(downloader.RetryPolicy{
+				Attempts:    config.Retry.Attempts,
+				Backoff:     config.Retry.Backoff,
+				MaxBackoff:  config.Retry.MaxBackoff,
+				Jitter:      config.Retry.Jitter,
+				StatusCodes: config.Retry.StatusCodes,
+			}),
+			downloader.WithChunks(config.DownloadChunks, downloader.DefaultMinChunkSize),
+			downloader.WithScheduler(downloader.NewScheduler(config.MaxConcurrentDownloads, maxRate)),
 		)
 	})
 	if err != nil {
@@ -195,7 +311,7 @@ func (f *Container) getExtractor() (*extractor.Extractor, error) {
 	f.extractorHolder.once.Do(func() {
 		f.extractorHolder.instance, err = extractor.New(
 			extractor.WithLogger(f.logger),
-			extractor.WithCleanup(),
+			extractor.WithUpdateInterval(f.progressInterval),
 		)
 	})
 	if err != nil {
@@ -205,6 +321,34 @@ func (f *Container) getExtractor() (*extractor.Extractor, error) {
 	return f.extractorHolder.instance, nil
 }
 
+func (f *Container) getVerifier() (*verifier.Verifier, error) {
+	var err error
+	f.verifierHolder.once.Do(func() {
+		configurator, errConfig := f.getConfigurator()
+		if errConfig != nil {
+			err = errConfig
+			return
+		}
+
+		config, errConfig := configurator.Get()
+		if errConfig != nil {
+			err = errConfig
+			return
+		}
+
+		f.verifierHolder.instance, err = verifier.New(
+			verifier.WithLogger(f.logger),
+			verifier.WithValidator(f.validator),
+			verifier.WithKeyFiles(config.TrustedKeys...),
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get/create verifier: %w", err)
+	}
+
+	return f.verifierHolder.instance, nil
+}
+
 func (f *Container) getRepository() (*repository.Repository, error) {
 	var err error
 	f.repositoryHolder.once.Do(func() {
@@ -232,7 +376,7 @@ func (f *Container) getRepository() (*repository.Repository, error) {
 			return
 		}
 
-		f.repositoryHolder.instance, err = repository.New(
+		options := []repository.Option{
 			repository.WithLogger(f.logger),
 			repository.WithValidator(f.validator),
 			repository.WithDownloader(downloader),
@@ -240,7 +384,31 @@ func (f *Container) getRepository() (*repository.Repository, error) {
 			repository.WithPackagePath(config.PackagesPath),
 			repository.WithInstallationPath(config.InstallationsPath),
 			repository.WithPlatform(config.Platform),
-		)
+			repository.WithOffline(f.offline || config.Offline),
+		}
+
+		sources, errSources := f.getPackageSources(config)
+		if errSources != nil {
+			err = errSources
+			return
+		}
+
+		for prefix, source := range sources {
+			options = append(options, repository.WithSource(prefix, source))
+		}
+
+		// Signature verification is only enforced once trusted keys are configured.
+		if len(config.TrustedKeys) > 0 {
+			verifier, errVerifier := f.getVerifier()
+			if errVerifier != nil {
+				err = errVerifier
+				return
+			}
+
+			options = append(options, repository.WithVerifier(verifier))
+		}
+
+		f.repositoryHolder.instance, err = repository.New(options...)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get/create repository: %w", err)
@@ -249,6 +417,49 @@ func (f *Container) getRepository() (*repository.Repository, error) {
 	return f.repositoryHolder.instance, nil
 }
 
+// getPackageSources creates the package sources configured for reference prefixes. Libraries that do not
+// match any prefix are cloned with git.
+func (f *Container) getPackageSources(config *types.Config) (map[string]types.PackageSource, error) {
+	offline := f.offline || config.Offline
+
+	network, err := f.getNetwork()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultSource, err := library.NewGit(
+		library.WithLogger(f.logger),
+		library.WithValidator(f.validator),
+		library.WithGitTransport(network),
+		library.WithOffline(offline),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[string]types.PackageSource{
+		"": defaultSource,
+	}
+
+	for prefix, sourceConfig := range config.PackageSources {
+		source, err := library.New(sourceConfig,
+			library.WithLogger(f.logger),
+			library.WithValidator(f.validator),
+			library.WithLocation(prefix, sourceConfig.URL),
+			library.WithHTTPClient(network.Client()),
+			library.WithGitTransport(network),
+			library.WithOffline(offline),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("package source %q: %w", prefix, err)
+		}
+
+		sources[prefix] = source
+	}
+
+	return sources, nil
+}
+
 func (f *Container) getDriver() (*driver.Driver, error) {
 	var err error
 	f.driverHolder.once.Do(func() {
@@ -286,6 +497,43 @@ func (f *Container) getBlender() (*blender.Blender, error) {
 	return f.blenderHolder.instance, nil
 }
 
+func (f *Container) getCoordinator() (*farm.Coordinator, error) {
+	var err error
+	f.coordinatorHolder.once.Do(func() {
+		f.coordinatorHolder.instance, err = farm.NewCoordinator(
+			farm.WithLogger(f.logger),
+			farm.WithValidator(f.validator),
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get/create coordinator: %w", err)
+	}
+
+	return f.coordinatorHolder.instance, nil
+}
+
+func (f *Container) getWorker() (*farm.Worker, error) {
+	var err error
+	f.workerHolder.once.Do(func() {
+		blender, errBlender := f.getBlender()
+		if errBlender != nil {
+			err = errBlender
+			return
+		}
+
+		f.workerHolder.instance, err = farm.NewWorker(
+			farm.WithLogger(f.logger),
+			farm.WithValidator(f.validator),
+			farm.WithBlender(blender),
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get/create worker: %w", err)
+	}
+
+	return f.workerHolder.instance, nil
+}
+
 func setupApplicationDir(name string, development bool) (string, error) {
 	userConfigDir, err := os.UserConfigDir()
 	if err != nil {
@@ -0,0 +1,375 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/taskrunner"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+var errRangesUnsupported = errors.New("server does not support range requests")
+
+type (
+	// chunk is a byte range of the file, End is inclusive.
+	chunk struct {
+		Start   int64 `json:"start"`
+		End     int64 `json:"end"`
+		Written int64 `json:"written"`
+	}
+)
+
+func (c *chunk) remaining() int64 {
+	return c.End - c.Start + 1 - c.Written
+}
+
+// downloadChunked downloads a remote file with concurrent range requests into a preallocated temp file. It
+// reports false, without downloading anything, if the file should be downloaded as a single stream instead.
+func (d *Downloader) downloadChunked(ctx context.Context, uri *types.URI, tempPath string, attempt int, progress chan<- types.Progress) (bool, error) {
+	state, err := loadState(tempPath)
+	if err != nil {
+		return true, err
+	}
+
+	// A partial single stream download carries on as one.
+	if (state == nil || len(state.Chunks) == 0) && d.checkFileSize(tempPath) > 0 {
+		return false, nil
+	}
+
+	remote, ranges, err := d.probe(ctx, uri)
+	if err != nil {
+		return true, err
+	}
+	remote.Host = uri.Host
+
+	size := remote.Size
+
+	chunks := d.chunks
+	if size/d.minChunkSize < int64(chunks) {
+		chunks = int(size / d.minChunkSize)
+	}
+
+	if !ranges || chunks < 2 {
+		if state != nil && len(state.Chunks) > 0 {
+			if err := discardDownload(tempPath); err != nil {
+				return true, err
+			}
+		}
+
+		return false, nil
+	}
+
+	// The remote file changed since the download started.
+	if state != nil && (len(state.Chunks) == 0 || !state.matches(remote)) {
+		d.logger.Debug("remote file changed, restarting chunked download", map[string]interface{}{
+			"uri":      uri.String(),
+			"previous": state.Size,
+			"size":     size,
+			"etag":     remote.ETag,
+		})
+
+		state = nil
+	}
+
+	if state == nil {
+		if state, err = newDownloadState(tempPath, remote, chunks); err != nil {
+			return true, err
+		}
+	}
+
+	file, err := os.OpenFile(tempPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return true, err
+	}
+	defer file.Close()
+
+	d.logger.Debug("starting chunked download", map[string]interface{}{
+		"uri":    uri.String(),
+		"size":   size,
+		"chunks": len(state.Chunks),
+	})
+
+	if err := d.runChunks(ctx, uri, file, state, tempPath, attempt, progress); err != nil {
+		if errors.Is(err, errRangesUnsupported) {
+			file.Close()
+			if err := discardDownload(tempPath); err != nil {
+				return true, err
+			}
+
+			return false, nil
+		}
+
+		return true, err
+	}
+
+	return true, nil
+}
+
+// runChunks downloads the remaining chunks concurrently, saving their progress periodically and when finished.
+func (d *Downloader) runChunks(ctx context.Context, uri *types.URI, file *os.File, state *downloadState, tempPath string, attempt int, progress chan<- types.Progress) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	written := func() int64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		total := int64(0)
+		for _, c := range state.Chunks {
+			total += c.Written
+		}
+
+		return total
+	}
+
+	save := func() error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return saveState(tempPath, state)
+	}
+
+	report := func(current int64, speed float64) {
+		if progress != nil {
+			progress <- types.Progress{
+				Current: current,
+				Total:   state.Size,
+				Speed:   speed,
+				URI:     uri.String(),
+				Attempt: attempt,
+			}
+		}
+	}
+
+	// The transfer slot the download holds is passed between its chunks.
+	held := make(chan struct{}, 1)
+	held <- struct{}{}
+
+	// The first error cancels the other chunks, which then fail with context errors of their own.
+	var firstErr error
+	var errOnce sync.Once
+	tasks := make([]taskrunner.Task[struct{}], 0, len(state.Chunks))
+	for _, c := range state.Chunks {
+		if c.remaining() == 0 {
+			continue
+		}
+
+		tasks = append(tasks, func(ctx context.Context) (struct{}, error) {
+			err := d.scheduledChunk(ctx, uri, file, c, state.ifRange(), &mutex, held)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+
+			return struct{}{}, err
+		})
+	}
+
+	report(written(), 0)
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	done := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+
+		ticker := time.NewTicker(d.updateInterval)
+		defer ticker.Stop()
+
+		last, lastTime := written(), time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				current := written()
+				report(current, float64(current-last)/now.Sub(lastTime).Seconds())
+				last, lastTime = current, now
+
+				if err := save(); err != nil {
+					d.logger.Error("failed to save download state", map[string]interface{}{"error": err, "path": tempPath})
+				}
+			}
+		}
+	}()
+
+	start := time.Now()
+	initial := written()
+	_, err := taskrunner.Run(ctx, &taskrunner.RunOpts[struct{}]{
+		Tasks: tasks,
+		Mode:  taskrunner.Concurrent,
+	})
+	close(done)
+	<-reported
+
+	if saveErr := save(); saveErr != nil {
+		return saveErr
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	current := written()
+	report(current, float64(current-initial)/time.Since(start).Seconds())
+
+	return nil
+}
+
+// scheduledChunk downloads a chunk once it has a transfer slot, as every chunk opens a connection of its own.
+// It uses the slot held by the download when it is free, and waits for another one otherwise.
+func (d *Downloader) scheduledChunk(ctx context.Context, uri *types.URI, file *os.File, c *chunk, ifRange string, mutex *sync.Mutex, held chan struct{}) error {
+	release, err := d.scheduler.acquireShared(ctx, held)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return d.downloadChunk(ctx, uri, file, c, ifRange, mutex)
+}
+
+// downloadChunk downloads the rest of a chunk, writing it at its offset in the file. The range is only served
+// if the remote file still matches the If-Range validator.
+func (d *Downloader) downloadChunk(ctx context.Context, uri *types.URI, file *os.File, c *chunk, ifRange string, mutex *sync.Mutex) error {
+	mutex.Lock()
+	offset := c.Start + c.Written
+	mutex.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, c.End))
+	if ifRange != "" {
+		req.Header.Set("If-Range", ifRange)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The whole file is sent when the server ignores ranges or the file changed, either way the chunks are
+	// useless.
+	if resp.StatusCode == http.StatusOK {
+		return errRangesUnsupported
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return &statusError{uri: uri.String(), statusCode: resp.StatusCode, status: resp.Status}
+	}
+
+	body := d.scheduler.reader(ctx, resp.Body)
+	buffer := make([]byte, d.bufferSize)
+	for {
+		n, readErr := body.Read(buffer)
+		if n > 0 {
+			mutex.Lock()
+			remaining := c.remaining()
+			mutex.Unlock()
+
+			if int64(n) > remaining {
+				return fmt.Errorf("received more data than requested for range %d-%d", c.Start, c.End)
+			}
+
+			if _, err := file.WriteAt(buffer[:n], offset); err != nil {
+				return err
+			}
+
+			offset += int64(n)
+			mutex.Lock()
+			c.Written += int64(n)
+			mutex.Unlock()
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if c.remaining() != 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
+
+// probe returns the size and validators of a remote file and whether the server accepts range requests for it.
+func (d *Downloader) probe(ctx context.Context, uri *types.URI) (*downloadState, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uri.String(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	resp.Body.Close()
+
+	remote := newStateFromResponse(resp, resp.ContentLength)
+
+	// Let the single stream download deal with unexpected responses.
+	if resp.StatusCode != http.StatusOK || resp.ContentLength <= 0 {
+		return remote, false, nil
+	}
+
+	return remote, resp.Header.Get("Accept-Ranges") == "bytes", nil
+}
+
+// newDownloadState preallocates the temp file and splits it into chunks of about equal size.
+func newDownloadState(tempPath string, remote *downloadState, chunks int) (*downloadState, error) {
+	size := remote.Size
+
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := file.Truncate(size); err != nil {
+		return nil, err
+	}
+
+	state := &downloadState{
+		Size:         size,
+		ETag:         remote.ETag,
+		LastModified: remote.LastModified,
+		Host:         remote.Host,
+		Chunks:       make([]*chunk, 0, chunks),
+	}
+
+	chunkSize := size / int64(chunks)
+	for i := 0; i < chunks; i++ {
+		end := int64(i+1)*chunkSize - 1
+		if i == chunks-1 {
+			end = size - 1
+		}
+
+		state.Chunks = append(state.Chunks, &chunk{Start: int64(i) * chunkSize, End: end})
+	}
+
+	return state, saveState(tempPath, state)
+}
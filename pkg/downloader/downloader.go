@@ -2,11 +2,14 @@ package downloader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/rocketblend/rocketblend/pkg/logger"
@@ -14,7 +17,8 @@ import (
 )
 
 const (
-	TempFileExtension = ".tmp"
+	TempFileExtension   = ".tmp"
+	DefaultMinChunkSize = 8 << 20 // 8MB
 	// DownloadInfoFile  = "download-info.json"
 )
 
@@ -24,21 +28,65 @@ type (
 	// 	Size int64  `json:"size"`
 	// }
 
+	// RetryPolicy controls how failed downloads are retried. Each retry resumes from the partially downloaded
+	// file where the server supports it.
+	RetryPolicy struct {
+		Attempts    int           // Attempts per URI, including the first.
+		Backoff     time.Duration // Delay before the first retry, doubled after each further attempt.
+		MaxBackoff  time.Duration // Upper bound for the delay, zero for no bound.
+		Jitter      float64       // Fraction of the delay that is randomised, between 0 and 1.
+		StatusCodes []int         // HTTP status codes that are retried, other failed responses are not.
+	}
+
 	Options struct {
 		Logger         logger.Logger
+		Client         *http.Client
 		BufferSize     int
 		UpdateInterval time.Duration
+		Offline        bool
+		RetryPolicy    RetryPolicy
+		Chunks         int
+		MinChunkSize   int64
+		Scheduler      *Scheduler
 	}
 
 	Option func(*Options)
 
 	Downloader struct {
 		logger         logger.Logger
+		client         *http.Client
 		bufferSize     int
 		updateInterval time.Duration
+		offline        bool
+		retryPolicy    RetryPolicy
+		chunks         int
+		minChunkSize   int64
+		scheduler      *Scheduler
+	}
+
+	// statusError is returned for unexpected HTTP responses.
+	statusError struct {
+		uri        string
+		statusCode int
+		status     string
 	}
 )
 
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received non 200/206 status code for %s: %s", e.uri, e.status)
+}
+
+// DefaultRetryPolicy retries transient failures three times per URI, starting with a one second delay.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:    3,
+		Backoff:     time.Second,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+		StatusCodes: []int{408, 425, 429, 500, 502, 503, 504},
+	}
+}
+
 // With Logger sets the logger to use. The default is no-op.
 func WithLogger(logger logger.Logger) Option {
 	return func(o *Options) {
@@ -46,6 +94,13 @@ func WithLogger(logger logger.Logger) Option {
 	}
 }
 
+// WithHTTPClient sets the client used for remote files. The default is http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.Client = client
+	}
+}
+
 // WithBufferSize sets the buffer size for reading and writing. The default is 1MB.
 func WithBufferSize(bufferSize int) Option {
 	return func(o *Options) {
@@ -60,31 +115,90 @@ func WithUpdateInterval(updateInterval time.Duration) Option {
 	}
 }
 
+// WithOffline refuses to download remote files, only local files can be copied.
+func WithOffline(offline bool) Option {
+	return func(o *Options) {
+		o.Offline = offline
+	}
+}
+
+// WithRetryPolicy sets how failed downloads are retried. The default is DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *Options) {
+		o.RetryPolicy = policy
+	}
+}
+
+// WithChunks downloads remote files as up to the given number of concurrent range requests, each at least
+// minChunkSize bytes. Servers that do not accept range requests are downloaded as a single stream. The default
+// is a single stream.
+func WithChunks(chunks int, minChunkSize int64) Option {
+	return func(o *Options) {
+		o.Chunks = chunks
+		o.MinChunkSize = minChunkSize
+	}
+}
+
+// WithScheduler limits remote transfers with a scheduler, which may be shared with other downloaders. The
+// default is no limit.
+func WithScheduler(scheduler *Scheduler) Option {
+	return func(o *Options) {
+		o.Scheduler = scheduler
+	}
+}
+
 // New creates a new Downloader.
 func New(opts ...Option) (*Downloader, error) {
 	options := &Options{
 		Logger:         logger.NoOp(),
+		Client:         http.DefaultClient,
 		BufferSize:     1 << 20,         // Default buffer size is 1MB
 		UpdateInterval: 5 * time.Second, // Default update interval is 5 seconds
+		RetryPolicy:    DefaultRetryPolicy(),
+		Chunks:         1,
+		MinChunkSize:   DefaultMinChunkSize,
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
 
+	if options.Client == nil {
+		return nil, errors.New("http client is nil")
+	}
+
+	if options.RetryPolicy.Attempts < 1 {
+		return nil, errors.New("retry attempts must be at least 1")
+	}
+
+	if options.Chunks < 1 || options.MinChunkSize < 1 {
+		return nil, errors.New("chunks and minimum chunk size must be at least 1")
+	}
+
 	options.Logger.Debug("initialising Downloader", map[string]interface{}{
 		"bufferSize":      options.BufferSize,
 		"updateFrequency": options.UpdateInterval,
+		"offline":         options.Offline,
+		"retryAttempts":   options.RetryPolicy.Attempts,
+		"chunks":          options.Chunks,
+		"scheduled":       options.Scheduler != nil,
 	})
 
 	return &Downloader{
 		logger:         options.Logger,
+		client:         options.Client,
 		bufferSize:     options.BufferSize,
 		updateInterval: options.UpdateInterval,
+		offline:        options.Offline,
+		retryPolicy:    options.RetryPolicy,
+		chunks:         options.Chunks,
+		minChunkSize:   options.MinChunkSize,
+		scheduler:      options.Scheduler,
 	}, nil
 }
 
-// DownloadOpts contains the options for downloading a file
+// Download downloads the file at the URI, falling back to the mirrors in order. Each URI is retried according
+// to the retry policy, resuming from the partially downloaded file.
 func (d *Downloader) Download(ctx context.Context, opts *types.DownloadOpts) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -95,27 +209,228 @@ func (d *Downloader) Download(ctx context.Context, opts *types.DownloadOpts) err
 		return err
 	}
 
-	fileSize := d.checkFileSize(tempPath)
-	reader, contentLength, err := d.setupReader(ctx, opts.URI, fileSize)
+	uris := append([]*types.URI{opts.URI}, opts.Mirrors...)
+	errs := make([]error, 0, len(uris))
+	for i, uri := range uris {
+		// A partial download from another host cannot be resumed from the mirror.
+		if i > 0 && uris[i-1].Host != uri.Host {
+			if err := discardDownload(tempPath); err != nil {
+				return err
+			}
+		}
+
+		err := d.downloadWithRetry(ctx, uri, tempPath, opts.ProgressChan)
+		if err == nil {
+			if err := os.Rename(tempPath, opts.Path); err != nil {
+				return err
+			}
+
+			if err := os.Remove(tempPath + MetaFileExtension); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			d.logger.Debug("file successfully downloaded", map[string]interface{}{
+				"uri":  uri.String(),
+				"path": opts.Path,
+			})
+
+			return nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		errs = append(errs, err)
+		if len(errs) < len(uris) {
+			d.logger.Warn("download failed, trying next mirror", map[string]interface{}{
+				"uri":   uri.String(),
+				"error": err,
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// downloadWithRetry downloads the URI to the temp path, retrying failed attempts with an exponential backoff.
+func (d *Downloader) downloadWithRetry(ctx context.Context, uri *types.URI, tempPath string, progress chan<- types.Progress) error {
+	for attempt := 1; ; attempt++ {
+		err := d.scheduledDownload(ctx, uri, tempPath, attempt, progress)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= d.retryPolicy.Attempts || !d.retryable(uri, err) {
+			return err
+		}
+
+		delay := d.backoff(attempt)
+		d.logger.Warn("download attempt failed, retrying", map[string]interface{}{
+			"uri":     uri.String(),
+			"attempt": attempt,
+			"delay":   delay.String(),
+			"error":   err,
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// scheduledDownload waits for the scheduler to allow another remote transfer before making an attempt. The
+// transfer slot is not held while waiting to retry.
+func (d *Downloader) scheduledDownload(ctx context.Context, uri *types.URI, tempPath string, attempt int, progress chan<- types.Progress) error {
+	if !uri.IsRemote() {
+		return d.download(ctx, uri, tempPath, attempt, progress)
+	}
+
+	release, err := d.scheduler.acquire(ctx)
 	if err != nil {
 		return err
 	}
-	defer reader.Close()
+	defer release()
+
+	return d.download(ctx, uri, tempPath, attempt, progress)
+}
 
-	if err := d.writeToFile(ctx, tempPath, contentLength, reader, opts.ProgressChan); err != nil {
+// download makes a single attempt at downloading the URI, resuming from the temp file if possible.
+func (d *Downloader) download(ctx context.Context, uri *types.URI, tempPath string, attempt int, progress chan<- types.Progress) error {
+	if err := discardForeignDownload(tempPath, uri); err != nil {
 		return err
 	}
 
-	if err := os.Rename(tempPath, opts.Path); err != nil {
+	if d.chunks > 1 && uri.IsRemote() && !d.offline {
+		if chunked, err := d.downloadChunked(ctx, uri, tempPath, attempt, progress); chunked {
+			return err
+		}
+	}
+
+	state, err := loadState(tempPath)
+	if err != nil {
 		return err
 	}
 
-	d.logger.Debug("file successfully downloaded", map[string]interface{}{
-		"uri":  opts.URI.String(),
-		"path": opts.Path,
-	})
+	// The temp file of an abandoned chunked download is preallocated and cannot be resumed as a stream.
+	if state != nil && len(state.Chunks) > 0 {
+		if err := discardDownload(tempPath); err != nil {
+			return err
+		}
+
+		state = nil
+	}
+
+	fileSize := d.checkFileSize(tempPath)
+	reader, remote, resumed, err := d.setupReader(ctx, uri, fileSize, state)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.statusCode == http.StatusRequestedRangeNotSatisfiable && fileSize > 0 {
+		// The temp file does not fit the remote file, start over.
+		d.logger.Debug("range not satisfiable, restarting download", map[string]interface{}{"uri": uri.String(), "currentSize": fileSize})
+		if err := discardDownload(tempPath); err != nil {
+			return err
+		}
+
+		reader, remote, resumed, err = d.setupReader(ctx, uri, 0, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Servers that ignore If-Range still serve the range of a changed file, which must not be appended.
+	if resumed && state != nil && !state.matches(remote) {
+		reader.Close()
+		d.logger.Debug("remote file changed, restarting download", map[string]interface{}{
+			"uri":          uri.String(),
+			"etag":         remote.ETag,
+			"lastModified": remote.LastModified,
+			"size":         remote.Size,
+		})
+
+		if err := discardDownload(tempPath); err != nil {
+			return err
+		}
+
+		reader, remote, resumed, err = d.setupReader(ctx, uri, 0, nil)
+		if err != nil {
+			return err
+		}
+	}
+	defer reader.Close()
+
+	// The validators are saved before any data is written, so an interrupted download can be checked when resumed.
+	if uri.IsRemote() {
+		remote.Host = uri.Host
+		if err := saveState(tempPath, remote); err != nil {
+			return err
+		}
+	}
+
+	// The download starts over unless the reader continues where the temp file ends.
+	offset := int64(0)
+	if resumed {
+		offset = fileSize
+	}
+
+	report := func(current int64, speed float64) {
+		if progress == nil {
+			return
+		}
+
+		progress <- types.Progress{
+			Current: offset + current,
+			Total:   remote.Size,
+			Speed:   speed,
+			URI:     uri.String(),
+			Attempt: attempt,
+		}
+	}
+
+	// Report the start of every attempt.
+	report(0, 0)
+
+	var source io.Reader = reader
+	if uri.IsRemote() {
+		source = d.scheduler.reader(ctx, reader)
+	}
+
+	return d.writeToFile(ctx, tempPath, offset, source, report)
+}
 
-	return nil
+// retryable reports whether a failed attempt is worth retrying. Local files are never retried.
+func (d *Downloader) retryable(uri *types.URI, err error) bool {
+	if !uri.IsRemote() || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, types.ErrOffline) {
+		return false
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(d.retryPolicy.StatusCodes, statusErr.statusCode)
+	}
+
+	// Connection and mid-stream errors.
+	return true
+}
+
+// backoff returns the delay before retrying after the given attempt.
+func (d *Downloader) backoff(attempt int) time.Duration {
+	delay := d.retryPolicy.Backoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if d.retryPolicy.MaxBackoff > 0 && delay >= d.retryPolicy.MaxBackoff {
+			delay = d.retryPolicy.MaxBackoff
+			break
+		}
+	}
+
+	if d.retryPolicy.Jitter > 0 && delay > 0 {
+		spread := float64(delay) * d.retryPolicy.Jitter
+		delay += time.Duration(spread * (2*rand.Float64() - 1))
+	}
+
+	return delay
 }
 
 // checkFileSize checks the size of the file on disk, returning 0 if it doesn't exist
@@ -128,9 +443,14 @@ func (d *Downloader) checkFileSize(tempPath string) int64 {
 	return fileSize
 }
 
-// writeToFile writes the file to disk, updating progress as it goes
-func (d *Downloader) writeToFile(ctx context.Context, path string, contentLength int64, reader io.ReadCloser, progress chan<- types.Progress) error {
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+// writeToFile writes the file to disk from the offset, updating progress as it goes
+func (d *Downloader) writeToFile(ctx context.Context, path string, offset int64, reader io.Reader, report func(current int64, speed float64)) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
 	if err != nil {
 		return err
 	}
@@ -148,13 +468,13 @@ func (d *Downloader) writeToFile(ctx context.Context, path string, contentLength
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			lastTime = d.reportProgress(lastUpdateBytes, totalBytes, contentLength, progress, lastTime)
+			lastTime = d.reportProgress(lastUpdateBytes, totalBytes, report, lastTime)
 			lastUpdateBytes = totalBytes
 		default:
 			totalBytes, err = d.processRead(reader, f, buffer, totalBytes)
 			if err != nil {
 				if err == io.EOF {
-					d.reportProgress(lastUpdateBytes, totalBytes, contentLength, progress, lastTime)
+					d.reportProgress(lastUpdateBytes, totalBytes, report, lastTime)
 					return nil
 				}
 
@@ -164,19 +484,13 @@ func (d *Downloader) writeToFile(ctx context.Context, path string, contentLength
 	}
 }
 
-// reportProgress sends progress updates to the channel
-func (d *Downloader) reportProgress(lastUpdateBytes int64, totalBytes int64, totalSize int64, progress chan<- types.Progress, lastTime time.Time) time.Time {
+// reportProgress reports the bytes written and the speed since the last update
+func (d *Downloader) reportProgress(lastUpdateBytes int64, totalBytes int64, report func(current int64, speed float64), lastTime time.Time) time.Time {
 	now := time.Now()
 	timeElapsed := now.Sub(lastTime).Seconds()
 	speed := float64(totalBytes-lastUpdateBytes) / timeElapsed
 
-	if progress != nil {
-		progress <- types.Progress{
-			Current: totalBytes,
-			Total:   totalSize,
-			Speed:   speed,
-		}
-	}
+	report(totalBytes, speed)
 
 	return now
 }
@@ -194,56 +508,73 @@ func (d *Downloader) processRead(reader io.Reader, f *os.File, buffer []byte, to
 	return totalBytes, readErr
 }
 
-// setupReader sets up an io.ReadCloser based on whether the file is local or remote
-func (d *Downloader) setupReader(ctx context.Context, uri *types.URI, fileSize int64) (io.ReadCloser, int64, error) {
+// setupReader sets up an io.ReadCloser based on whether the file is local or remote. It returns the size and
+// validators of the file, and reports whether the reader resumes from the given file size.
+func (d *Downloader) setupReader(ctx context.Context, uri *types.URI, fileSize int64, state *downloadState) (io.ReadCloser, *downloadState, bool, error) {
 	if uri.IsRemote() {
-		return d.setupRemoteReader(ctx, uri, fileSize)
+		if d.offline {
+			return nil, nil, false, &types.OfflineError{Artifacts: []string{uri.String()}}
+		}
+
+		return d.setupRemoteReader(ctx, uri, fileSize, state)
 	}
 
 	if uri.IsLocal() {
-		return d.setupLocalReader(uri)
+		reader, size, err := d.setupLocalReader(uri)
+		return reader, &downloadState{Size: size}, false, err
 	}
 
-	return nil, 0, fmt.Errorf("unknown URI type: %s", uri.String())
+	return nil, nil, false, fmt.Errorf("unknown URI type: %s", uri.String())
 }
 
-// setupRemoteReader sets up an io.ReadCloser for a remote file
-func (d *Downloader) setupRemoteReader(ctx context.Context, uri *types.URI, fileSize int64) (io.ReadCloser, int64, error) {
+// setupRemoteReader sets up an io.ReadCloser for a remote file. A partial download is resumed with an If-Range
+// request, so the server sends the whole file instead if it changed since the state was saved.
+func (d *Downloader) setupRemoteReader(ctx context.Context, uri *types.URI, fileSize int64, state *downloadState) (io.ReadCloser, *downloadState, bool, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, 0, err
+		return nil, nil, false, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, false, err
 	}
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", fileSize))
+	if fileSize > 0 && state != nil && state.ifRange() != "" {
+		req.Header.Set("If-Range", state.ifRange())
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := d.client.Do(req)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, false, err
 	}
 
-	if resp.StatusCode != 200 && resp.StatusCode != 206 {
-		err = fmt.Errorf("received non 200/206 status code: %s", resp.Status)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		err := &statusError{uri: uri.String(), statusCode: resp.StatusCode, status: resp.Status}
 		d.logger.Error("received non 200/206 status code", map[string]interface{}{"err": err.Error()})
-		return nil, 0, err
+		return nil, nil, false, err
 	}
 
-	contentLength := resp.ContentLength
-	var resumed bool
-	if resp.StatusCode == 206 && fileSize > 0 {
-		resumed = true
+	// A 200 response ignores the range and sends the whole file.
+	resumed := resp.StatusCode == http.StatusPartialContent && fileSize > 0
+
+	size := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		size = contentRangeSize(resp.Header.Get("Content-Range"))
 	}
 
+	remote := newStateFromResponse(resp, size)
+
 	d.logger.Debug("http request successful", map[string]interface{}{
 		"status":        resp.Status,
-		"contentLength": contentLength,
+		"contentLength": resp.ContentLength,
+		"size":          size,
 		"currentSize":   fileSize,
 		"resumed":       resumed,
+		"etag":          remote.ETag,
 	})
 
-	return resp.Body, contentLength, nil
+	return resp.Body, remote, resumed, nil
 }
 
 // setupLocalReader sets up an io.ReadCloser for a local file
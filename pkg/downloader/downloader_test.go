@@ -0,0 +1,481 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func newTestDownloader(t *testing.T) *Downloader {
+	t.Helper()
+
+	policy := DefaultRetryPolicy()
+	policy.Backoff = time.Millisecond
+	policy.Jitter = 0
+
+	d, err := New(WithRetryPolicy(policy), WithUpdateInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func newTestURI(t *testing.T, raw string) *types.URI {
+	t.Helper()
+
+	uri, err := types.NewURI(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return uri
+}
+
+func collectProgress(progress chan types.Progress) func() []types.Progress {
+	done := make(chan []types.Progress)
+	go func() {
+		var updates []types.Progress
+		for p := range progress {
+			updates = append(updates, p)
+		}
+
+		done <- updates
+	}()
+
+	return func() []types.Progress {
+		close(progress)
+		return <-done
+	}
+}
+
+func TestDownloadRetry(t *testing.T) {
+	content := strings.Repeat("rocketblend", 100)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	progress := make(chan types.Progress)
+	wait := collectProgress(progress)
+
+	err := newTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:          newTestURI(t, server.URL+"/file"),
+		Path:         path,
+		ProgressChan: progress,
+	})
+	updates := wait()
+	if err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != content {
+		t.Errorf("Download() content length = %d, want %d", len(data), len(content))
+	}
+
+	if last := updates[len(updates)-1]; last.Attempt != 3 || last.Current != int64(len(content)) {
+		t.Errorf("Download() last progress = %+v, want attempt 3 with %d bytes", last, len(content))
+	}
+}
+
+func TestDownloadMirror(t *testing.T) {
+	content := "mirrored"
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer primary.Close()
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer mirror.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := newTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:     newTestURI(t, primary.URL+"/file"),
+		Mirrors: []*types.URI{newTestURI(t, mirror.URL+"/file")},
+		Path:    path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != content {
+		t.Errorf("Download() content = %q, want %q", data, content)
+	}
+}
+
+func TestDownloadMirrorRestarts(t *testing.T) {
+	content := "0123456789abcdef"
+
+	// The primary breaks off the transfer and then stops serving the file, leaving a partial download behind.
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rng := r.Header.Get("Range"); rng != "" && rng != "bytes=0-" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write([]byte("primary"))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	defer primary.Close()
+
+	var ranges []string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer mirror.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := newTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:     newTestURI(t, primary.URL+"/file"),
+		Mirrors: []*types.URI{newTestURI(t, mirror.URL+"/file")},
+		Path:    path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != content {
+		t.Errorf("Download() content = %q, want %q", data, content)
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=0-" {
+		t.Errorf("Download() mirror ranges = %v, want the whole file", ranges)
+	}
+}
+
+func TestDownloadResumeOtherHost(t *testing.T) {
+	content := "0123456789abcdef"
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	// The partial download was started from a mirror by an earlier run.
+	path := filepath.Join(t.TempDir(), "file")
+	tempPath := path + TempFileExtension
+	if err := os.WriteFile(tempPath, []byte("mirror"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveState(tempPath, &downloadState{Size: int64(len(content)), Host: "mirror.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:  newTestURI(t, server.URL+"/file"),
+		Path: path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != content {
+		t.Errorf("Download() content = %q, want %q", data, content)
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=0-" {
+		t.Errorf("Download() ranges = %v, want the whole file", ranges)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := "0123456789abcdef"
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path+TempFileExtension, []byte(content[:6]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:  newTestURI(t, server.URL+"/file"),
+		Path: path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != content {
+		t.Errorf("Download() content = %q, want %q", data, content)
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=6-" {
+		t.Errorf("Download() ranges = %v, want [bytes=6-]", ranges)
+	}
+}
+
+func TestDownloadResumeChanged(t *testing.T) {
+	content := "fedcba9876543210"
+
+	tests := []struct {
+		name          string
+		ignoreIfRange bool
+	}{
+		{name: "if-range"},
+		{name: "ignored if-range", ignoreIfRange: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ifRanges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+				if tt.ignoreIfRange {
+					r.Header.Del("If-Range")
+				}
+
+				w.Header().Set("ETag", `"v2"`)
+				http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+			}))
+			defer server.Close()
+
+			// The partial download belongs to an older version of the file.
+			path := filepath.Join(t.TempDir(), "file")
+			tempPath := path + TempFileExtension
+			if err := os.WriteFile(tempPath, []byte("012345"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := saveState(tempPath, &downloadState{Size: int64(len(content)), ETag: `"v1"`}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := newTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+				URI:  newTestURI(t, server.URL+"/file"),
+				Path: path,
+			}); err != nil {
+				t.Fatalf("Download() unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != content {
+				t.Errorf("Download() content = %q, want %q", data, content)
+			}
+
+			if len(ifRanges) == 0 || ifRanges[0] != `"v1"` {
+				t.Errorf("Download() If-Range headers = %v, want first to be %q", ifRanges, `"v1"`)
+			}
+
+			if _, err := os.Stat(tempPath + MetaFileExtension); !os.IsNotExist(err) {
+				t.Errorf("Download() left download state behind, stat error = %v", err)
+			}
+		})
+	}
+}
+
+func newChunkedTestDownloader(t *testing.T) *Downloader {
+	t.Helper()
+
+	policy := DefaultRetryPolicy()
+	policy.Backoff = time.Millisecond
+	policy.Jitter = 0
+
+	d, err := New(WithRetryPolicy(policy), WithUpdateInterval(time.Hour), WithChunks(4, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+// rangeRecorder serves content, recording the Range header of every GET request.
+type rangeRecorder struct {
+	mutex   sync.Mutex
+	content string
+	ranges  []string
+	noRange bool
+}
+
+func (rr *rangeRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		rr.mutex.Lock()
+		rr.ranges = append(rr.ranges, r.Header.Get("Range"))
+		rr.mutex.Unlock()
+	}
+
+	if rr.noRange {
+		w.Header().Set("Content-Length", strconv.Itoa(len(rr.content)))
+		if r.Method == http.MethodGet {
+			w.Write([]byte(rr.content))
+		}
+
+		return
+	}
+
+	http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(rr.content))
+}
+
+func (rr *rangeRecorder) Ranges() []string {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	ranges := append([]string(nil), rr.ranges...)
+	sort.Strings(ranges)
+	return ranges
+}
+
+func TestDownloadChunked(t *testing.T) {
+	recorder := &rangeRecorder{content: strings.Repeat("0123456789", 10)}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := newChunkedTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:  newTestURI(t, server.URL+"/file"),
+		Path: path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != recorder.content {
+		t.Errorf("Download() content = %q, want %q", data, recorder.content)
+	}
+
+	want := []string{"bytes=0-24", "bytes=25-49", "bytes=50-74", "bytes=75-99"}
+	if got := recorder.Ranges(); !slices.Equal(got, want) {
+		t.Errorf("Download() ranges = %v, want %v", got, want)
+	}
+
+	if _, err := os.Stat(path + TempFileExtension + MetaFileExtension); !os.IsNotExist(err) {
+		t.Errorf("Download() left download state behind, stat error = %v", err)
+	}
+}
+
+func TestDownloadChunkedResume(t *testing.T) {
+	recorder := &rangeRecorder{content: strings.Repeat("0123456789", 10)}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	// The first two chunks were interrupted, the others are complete.
+	path := filepath.Join(t.TempDir(), "file")
+	tempPath := path + TempFileExtension
+	partial := []byte(recorder.content[:10] + strings.Repeat("\x00", 15) + recorder.content[25:30] + strings.Repeat("\x00", 20) + recorder.content[50:])
+	if err := os.WriteFile(tempPath, partial, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveState(tempPath, &downloadState{
+		Size: 100,
+		Chunks: []*chunk{
+			{Start: 0, End: 24, Written: 10},
+			{Start: 25, End: 49, Written: 5},
+			{Start: 50, End: 74, Written: 25},
+			{Start: 75, End: 99, Written: 25},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newChunkedTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:  newTestURI(t, server.URL+"/file"),
+		Path: path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != recorder.content {
+		t.Errorf("Download() content = %q, want %q", data, recorder.content)
+	}
+
+	want := []string{"bytes=10-24", "bytes=30-49"}
+	if got := recorder.Ranges(); !slices.Equal(got, want) {
+		t.Errorf("Download() ranges = %v, want %v", got, want)
+	}
+}
+
+func TestDownloadChunkedFallback(t *testing.T) {
+	recorder := &rangeRecorder{content: strings.Repeat("0123456789", 10), noRange: true}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := newChunkedTestDownloader(t).Download(context.Background(), &types.DownloadOpts{
+		URI:  newTestURI(t, server.URL+"/file"),
+		Path: path,
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != recorder.content {
+		t.Errorf("Download() content = %q, want %q", data, recorder.content)
+	}
+
+	if got := recorder.Ranges(); len(got) != 1 {
+		t.Errorf("Download() made %d requests, want a single stream", len(got))
+	}
+}
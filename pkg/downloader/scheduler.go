@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type (
+	// Scheduler limits the remote transfers of every downloader that shares it, both in how many connections
+	// are open at once and in the total bytes per second they receive. Each chunk of a chunked download counts
+	// as a transfer of its own. A nil Scheduler imposes no limits.
+	Scheduler struct {
+		slots   chan struct{}
+		limiter *rateLimiter
+	}
+
+	// rateLimiter is a token bucket holding up to one second of transfer.
+	rateLimiter struct {
+		mutex  sync.Mutex
+		rate   float64
+		tokens float64
+		last   time.Time
+	}
+
+	// throttledReader waits for the rate limiter after every read.
+	throttledReader struct {
+		ctx     context.Context
+		reader  io.Reader
+		limiter *rateLimiter
+	}
+)
+
+// NewScheduler creates a Scheduler that allows up to maxConcurrent remote transfers at once, sharing maxRate
+// bytes per second between them. Zero means no limit for either.
+func NewScheduler(maxConcurrent int, maxRate int64) *Scheduler {
+	scheduler := &Scheduler{}
+	if maxConcurrent > 0 {
+		scheduler.slots = make(chan struct{}, maxConcurrent)
+	}
+
+	if maxRate > 0 {
+		scheduler.limiter = &rateLimiter{
+			rate:   float64(maxRate),
+			tokens: float64(maxRate),
+			last:   time.Now(),
+		}
+	}
+
+	return scheduler
+}
+
+// acquire waits for a free transfer slot. The returned function releases it.
+func (s *Scheduler) acquire(ctx context.Context) (func(), error) {
+	if s == nil || s.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, nil
+	}
+}
+
+// acquireShared waits for either the slot already held by the caller, which is passed around through held, or
+// a free one. The returned function releases whichever it got.
+func (s *Scheduler) acquireShared(ctx context.Context, held chan struct{}) (func(), error) {
+	if s == nil || s.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-held:
+		return func() { held <- struct{}{} }, nil
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, nil
+	}
+}
+
+// reader returns a reader that is throttled to the scheduler's rate.
+func (s *Scheduler) reader(ctx context.Context, reader io.Reader) io.Reader {
+	if s == nil || s.limiter == nil {
+		return reader
+	}
+
+	return &throttledReader{
+		ctx:     ctx,
+		reader:  reader,
+		limiter: s.limiter,
+	}
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+// wait takes n tokens from the bucket, waiting until the bucket has refilled if it runs into debt.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	debt := l.tokens
+	l.mutex.Unlock()
+
+	if debt >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-debt / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
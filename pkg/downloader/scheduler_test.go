@@ -0,0 +1,147 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestSchedulerMaxConcurrent(t *testing.T) {
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader("content"))
+	}))
+	defer server.Close()
+
+	d, err := New(WithUpdateInterval(time.Hour), WithScheduler(NewScheduler(2, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	errs := make(chan error, 6)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- d.Download(context.Background(), &types.DownloadOpts{
+				URI:  newTestURI(t, fmt.Sprintf("%s/file%d", server.URL, i)),
+				Path: filepath.Join(dir, fmt.Sprintf("file%d", i)),
+			})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Download() unexpected error: %v", err)
+		}
+	}
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("Download() peak concurrent transfers = %d, want 2", got)
+	}
+}
+
+func TestSchedulerCountsChunks(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", 8)
+
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			current := active.Add(1)
+			defer active.Add(-1)
+
+			for {
+				previous := peak.Load()
+				if current <= previous || peak.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	d, err := New(WithUpdateInterval(time.Hour), WithChunks(4, 16), WithScheduler(NewScheduler(2, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	errs := make(chan error, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- d.Download(context.Background(), &types.DownloadOpts{
+				URI:  newTestURI(t, fmt.Sprintf("%s/file%d", server.URL, i)),
+				Path: filepath.Join(dir, fmt.Sprintf("file%d", i)),
+			})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Download() unexpected error: %v", err)
+		}
+	}
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("Download() peak concurrent connections = %d, want 2", got)
+	}
+}
+
+func TestSchedulerMaxRate(t *testing.T) {
+	content := strings.Repeat("x", 75000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	// The first second of transfer is available at once, the rest takes half a second at this rate.
+	d, err := New(WithUpdateInterval(time.Hour), WithBufferSize(4096), WithScheduler(NewScheduler(0, 50000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := d.Download(context.Background(), &types.DownloadOpts{
+		URI:  newTestURI(t, server.URL+"/file"),
+		Path: filepath.Join(t.TempDir(), "file"),
+	}); err != nil {
+		t.Fatalf("Download() unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Download() took %s, want it throttled to at least 400ms", elapsed)
+	}
+}
@@ -0,0 +1,126 @@
+package downloader
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// MetaFileExtension is appended to the temp file path for the file that describes a partial download.
+const MetaFileExtension = ".meta"
+
+type (
+	// downloadState is persisted beside the temp file. It records the validators of the remote file, so a
+	// resumed download can detect that the file changed, and the progress of each chunk of a chunked download.
+	downloadState struct {
+		Size         int64    `json:"size"` // Content-Length of the complete file, or -1 if unknown.
+		ETag         string   `json:"etag,omitempty"`
+		LastModified string   `json:"lastModified,omitempty"`
+		Host         string   `json:"host,omitempty"`   // Host the file is downloaded from, mirrors serve their own copy.
+		Chunks       []*chunk `json:"chunks,omitempty"` // Only set for chunked downloads.
+	}
+)
+
+func newStateFromResponse(resp *http.Response, size int64) *downloadState {
+	return &downloadState{
+		Size:         size,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// ifRange returns the value for the If-Range header, which only accepts strong entity tags.
+func (s *downloadState) ifRange() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+
+	return s.LastModified
+}
+
+// matches reports whether the remote file is still the one the state was recorded for.
+func (s *downloadState) matches(other *downloadState) bool {
+	if s.Size > 0 && other.Size > 0 && s.Size != other.Size {
+		return false
+	}
+
+	if s.ETag != "" && other.ETag != "" && s.ETag != other.ETag {
+		return false
+	}
+
+	if s.LastModified != "" && other.LastModified != "" && s.LastModified != other.LastModified {
+		return false
+	}
+
+	return true
+}
+
+// loadState returns the saved state of a partial download, or nil if there is none.
+func loadState(tempPath string) (*downloadState, error) {
+	data, err := os.ReadFile(tempPath + MetaFileExtension)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// A corrupt state cannot be trusted, start over.
+		return nil, discardDownload(tempPath)
+	}
+
+	return &state, nil
+}
+
+func saveState(tempPath string, state *downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(tempPath+MetaFileExtension, data, 0644)
+}
+
+// discardForeignDownload removes a partial download that was started from another host. Mirrors may serve a
+// different copy of the file, whose validators cannot be compared with the ones recorded.
+func discardForeignDownload(tempPath string, uri *types.URI) error {
+	state, err := loadState(tempPath)
+	if err != nil || state == nil || state.Host == "" || state.Host == uri.Host {
+		return err
+	}
+
+	return discardDownload(tempPath)
+}
+
+// discardDownload removes a partial download and its state.
+func discardDownload(tempPath string) error {
+	for _, path := range []string{tempPath, tempPath + MetaFileExtension} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// contentRangeSize returns the complete length from a Content-Range header, or -1 if it is unknown.
+func contentRangeSize(contentRange string) int64 {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return size
+}
@@ -0,0 +1,80 @@
+package driver
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/semver"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// checkBuildCompatibility ensures that the build of a profile is within the range supported by each of its
+// addons. Incompatible addons fail strict profiles and are only warned about otherwise.
+func (d *Driver) checkBuildCompatibility(dependencies []*types.Dependency, packs map[reference.Reference]*types.Package, strict bool) error {
+	var build reference.Reference
+	for _, dep := range dependencies {
+		if packs[dep.Reference].Type == types.PackageBuild {
+			build = dep.Reference
+			break
+		}
+	}
+
+	if build == "" {
+		return nil
+	}
+
+	version := buildVersion(build, packs[build])
+	for _, dep := range dependencies {
+		pack := packs[dep.Reference]
+		if pack.Type != types.PackageAddon || pack.Builds == "" {
+			continue
+		}
+
+		constraint, err := semver.ParseConstraint(pack.Builds)
+		if err != nil {
+			return fmt.Errorf("addon %s has an invalid build range: %w", dep.Reference.String(), err)
+		}
+
+		if version == nil {
+			d.logger.Warn("cannot check addon compatibility, build has no version", map[string]interface{}{
+				"addon":  dep.Reference.String(),
+				"build":  build.String(),
+				"builds": pack.Builds,
+			})
+
+			continue
+		}
+
+		if constraint.Check(*version) {
+			continue
+		}
+
+		if strict {
+			return fmt.Errorf("%w: addon %s supports builds %s, but the profile uses %s (%s)", types.ErrIncompatibleBuild, dep.Reference.String(), pack.Builds, build.String(), version.String())
+		}
+
+		d.logger.Warn("addon does not support build", map[string]interface{}{
+			"addon":   dep.Reference.String(),
+			"builds":  pack.Builds,
+			"build":   build.String(),
+			"version": version.String(),
+		})
+	}
+
+	return nil
+}
+
+// buildVersion returns the version of a build, falling back to the version in its reference.
+func buildVersion(ref reference.Reference, pack *types.Package) *semver.Version {
+	if pack.Version != nil {
+		return pack.Version
+	}
+
+	version, err := semver.Parse(path.Base(string(ref)))
+	if err != nil {
+		return nil
+	}
+
+	return version
+}
@@ -0,0 +1,115 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/logger"
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/semver"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/rocketblend/rocketblend/pkg/validator"
+)
+
+// warningLogger records the warnings it is sent.
+type warningLogger struct {
+	types.Logger
+
+	warnings []string
+}
+
+func (l *warningLogger) Warn(msg string, fields ...map[string]interface{}) {
+	l.warnings = append(l.warnings, msg)
+}
+
+func compatibilityPackages() map[reference.Reference]*types.Package {
+	return map[reference.Reference]*types.Package{
+		"builds/blender/4.2.0": {Type: types.PackageBuild},
+		"addons/current/1.0.0": {Type: types.PackageAddon, Builds: ">=4.1 <4.3"},
+		"addons/legacy/1.0.0":  {Type: types.PackageAddon, Builds: "<4.0"},
+		"addons/any/1.0.0":     {Type: types.PackageAddon},
+	}
+}
+
+func TestCheckBuildCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		addon    reference.Reference
+		strict   bool
+		wantErr  error
+		warnings int
+	}{
+		{name: "supported build", addon: "addons/current/1.0.0", strict: true},
+		{name: "no build range", addon: "addons/any/1.0.0", strict: true},
+		{name: "unsupported build, strict", addon: "addons/legacy/1.0.0", strict: true, wantErr: types.ErrIncompatibleBuild},
+		{name: "unsupported build", addon: "addons/legacy/1.0.0", warnings: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := &warningLogger{Logger: logger.NoOp()}
+			driver, err := New(WithLogger(logger), WithValidator(validator.New()), WithRepository(&fakeRepository{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deps := dependencies("builds/blender/4.2.0", test.addon)
+			err = driver.checkBuildCompatibility(deps, compatibilityPackages(), test.strict)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("checkBuildCompatibility() error = %v, want %v", err, test.wantErr)
+			}
+
+			if len(logger.warnings) != test.warnings {
+				t.Errorf("checkBuildCompatibility() warnings = %v, want %d", logger.warnings, test.warnings)
+			}
+		})
+	}
+}
+
+func TestCheckBuildCompatibilityPackageVersion(t *testing.T) {
+	driver, _ := newTestDriver(t, nil)
+
+	// The version declared by the build takes precedence over the one in its reference.
+	version, err := semver.Parse("3.6.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packs := compatibilityPackages()
+	packs["builds/blender/4.2.0"].Version = version
+
+	err = driver.checkBuildCompatibility(dependencies("builds/blender/4.2.0", "addons/legacy/1.0.0"), packs, true)
+	if err != nil {
+		t.Errorf("checkBuildCompatibility() returned unexpected error: %v", err)
+	}
+
+	err = driver.checkBuildCompatibility(dependencies("builds/blender/4.2.0", "addons/current/1.0.0"), packs, true)
+	if !errors.Is(err, types.ErrIncompatibleBuild) {
+		t.Errorf("checkBuildCompatibility() error = %v, want %v", err, types.ErrIncompatibleBuild)
+	}
+}
+
+func TestProfilesRejectIncompatibleBuild(t *testing.T) {
+	driver, _ := newTestDriver(t, compatibilityPackages())
+
+	profile := func() *types.Profile {
+		return &types.Profile{
+			Dependencies: []*types.Dependency{
+				{Reference: "builds/blender/4.2.0", Type: types.PackageBuild},
+				{Reference: "addons/legacy/1.0.0", Type: types.PackageAddon},
+			},
+			Strict: true,
+		}
+	}
+
+	err := driver.InstallProfiles(context.Background(), &types.InstallProfilesOpts{Profiles: []*types.Profile{profile()}})
+	if !errors.Is(err, types.ErrIncompatibleBuild) {
+		t.Errorf("InstallProfiles() error = %v, want %v", err, types.ErrIncompatibleBuild)
+	}
+
+	err = driver.TidyProfiles(context.Background(), &types.TidyProfilesOpts{Profiles: []*types.Profile{profile()}})
+	if !errors.Is(err, types.ErrIncompatibleBuild) {
+		t.Errorf("TidyProfiles() error = %v, want %v", err, types.ErrIncompatibleBuild)
+	}
+}
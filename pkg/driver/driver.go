@@ -89,18 +89,21 @@ func New(opts ...Option) (*Driver, error) {
 	}, nil
 }
 
-func (d *Driver) getInstallations(ctx context.Context, dependencies []*types.Dependency, fetch bool) (map[reference.Reference]*types.Installation, error) {
+func (d *Driver) getInstallations(ctx context.Context, dependencies []*types.Dependency, locks map[reference.Reference]*types.LockedPackage, fetch bool, requireDigests bool, progress chan<- types.InstallationProgress) (*types.GetInstallationsResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
 	result, err := d.repository.GetInstallations(ctx, &types.GetInstallationsOpts{
-		Dependencies: dependencies,
-		Fetch:        fetch,
+		Dependencies:   dependencies,
+		Locks:          locks,
+		Fetch:          fetch,
+		RequireDigests: requireDigests,
+		Progress:       progress,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return result.Installations, nil
+	return result, nil
 }
@@ -0,0 +1,485 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/semver"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// maxGraphPasses bounds how often versions are re-picked before giving up on a dependency graph.
+const maxGraphPasses = 10
+
+type (
+	// requirement records a declared dependency and the chain of packages that declared it.
+	requirement struct {
+		reference   reference.Reference
+		packageType types.PackageType
+		path        []reference.Reference // Empty when declared by the profile itself.
+	}
+
+	graphNode struct {
+		reference    reference.Reference
+		pack         *types.Package
+		path         []reference.Reference // Shortest chain of packages that pulled this package in, itself included.
+		dependencies []reference.Reference
+	}
+
+	dependencyGraph struct {
+		locks  map[reference.Reference]*types.LockedPackage
+		update bool
+		frozen bool
+
+		packs  map[reference.Reference]*types.Package
+		picks  map[reference.Reference]reference.Reference // Chosen concrete reference per requirement key.
+		pinned map[reference.Reference]bool                // Keys chosen by the profile, which are never re-picked.
+	}
+)
+
+// resolveGraph expands the dependencies of a profile with the packages they require, transitively. A single
+// version is picked for each package so that every requirement on it is satisfied, preferring locked versions.
+// The profile's own dependencies come first, followed by the packages they pulled in. The package definitions
+// are returned alongside, keyed by reference.
+func (d *Driver) resolveGraph(ctx context.Context, dependencies []*types.Dependency, locks map[reference.Reference]*types.LockedPackage, update bool, frozen bool) ([]*types.Dependency, map[reference.Reference]*types.Package, error) {
+	graph := &dependencyGraph{
+		locks:  locks,
+		update: update,
+		frozen: frozen,
+		packs:  make(map[reference.Reference]*types.Package),
+		picks:  make(map[reference.Reference]reference.Reference),
+		pinned: make(map[reference.Reference]bool),
+	}
+
+	direct := make(map[reference.Reference]*types.Dependency, len(dependencies))
+	for _, dep := range dependencies {
+		key, _ := requirementKey(dep.Reference)
+		graph.picks[key] = dep.Reference
+		graph.pinned[key] = true
+		direct[dep.Reference] = dep
+	}
+
+	for pass := 0; pass < maxGraphPasses; pass++ {
+		nodes, order, requirements, err := d.walkGraph(ctx, graph, dependencies)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		changed, err := d.reconcileGraph(ctx, graph, requirements)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if changed {
+			continue
+		}
+
+		if err := checkRequiredTypes(graph, nodes, requirements); err != nil {
+			return nil, nil, err
+		}
+
+		if err := checkBuilds(nodes, order); err != nil {
+			return nil, nil, err
+		}
+
+		if err := checkCycles(nodes, order); err != nil {
+			return nil, nil, err
+		}
+
+		resolved := make([]*types.Dependency, 0, len(order))
+		packs := make(map[reference.Reference]*types.Package, len(order))
+		for _, ref := range order {
+			packs[ref] = nodes[ref].pack
+			if dep, ok := direct[ref]; ok {
+				resolved = append(resolved, dep)
+				continue
+			}
+
+			resolved = append(resolved, &types.Dependency{
+				Reference: ref,
+				Type:      nodes[ref].pack.Type,
+			})
+		}
+
+		d.logger.Debug("resolved dependency graph", map[string]interface{}{
+			"dependencies": len(dependencies),
+			"resolved":     len(resolved),
+			"passes":       pass + 1,
+		})
+
+		return resolved, packs, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: versions did not settle after %d passes", types.ErrDependencyConflict, maxGraphPasses)
+}
+
+// walkGraph visits every package reachable from the dependencies breadth first, picking a version for
+// requirements that have not been seen before.
+func (d *Driver) walkGraph(ctx context.Context, graph *dependencyGraph, dependencies []*types.Dependency) (map[reference.Reference]*graphNode, []reference.Reference, map[reference.Reference][]*requirement, error) {
+	nodes := make(map[reference.Reference]*graphNode)
+	order := make([]reference.Reference, 0, len(dependencies))
+	requirements := make(map[reference.Reference][]*requirement)
+
+	queue := make([]*graphNode, 0, len(dependencies))
+	for _, dep := range dependencies {
+		key, _ := requirementKey(dep.Reference)
+		requirements[key] = append(requirements[key], &requirement{reference: dep.Reference, packageType: dep.Type})
+		if _, exists := nodes[dep.Reference]; exists {
+			continue
+		}
+
+		node := &graphNode{reference: dep.Reference, path: []reference.Reference{dep.Reference}}
+		nodes[dep.Reference] = node
+		order = append(order, dep.Reference)
+		queue = append(queue, node)
+	}
+
+	for len(queue) > 0 {
+		if err := d.loadGraphPackages(ctx, graph, queue); err != nil {
+			return nil, nil, nil, err
+		}
+
+		var next []*graphNode
+		for _, node := range queue {
+			node.pack = graph.packs[node.reference]
+			for _, dep := range node.pack.Dependencies {
+				key, _ := requirementKey(dep.Reference)
+				requirements[key] = append(requirements[key], &requirement{
+					reference:   dep.Reference,
+					packageType: dep.Type,
+					path:        node.path,
+				})
+
+				pick, exists := graph.picks[key]
+				if !exists {
+					var err error
+					if pick, err = d.resolveRequirement(ctx, graph, key, dep.Reference); err != nil {
+						return nil, nil, nil, fmt.Errorf("%s requires %s: %w", describePath(node.path), dep.Reference.String(), err)
+					}
+
+					graph.picks[key] = pick
+				}
+
+				node.dependencies = append(node.dependencies, pick)
+				if _, exists := nodes[pick]; exists {
+					continue
+				}
+
+				child := &graphNode{
+					reference: pick,
+					path:      append(append([]reference.Reference{}, node.path...), pick),
+				}
+
+				nodes[pick] = child
+				order = append(order, pick)
+				next = append(next, child)
+			}
+		}
+
+		queue = next
+	}
+
+	return nodes, order, requirements, nil
+}
+
+// reconcileGraph re-picks versions that do not satisfy every requirement on them. It reports whether any
+// pick changed, in which case the graph has to be walked again.
+func (d *Driver) reconcileGraph(ctx context.Context, graph *dependencyGraph, requirements map[reference.Reference][]*requirement) (bool, error) {
+	keys := make([]reference.Reference, 0, len(requirements))
+	for key := range requirements {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	changed := false
+	for _, key := range keys {
+		pick := graph.picks[key]
+		reqs := requirements[key]
+
+		satisfied := true
+		for _, req := range reqs {
+			if !satisfies(pick, req.reference) {
+				satisfied = false
+				break
+			}
+		}
+
+		if satisfied {
+			continue
+		}
+
+		combined, ok := combineRequirements(reqs)
+		if graph.pinned[key] || !ok {
+			return false, conflictError(key, reqs)
+		}
+
+		repick, err := d.resolveRequirement(ctx, graph, key, key.WithConstraint(combined))
+		if errors.Is(err, types.ErrNoMatchingVersion) {
+			return false, conflictError(key, reqs)
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		d.logger.Debug("re-picked dependency version", map[string]interface{}{
+			"reference":  key.String(),
+			"previous":   pick.String(),
+			"picked":     repick.String(),
+			"constraint": combined,
+		})
+
+		graph.picks[key] = repick
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// resolveRequirement picks the concrete reference for a requirement, preferring a locked version.
+func (d *Driver) resolveRequirement(ctx context.Context, graph *dependencyGraph, key reference.Reference, ref reference.Reference) (reference.Reference, error) {
+	if !ref.IsRange() {
+		return ref, nil
+	}
+
+	if locked := graph.lockedPick(key, ref); locked != "" {
+		return locked, nil
+	}
+
+	if graph.frozen {
+		return "", fmt.Errorf("%w: no locked version of %s", types.ErrProfileLockMismatch, ref.String())
+	}
+
+	result, err := d.repository.ResolveReferences(ctx, &types.ResolveReferencesOpts{
+		References: []reference.Reference{ref},
+		Update:     graph.update,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.References[ref], nil
+}
+
+// loadGraphPackages fetches the package definitions for nodes that have not been loaded yet.
+func (d *Driver) loadGraphPackages(ctx context.Context, graph *dependencyGraph, nodes []*graphNode) error {
+	references := make([]reference.Reference, 0, len(nodes))
+	for _, node := range nodes {
+		if _, exists := graph.packs[node.reference]; !exists {
+			references = append(references, node.reference)
+		}
+	}
+
+	if len(references) == 0 {
+		return nil
+	}
+
+	result, err := d.repository.GetPackages(ctx, &types.GetPackagesOpts{
+		References: references,
+		Locks:      graph.locks,
+		Update:     graph.update,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range references {
+		pack, ok := result.Packs[ref]
+		if !ok {
+			return fmt.Errorf("dependency not found: %s", ref.String())
+		}
+
+		graph.packs[ref] = pack
+	}
+
+	return nil
+}
+
+// lockedPick returns the highest locked reference that satisfies the requirement, if any.
+func (g *dependencyGraph) lockedPick(key reference.Reference, ref reference.Reference) reference.Reference {
+	var picked reference.Reference
+	var highest *semver.Version
+	for locked := range g.locks {
+		if lockedKey, _ := requirementKey(locked); lockedKey != key || !satisfies(locked, ref) {
+			continue
+		}
+
+		version, err := semver.Parse(path.Base(string(locked)))
+		if err != nil {
+			continue
+		}
+
+		if highest == nil || version.GreaterThan(*highest) {
+			picked = locked
+			highest = version
+		}
+	}
+
+	return picked
+}
+
+// checkRequiredTypes ensures that packages are of the type their dependents declared.
+func checkRequiredTypes(graph *dependencyGraph, nodes map[reference.Reference]*graphNode, requirements map[reference.Reference][]*requirement) error {
+	for key, reqs := range requirements {
+		node, ok := nodes[graph.picks[key]]
+		if !ok {
+			continue
+		}
+
+		for _, req := range reqs {
+			if req.packageType != "" && req.packageType != node.pack.Type {
+				return fmt.Errorf("%w: %s requires %s as %s, but it is %s", types.ErrDependencyConflict, describePath(req.path), req.reference.String(), req.packageType, node.pack.Type)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkBuilds ensures that the graph contains at most one build.
+func checkBuilds(nodes map[reference.Reference]*graphNode, order []reference.Reference) error {
+	var builds []*graphNode
+	for _, ref := range order {
+		if node := nodes[ref]; node.pack.Type == types.PackageBuild {
+			builds = append(builds, node)
+		}
+	}
+
+	if len(builds) <= 1 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(builds))
+	for _, node := range builds {
+		lines = append(lines, fmt.Sprintf("  %s", describePath(node.path)))
+	}
+
+	return fmt.Errorf("%w: only one build can be used, but %d are required:\n%s", types.ErrDependencyConflict, len(builds), strings.Join(lines, "\n"))
+}
+
+// checkCycles returns an error describing the first dependency cycle found in the graph.
+func checkCycles(nodes map[reference.Reference]*graphNode, order []reference.Reference) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[reference.Reference]int, len(nodes))
+	var stack []reference.Reference
+
+	var visit func(ref reference.Reference) error
+	visit = func(ref reference.Reference) error {
+		switch state[ref] {
+		case visiting:
+			for i, r := range stack {
+				if r == ref {
+					cycle := append(append([]reference.Reference{}, stack[i:]...), ref)
+					return fmt.Errorf("%w: %s", types.ErrDependencyCycle, joinReferences(cycle))
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[ref] = visiting
+		stack = append(stack, ref)
+		for _, dep := range nodes[ref].dependencies {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[ref] = visited
+		return nil
+	}
+
+	for _, ref := range order {
+		if err := visit(ref); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// requirementKey returns the reference identifying a package independent of its version, along with the
+// constraint the reference puts on that version. References without a version have no constraint.
+func requirementKey(ref reference.Reference) (reference.Reference, string) {
+	if ref.IsRange() {
+		return ref.Range()
+	}
+
+	version := path.Base(string(ref))
+	if _, err := semver.Parse(version); err == nil {
+		return ref.Parent(), "=" + version
+	}
+
+	return ref, ""
+}
+
+// satisfies reports whether a concrete reference meets a requirement.
+func satisfies(pick reference.Reference, ref reference.Reference) bool {
+	_, rawConstraint := requirementKey(ref)
+	if rawConstraint == "" {
+		return pick == ref
+	}
+
+	constraint, err := semver.ParseConstraint(rawConstraint)
+	if err != nil {
+		return false
+	}
+
+	version, err := semver.Parse(path.Base(string(pick)))
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(*version)
+}
+
+// combineRequirements joins the constraints of all requirements into one that satisfies every one of them.
+func combineRequirements(reqs []*requirement) (string, bool) {
+	constraints := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		_, constraint := requirementKey(req.reference)
+		if constraint == "" || strings.Contains(constraint, "||") {
+			return "", false
+		}
+
+		constraints = append(constraints, constraint)
+	}
+
+	return strings.Join(constraints, " "), true
+}
+
+func conflictError(key reference.Reference, reqs []*requirement) error {
+	lines := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		lines = append(lines, fmt.Sprintf("  %s requires %s", describePath(req.path), req.reference.String()))
+	}
+
+	return fmt.Errorf("%w: no version of %s satisfies every requirement:\n%s", types.ErrDependencyConflict, key.String(), strings.Join(lines, "\n"))
+}
+
+// describePath formats the chain of packages that led to a requirement, starting from the profile.
+func describePath(refs []reference.Reference) string {
+	return joinReferences(append([]reference.Reference{"profile"}, refs...))
+}
+
+func joinReferences(refs []reference.Reference) string {
+	parts := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		parts = append(parts, string(ref))
+	}
+
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,237 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/semver"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/rocketblend/rocketblend/pkg/validator"
+)
+
+// fakeRepository serves package definitions from memory, resolving ranges to the highest matching version.
+type fakeRepository struct {
+	types.Repository
+
+	packs    map[reference.Reference]*types.Package
+	resolved []reference.Reference // Ranges passed to ResolveReferences, in order.
+}
+
+func (r *fakeRepository) GetPackages(ctx context.Context, opts *types.GetPackagesOpts) (*types.GetPackagesResult, error) {
+	packs := make(map[reference.Reference]*types.Package, len(opts.References))
+	for _, ref := range opts.References {
+		if pack, ok := r.packs[ref]; ok {
+			packs[ref] = pack
+		}
+	}
+
+	return &types.GetPackagesResult{Packs: packs}, nil
+}
+
+func (r *fakeRepository) ResolveReferences(ctx context.Context, opts *types.ResolveReferencesOpts) (*types.ResolveReferencesResult, error) {
+	references := make(map[reference.Reference]reference.Reference, len(opts.References))
+	for _, ref := range opts.References {
+		r.resolved = append(r.resolved, ref)
+
+		base, rawConstraint := ref.Range()
+		constraint, err := semver.ParseConstraint(rawConstraint)
+		if err != nil {
+			return nil, err
+		}
+
+		var highest *semver.Version
+		for candidate := range r.packs {
+			if candidate.Parent() != base {
+				continue
+			}
+
+			version, err := semver.Parse(path.Base(string(candidate)))
+			if err != nil || !constraint.Check(*version) {
+				continue
+			}
+
+			if highest == nil || version.GreaterThan(*highest) {
+				highest = version
+				references[ref] = candidate
+			}
+		}
+
+		if highest == nil {
+			return nil, types.ErrNoMatchingVersion
+		}
+	}
+
+	return &types.ResolveReferencesResult{References: references}, nil
+}
+
+func newTestDriver(t *testing.T, packs map[reference.Reference]*types.Package) (*Driver, *fakeRepository) {
+	t.Helper()
+
+	repository := &fakeRepository{packs: packs}
+	driver, err := New(
+		WithValidator(validator.New()),
+		WithRepository(repository),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return driver, repository
+}
+
+func addon(dependencies ...reference.Reference) *types.Package {
+	pack := &types.Package{Type: types.PackageAddon}
+	for _, dep := range dependencies {
+		pack.Dependencies = append(pack.Dependencies, &types.PackageDependency{Reference: dep})
+	}
+
+	return pack
+}
+
+func dependencies(refs ...reference.Reference) []*types.Dependency {
+	deps := make([]*types.Dependency, 0, len(refs))
+	for _, ref := range refs {
+		deps = append(deps, &types.Dependency{Reference: ref})
+	}
+
+	return deps
+}
+
+func resolvedReferences(deps []*types.Dependency) []string {
+	refs := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		refs = append(refs, string(dep.Reference))
+	}
+
+	return refs
+}
+
+func TestResolveGraphCycle(t *testing.T) {
+	driver, _ := newTestDriver(t, map[reference.Reference]*types.Package{
+		"addons/a/1.0.0": addon("addons/b/1.0.0"),
+		"addons/b/1.0.0": addon("addons/c/1.0.0"),
+		"addons/c/1.0.0": addon("addons/a/1.0.0"),
+	})
+
+	_, _, err := driver.resolveGraph(context.Background(), dependencies("addons/a/1.0.0"), nil, false, false)
+	if !errors.Is(err, types.ErrDependencyCycle) {
+		t.Fatalf("resolveGraph() error = %v, want %v", err, types.ErrDependencyCycle)
+	}
+
+	want := "addons/a/1.0.0 -> addons/b/1.0.0 -> addons/c/1.0.0 -> addons/a/1.0.0"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("resolveGraph() error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestResolveGraphRepicksNarrowedRange(t *testing.T) {
+	driver, repository := newTestDriver(t, map[reference.Reference]*types.Package{
+		"addons/a/1.0.0":   addon("addons/lib@^1.0"),
+		"addons/b/1.0.0":   addon("addons/lib@<1.3"),
+		"addons/lib/1.0.0": addon(),
+		"addons/lib/1.2.0": addon(),
+		"addons/lib/1.5.0": addon(),
+		"addons/lib/2.0.0": addon(),
+	})
+
+	resolved, packs, err := driver.resolveGraph(context.Background(), dependencies("addons/a/1.0.0", "addons/b/1.0.0"), nil, false, false)
+	if err != nil {
+		t.Fatalf("resolveGraph() returned unexpected error: %v", err)
+	}
+
+	got := strings.Join(resolvedReferences(resolved), ", ")
+	want := "addons/a/1.0.0, addons/b/1.0.0, addons/lib/1.2.0"
+	if got != want {
+		t.Errorf("resolveGraph() = %s, want %s", got, want)
+	}
+
+	if _, ok := packs["addons/lib/1.5.0"]; ok {
+		t.Errorf("resolveGraph() returned the package of the version that was re-picked")
+	}
+
+	if len(repository.resolved) != 2 || repository.resolved[1] != "addons/lib@^1.0 <1.3" {
+		t.Errorf("resolved ranges = %v, want the combined range to be resolved last", repository.resolved)
+	}
+}
+
+func TestResolveGraphPinnedConflict(t *testing.T) {
+	driver, _ := newTestDriver(t, map[reference.Reference]*types.Package{
+		"addons/a/1.0.0":   addon("addons/lib@<1.3"),
+		"addons/lib/1.2.0": addon(),
+		"addons/lib/1.5.0": addon(),
+	})
+
+	_, _, err := driver.resolveGraph(context.Background(), dependencies("addons/lib/1.5.0", "addons/a/1.0.0"), nil, false, false)
+	if !errors.Is(err, types.ErrDependencyConflict) {
+		t.Fatalf("resolveGraph() error = %v, want %v", err, types.ErrDependencyConflict)
+	}
+
+	want := "dependency conflict: no version of addons/lib satisfies every requirement:\n" +
+		"  profile requires addons/lib/1.5.0\n" +
+		"  profile -> addons/a/1.0.0 requires addons/lib@<1.3"
+	if err.Error() != want {
+		t.Errorf("resolveGraph() error = %q, want %q", err, want)
+	}
+}
+
+func TestResolveGraphMultipleBuilds(t *testing.T) {
+	driver, _ := newTestDriver(t, map[reference.Reference]*types.Package{
+		"builds/blender/4.2.0":     {Type: types.PackageBuild},
+		"builds/bforartists/4.1.0": {Type: types.PackageBuild},
+		"addons/a/1.0.0":           addon("builds/bforartists/4.1.0"),
+	})
+
+	_, _, err := driver.resolveGraph(context.Background(), dependencies("builds/blender/4.2.0", "addons/a/1.0.0"), nil, false, false)
+	if !errors.Is(err, types.ErrDependencyConflict) {
+		t.Fatalf("resolveGraph() error = %v, want %v", err, types.ErrDependencyConflict)
+	}
+
+	want := "dependency conflict: only one build can be used, but 2 are required:\n" +
+		"  profile -> builds/blender/4.2.0\n" +
+		"  profile -> addons/a/1.0.0 -> builds/bforartists/4.1.0"
+	if err.Error() != want {
+		t.Errorf("resolveGraph() error = %q, want %q", err, want)
+	}
+}
+
+func TestResolveGraphPrefersLocked(t *testing.T) {
+	packs := map[reference.Reference]*types.Package{
+		"addons/a/1.0.0":   addon("addons/lib@^1.0"),
+		"addons/lib/1.2.0": addon(),
+		"addons/lib/1.5.0": addon(),
+	}
+
+	locks := map[reference.Reference]*types.LockedPackage{
+		"addons/a/1.0.0":   {},
+		"addons/lib/1.0.0": {}, // Not the highest locked match.
+		"addons/lib/1.2.0": {},
+		"addons/lib/2.0.0": {}, // Outside of the range.
+	}
+
+	driver, repository := newTestDriver(t, packs)
+	resolved, _, err := driver.resolveGraph(context.Background(), dependencies("addons/a/1.0.0"), locks, false, false)
+	if err != nil {
+		t.Fatalf("resolveGraph() returned unexpected error: %v", err)
+	}
+
+	got := strings.Join(resolvedReferences(resolved), ", ")
+	want := "addons/a/1.0.0, addons/lib/1.2.0"
+	if got != want {
+		t.Errorf("resolveGraph() = %s, want %s", got, want)
+	}
+
+	if len(repository.resolved) != 0 {
+		t.Errorf("resolved ranges = %v, want locked versions to be used without resolving", repository.resolved)
+	}
+
+	delete(locks, "addons/lib/1.2.0")
+	delete(locks, "addons/lib/1.0.0")
+	driver, _ = newTestDriver(t, packs)
+	if _, _, err := driver.resolveGraph(context.Background(), dependencies("addons/a/1.0.0"), locks, false, true); !errors.Is(err, types.ErrProfileLockMismatch) {
+		t.Errorf("resolveGraph() frozen error = %v, want %v", err, types.ErrProfileLockMismatch)
+	}
+}
@@ -14,7 +14,7 @@ func (d *Driver) InstallProfiles(ctx context.Context, opts *types.InstallProfile
 	tasks := make([]taskrunner.Task[struct{}], len(opts.Profiles))
 	for i, profile := range opts.Profiles {
 		tasks[i] = func(ctx context.Context) (struct{}, error) {
-			if err := d.installDependencies(ctx, profile); err != nil {
+			if err := d.installDependencies(ctx, profile, opts.Frozen, opts.Progress); err != nil {
 				return struct{}{}, err
 			}
 
@@ -34,19 +34,44 @@ func (d *Driver) InstallProfiles(ctx context.Context, opts *types.InstallProfile
 	return nil
 }
 
-func (d *Driver) installDependencies(ctx context.Context, profile *types.Profile) error {
+func (d *Driver) installDependencies(ctx context.Context, profile *types.Profile, frozen bool, progress chan<- types.InstallationProgress) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
 	d.logger.Debug("installing dependencies", map[string]interface{}{
 		"profile": profile,
+		"frozen":  frozen,
 	})
 
-	_, err := d.getInstallations(ctx, profile.Dependencies, true)
+	locks, err := profileLocks(profile, frozen)
 	if err != nil {
 		return err
 	}
 
+	dependencies, packs, err := d.resolveGraph(ctx, profile.Dependencies, locks, false, frozen)
+	if err != nil {
+		return err
+	}
+
+	if err := d.checkBuildCompatibility(dependencies, packs, profile.Strict); err != nil {
+		return err
+	}
+
+	if frozen {
+		if err := verifyLock(profile.Lock, dependencies); err != nil {
+			return err
+		}
+	}
+
+	result, err := d.getInstallations(ctx, dependencies, locks, true, profile.RequireDigests, progress)
+	if err != nil {
+		return err
+	}
+
+	if !frozen {
+		profile.Lock = newProfileLock(dependencies, result.Locks)
+	}
+
 	return nil
 }
@@ -57,5 +57,12 @@ func (d *Driver) load(ctx context.Context, path string, defaultProfile *types.Pr
 		return nil, err
 	}
 
+	lock, err := helpers.Load[types.ProfileLock](d.validator, profileLockFilePath(path))
+	if err != nil && !errors.Is(err, types.ErrFileNotFound) {
+		return nil, err
+	}
+
+	profile.Lock = lock
+
 	return profile, nil
 }
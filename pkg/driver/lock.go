@@ -0,0 +1,83 @@
+package driver
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// profileLocks returns the locked packages for a profile keyed by reference. In frozen mode the profile
+// must have a lock.
+func profileLocks(profile *types.Profile, frozen bool) (map[reference.Reference]*types.LockedPackage, error) {
+	if frozen && profile.Lock == nil {
+		return nil, types.ErrMissingProfileLock
+	}
+
+	if profile.Lock == nil {
+		return nil, nil
+	}
+
+	locks := make(map[reference.Reference]*types.LockedPackage, len(profile.Lock.Packages))
+	for _, pack := range profile.Lock.Packages {
+		locks[pack.Reference] = pack
+	}
+
+	return locks, nil
+}
+
+// verifyLock checks that every dependency, including those pulled in by other packages, is locked and that
+// the lock has no extra packages.
+func verifyLock(lock *types.ProfileLock, dependencies []*types.Dependency) error {
+	if lock == nil {
+		return types.ErrMissingProfileLock
+	}
+
+	locked := make(map[reference.Reference]*types.LockedPackage, len(lock.Packages))
+	for _, pack := range lock.Packages {
+		locked[pack.Reference] = pack
+	}
+
+	for _, dep := range dependencies {
+		pack, ok := locked[dep.Reference]
+		if !ok {
+			return fmt.Errorf("%w: %s is not locked", types.ErrProfileLockMismatch, dep.Reference.String())
+		}
+
+		if dep.Type != "" && dep.Type != pack.Type {
+			return fmt.Errorf("%w: %s is locked as %s but required as %s", types.ErrProfileLockMismatch, dep.Reference.String(), pack.Type, dep.Type)
+		}
+
+		delete(locked, dep.Reference)
+	}
+
+	for ref := range locked {
+		return fmt.Errorf("%w: %s is locked but not a dependency", types.ErrProfileLockMismatch, ref.String())
+	}
+
+	return nil
+}
+
+// newProfileLock creates a lock for the dependencies from the resolved package locks.
+func newProfileLock(dependencies []*types.Dependency, locks map[reference.Reference]*types.LockedPackage) *types.ProfileLock {
+	packages := make([]*types.LockedPackage, 0, len(dependencies))
+	for _, dep := range dependencies {
+		if lock, ok := locks[dep.Reference]; ok {
+			packages = append(packages, lock)
+		}
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Reference < packages[j].Reference
+	})
+
+	return &types.ProfileLock{
+		Packages: packages,
+	}
+}
+
+func profileLockFilePath(path string) string {
+	return filepath.Join(path, types.ProfileDirName, types.ProfileLockFileName)
+}
@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func locked(refs ...reference.Reference) *types.ProfileLock {
+	lock := &types.ProfileLock{}
+	for _, ref := range refs {
+		lock.Packages = append(lock.Packages, &types.LockedPackage{Reference: ref, Type: types.PackageAddon})
+	}
+
+	return lock
+}
+
+func TestVerifyLock(t *testing.T) {
+	deps := dependencies("addons/a/1.0.0", "addons/lib/1.2.0")
+	for _, dep := range deps {
+		dep.Type = types.PackageAddon
+	}
+
+	tests := []struct {
+		name    string
+		lock    *types.ProfileLock
+		wantErr error
+	}{
+		{name: "matching", lock: locked("addons/lib/1.2.0", "addons/a/1.0.0")},
+		{name: "missing lock", wantErr: types.ErrMissingProfileLock},
+		{name: "dependency not locked", lock: locked("addons/a/1.0.0"), wantErr: types.ErrProfileLockMismatch},
+		{name: "other version locked", lock: locked("addons/a/1.0.0", "addons/lib/1.0.0"), wantErr: types.ErrProfileLockMismatch},
+		{name: "extra package locked", lock: locked("addons/a/1.0.0", "addons/lib/1.2.0", "addons/old/1.0.0"), wantErr: types.ErrProfileLockMismatch},
+		{
+			name: "type mismatch",
+			lock: &types.ProfileLock{Packages: []*types.LockedPackage{
+				{Reference: "addons/a/1.0.0", Type: types.PackageAddon},
+				{Reference: "addons/lib/1.2.0", Type: types.PackageBuild},
+			}},
+			wantErr: types.ErrProfileLockMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := verifyLock(test.lock, deps); !errors.Is(err, test.wantErr) {
+				t.Errorf("verifyLock() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallProfilesFrozen(t *testing.T) {
+	packs := map[reference.Reference]*types.Package{
+		"addons/a/1.0.0":   addon("addons/lib@^1.0"),
+		"addons/lib/1.2.0": addon(),
+		"addons/lib/1.5.0": addon(),
+	}
+
+	tests := []struct {
+		name    string
+		lock    *types.ProfileLock
+		wantErr error
+	}{
+		{name: "missing lock", wantErr: types.ErrMissingProfileLock},
+		{name: "range not locked", lock: locked("addons/a/1.0.0"), wantErr: types.ErrProfileLockMismatch},
+		{name: "stale package locked", lock: locked("addons/a/1.0.0", "addons/lib/1.2.0", "addons/b/1.0.0"), wantErr: types.ErrProfileLockMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			driver, _ := newTestDriver(t, packs)
+			profile := &types.Profile{
+				Dependencies: []*types.Dependency{{Reference: "addons/a/1.0.0", Type: types.PackageAddon}},
+				Lock:         test.lock,
+			}
+
+			err := driver.InstallProfiles(context.Background(), &types.InstallProfilesOpts{
+				Profiles: []*types.Profile{profile},
+				Frozen:   true,
+			})
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("InstallProfiles() error = %v, want %v", err, test.wantErr)
+			}
+
+			if profile.Lock != test.lock {
+				t.Errorf("InstallProfiles() replaced the lock of a frozen profile")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/taskrunner"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// PruneProfiles removes the installations and package libraries that none of the profiles depend on, including
+// transitive dependencies. Profiles that cannot be resolved abort the prune, so nothing a project might still
+// need is removed.
+func (d *Driver) PruneProfiles(ctx context.Context, opts *types.PruneProfilesOpts) (*types.PruneProfilesResult, error) {
+	if err := d.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	tasks := make([]taskrunner.Task[[]*types.Dependency], len(opts.Profiles))
+	for i, profile := range opts.Profiles {
+		tasks[i] = func(ctx context.Context) ([]*types.Dependency, error) {
+			locks, err := profileLocks(profile, false)
+			if err != nil {
+				return nil, err
+			}
+
+			dependencies, _, err := d.resolveGraph(ctx, profile.Dependencies, locks, false, false)
+			if err != nil {
+				return nil, err
+			}
+
+			return dependencies, nil
+		}
+	}
+
+	results, err := taskrunner.Run(ctx, &taskrunner.RunOpts[[]*types.Dependency]{
+		Tasks:          tasks,
+		Mode:           d.executionMode,
+		MaxConcurrency: d.maxConcurrency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keep := []reference.Reference{}
+	seen := make(map[reference.Reference]struct{})
+	for _, dependencies := range results {
+		for _, dep := range dependencies {
+			if _, exists := seen[dep.Reference]; !exists {
+				seen[dep.Reference] = struct{}{}
+				keep = append(keep, dep.Reference)
+			}
+		}
+	}
+
+	d.logger.Debug("pruning unused installations and packages", map[string]interface{}{
+		"keep":   keep,
+		"dryRun": opts.DryRun,
+	})
+
+	installations, err := d.repository.PruneInstallations(ctx, &types.PruneOpts{
+		Keep:   keep,
+		DryRun: opts.DryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := d.repository.PrunePackages(ctx, &types.PruneOpts{
+		Keep:   keep,
+		DryRun: opts.DryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PruneProfilesResult{
+		Installations: installations,
+		Packages:      packages,
+	}, nil
+}
@@ -14,7 +14,7 @@ func (d *Driver) ResolveProfiles(ctx context.Context, opts *types.ResolveProfile
 	tasks := make([]taskrunner.Task[[]*types.Installation], len(opts.Profiles))
 	for i, profile := range opts.Profiles {
 		tasks[i] = func(ctx context.Context) ([]*types.Installation, error) {
-			installations, err := d.resolve(ctx, profile)
+			installations, err := d.resolve(ctx, profile, opts.Frozen)
 			if err != nil {
 				return nil, err
 			}
@@ -37,21 +37,42 @@ func (d *Driver) ResolveProfiles(ctx context.Context, opts *types.ResolveProfile
 	}, nil
 }
 
-func (d *Driver) resolve(ctx context.Context, profile *types.Profile) ([]*types.Installation, error) {
-	installations, err := d.getInstallations(ctx, profile.Dependencies, false)
+func (d *Driver) resolve(ctx context.Context, profile *types.Profile, frozen bool) ([]*types.Installation, error) {
+	locks, err := profileLocks(profile, frozen)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, packs, err := d.resolveGraph(ctx, profile.Dependencies, locks, false, frozen)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.checkBuildCompatibility(dependencies, packs, profile.Strict); err != nil {
+		return nil, err
+	}
+
+	if frozen {
+		if err := verifyLock(profile.Lock, dependencies); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := d.getInstallations(ctx, dependencies, locks, false, profile.RequireDigests, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	d.logger.Debug("resolving profile", map[string]interface{}{
 		"profile":       profile,
-		"installations": installations,
+		"installations": result.Installations,
+		"frozen":        frozen,
 	})
 
-	dependencies := make([]*types.Installation, 0, len(installations))
-	for _, installation := range installations {
-		dependencies = append(dependencies, installation)
+	installations := make([]*types.Installation, 0, len(result.Installations))
+	for _, installation := range result.Installations {
+		installations = append(installations, installation)
 	}
 
-	return dependencies, nil
+	return installations, nil
 }
@@ -47,6 +47,12 @@ func (d *Driver) save(ctx context.Context, path string, profile *types.Profile,
 		return err
 	}
 
+	if profile.Lock != nil {
+		if err := helpers.Save(d.validator, profileLockFilePath(path), profile.Lock, ensurePath, true); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
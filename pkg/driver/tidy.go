@@ -20,7 +20,16 @@ func (d *Driver) TidyProfiles(ctx context.Context, opts *types.TidyProfilesOpts)
 	tasks := make([]taskrunner.Task[[]*types.Dependency], len(opts.Profiles))
 	for i, profile := range opts.Profiles {
 		tasks[i] = func(ctx context.Context) ([]*types.Dependency, error) {
-			dependencies, err := d.tidyDependencies(ctx, profile.Dependencies, opts.Fetch)
+			// Fetching replaces the locked package definitions with the latest ones.
+			var locks map[reference.Reference]*types.LockedPackage
+			if !opts.Fetch {
+				var err error
+				if locks, err = profileLocks(profile, false); err != nil {
+					return nil, err
+				}
+			}
+
+			dependencies, err := d.tidyDependencies(ctx, profile.Dependencies, locks, opts.Fetch, profile.Strict)
 			if err != nil {
 				return nil, err
 			}
@@ -40,12 +49,15 @@ func (d *Driver) TidyProfiles(ctx context.Context, opts *types.TidyProfilesOpts)
 
 	for i, profile := range opts.Profiles {
 		profile.Dependencies = results[i]
+		if opts.Fetch {
+			profile.Lock = nil
+		}
 	}
 
 	return nil
 }
 
-func (d *Driver) tidyDependencies(ctx context.Context, dependencies []*types.Dependency, update bool) ([]*types.Dependency, error) {
+func (d *Driver) tidyDependencies(ctx context.Context, dependencies []*types.Dependency, locks map[reference.Reference]*types.LockedPackage, update bool, strict bool) ([]*types.Dependency, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -53,19 +65,28 @@ func (d *Driver) tidyDependencies(ctx context.Context, dependencies []*types.Dep
 	d.logger.Debug("tidying dependencies", map[string]interface{}{
 		"dependencies": dependencies,
 		"update":       update,
+		"strict":       strict,
 	})
 
+	dependencies, err := d.resolveDependencies(ctx, dependencies, update)
+	if err != nil {
+		return nil, err
+	}
+
 	references := make([]reference.Reference, 0, len(dependencies))
+	constraints := make(map[reference.Reference]string)
 	seen := make(map[reference.Reference]struct{})
 	for _, dep := range dependencies {
 		if _, exists := seen[dep.Reference]; !exists {
 			references = append(references, dep.Reference)
+			constraints[dep.Reference] = dep.Constraint
 			seen[dep.Reference] = struct{}{}
 		}
 	}
 
 	results, err := d.repository.GetPackages(ctx, &types.GetPackagesOpts{
 		References: references,
+		Locks:      locks,
 		Update:     update,
 	})
 	if err != nil {
@@ -87,8 +108,9 @@ func (d *Driver) tidyDependencies(ctx context.Context, dependencies []*types.Dep
 			}
 
 			tidied = append(tidied, &types.Dependency{
-				Reference: ref,
-				Type:      pack.Type,
+				Reference:  ref,
+				Type:       pack.Type,
+				Constraint: constraints[ref],
 			})
 		}
 	}
@@ -98,5 +120,73 @@ func (d *Driver) tidyDependencies(ctx context.Context, dependencies []*types.Dep
 		return tidied[i].Reference < tidied[j].Reference
 	})
 
+	// Only direct dependencies are kept in the profile, but the packages they require must fit together.
+	resolved, packs, err := d.resolveGraph(ctx, tidied, locks, update, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.checkBuildCompatibility(resolved, packs, strict); err != nil {
+		return nil, err
+	}
+
 	return tidied, nil
 }
+
+// resolveDependencies replaces ranged references with the highest matching version. Dependencies that were
+// resolved from a range keep their constraint, so updating moves them to the newest matching version.
+func (d *Driver) resolveDependencies(ctx context.Context, dependencies []*types.Dependency, update bool) ([]*types.Dependency, error) {
+	targets := make([]reference.Reference, len(dependencies))
+	ranged := make([]reference.Reference, 0, len(dependencies))
+	seen := make(map[reference.Reference]struct{})
+	for i, dep := range dependencies {
+		targets[i] = dep.Reference
+		if !dep.Reference.IsRange() {
+			if dep.Constraint == "" || !update {
+				continue
+			}
+
+			targets[i] = dep.Reference.Parent().WithConstraint(dep.Constraint)
+		}
+
+		if _, exists := seen[targets[i]]; !exists {
+			ranged = append(ranged, targets[i])
+			seen[targets[i]] = struct{}{}
+		}
+	}
+
+	if len(ranged) == 0 {
+		return dependencies, nil
+	}
+
+	result, err := d.repository.ResolveReferences(ctx, &types.ResolveReferencesOpts{
+		References: ranged,
+		Update:     update,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]*types.Dependency, 0, len(dependencies))
+	for i, dep := range dependencies {
+		if !targets[i].IsRange() {
+			resolved = append(resolved, dep)
+			continue
+		}
+
+		ref := result.References[targets[i]]
+		_, constraint := targets[i].Range()
+		d.logger.Debug("resolved dependency", map[string]interface{}{
+			"reference": targets[i].String(),
+			"resolved":  ref.String(),
+		})
+
+		resolved = append(resolved, &types.Dependency{
+			Reference:  ref,
+			Type:       dep.Type,
+			Constraint: constraint,
+		})
+	}
+
+	return resolved, nil
+}
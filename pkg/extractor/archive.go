@@ -0,0 +1,278 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	kzip "github.com/klauspost/compress/zip"
+	"github.com/mholt/archiver/v3"
+)
+
+// extractArchive extracts the archive into the destination. Entries that would be written outside of the
+// destination, either directly or through a link, are rejected.
+func (e *Extractor) extractArchive(ctx context.Context, filePath string, destination string, progress *progressReporter) error {
+	// Formats are picked by the name of the file, which archiver only matches in lower case.
+	format, err := archiver.ByExtension(strings.ToLower(filepath.Base(filePath)))
+	if err != nil {
+		return err
+	}
+
+	reader, ok := format.(archiver.Reader)
+	if !ok {
+		return fmt.Errorf("unsupported archive format: %s", filePath)
+	}
+
+	file, info, err := openArchive(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Reading the archive through a counter measures progress for compressed streams too.
+	if err := reader.Open(&countingFile{file: file, reporter: progress}, info.Size()); err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		f, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = writeEntry(destination, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		progress.addEntry()
+	}
+}
+
+// extractSevenZip extracts the 7z archive into the destination, with the same checks as other archives.
+func (e *Extractor) extractSevenZip(ctx context.Context, filePath string, destination string, progress *progressReporter) error {
+	file, info, err := openArchive(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := sevenzip.NewReader(&countingFile{file: file, reporter: progress}, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, file := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := writeSevenZipEntry(destination, file); err != nil {
+			return err
+		}
+
+		progress.addEntry()
+	}
+
+	return nil
+}
+
+func openArchive(filePath string) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, info, nil
+}
+
+func writeSevenZipEntry(destination string, file *sevenzip.File) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return writeEntry(destination, archiver.File{
+		FileInfo:   file.FileInfo(),
+		Header:     file.FileHeader,
+		ReadCloser: rc,
+	})
+}
+
+// writeEntry writes a single archive entry below the destination.
+func writeEntry(destination string, f archiver.File) error {
+	name, linkName, hardLink := entryNames(f)
+	if name == "" {
+		return nil
+	}
+
+	target, err := securePath(destination, name)
+	if err != nil {
+		return err
+	}
+
+	// Entries may not be written through links extracted earlier, which could point anywhere.
+	if err := checkNoLinks(destination, target); err != nil {
+		return err
+	}
+
+	if f.IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case hardLink:
+		source, err := securePath(destination, linkName)
+		if err != nil {
+			return err
+		}
+
+		if err := checkNoLinks(destination, filepath.Dir(source)); err != nil {
+			return err
+		}
+
+		return os.Link(source, target)
+	case f.Mode()&os.ModeSymlink != 0:
+		// Zip archives store the link target as the content of the entry.
+		if linkName == "" {
+			data, err := io.ReadAll(io.LimitReader(f, 4096))
+			if err != nil {
+				return err
+			}
+
+			linkName = string(data)
+		}
+
+		if err := checkLinkTarget(destination, name, linkName); err != nil {
+			return err
+		}
+
+		return os.Symlink(linkName, target)
+	case f.Mode().IsRegular():
+		return writeFile(target, f, f.Mode().Perm())
+	default:
+		// Devices, pipes and the like have no place in a package.
+		return nil
+	}
+}
+
+// entryNames returns the path of an entry within the archive, the target of a link entry and whether the
+// entry is a hard link.
+func entryNames(f archiver.File) (string, string, bool) {
+	switch header := f.Header.(type) {
+	case *tar.Header:
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			return "", "", false
+		}
+
+		return header.Name, header.Linkname, header.Typeflag == tar.TypeLink
+	case zip.FileHeader:
+		// archiver reads zip files with klauspost/compress, but the standard header may still be used.
+		return header.Name, "", false
+	case kzip.FileHeader:
+		return header.Name, "", false
+	case sevenzip.FileHeader:
+		return header.Name, "", false
+	default:
+		return f.Name(), "", false
+	}
+}
+
+// securePath returns the path of an archive entry below the destination, or an error if it would escape it.
+func securePath(destination string, name string) (string, error) {
+	name = filepath.FromSlash(strings.ReplaceAll(name, `\`, "/"))
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("illegal file path in archive: %s", name)
+	}
+
+	target := filepath.Join(destination, name)
+	rel, err := filepath.Rel(destination, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal file path in archive: %s", name)
+	}
+
+	return target, nil
+}
+
+// checkLinkTarget returns an error if the target of the symbolic link entry is absolute or outside of the
+// destination.
+func checkLinkTarget(destination string, name string, linkName string) error {
+	if filepath.IsAbs(linkName) || strings.HasPrefix(linkName, "/") {
+		return fmt.Errorf("illegal link target in archive: %s -> %s", name, linkName)
+	}
+
+	if _, err := securePath(destination, filepath.Join(filepath.Dir(name), linkName)); err != nil {
+		return fmt.Errorf("illegal link target in archive: %s -> %s", name, linkName)
+	}
+
+	return nil
+}
+
+// checkNoLinks returns an error if the path, or any existing directory between it and the destination, is a
+// symbolic link.
+func checkNoLinks(destination string, path string) error {
+	rel, err := filepath.Rel(destination, path)
+	if err != nil || rel == "." {
+		return err
+	}
+
+	current := destination
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("illegal file path in archive, writes through link: %s", current)
+		}
+	}
+
+	return nil
+}
+
+func writeFile(path string, reader io.Reader, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0200)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
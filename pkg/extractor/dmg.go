@@ -4,15 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	"github.com/rocketblend/rocketblend/pkg/extractor/dmg"
 	"github.com/rocketblend/rocketblend/pkg/helpers"
 )
 
-// TODO: Better cleanup on context cancel
-func (e *Extractor) extractDMG(ctx context.Context, filePath string, destination string) error {
+// extractDMG copies the applications at the root of the disk image's volume into the destination.
+func (e *Extractor) extractDMG(ctx context.Context, filePath string, destination string, progress *progressReporter) error {
 	logContext := map[string]interface{}{
 		"filePath":    filePath,
 		"destination": destination,
@@ -20,6 +24,155 @@ func (e *Extractor) extractDMG(ctx context.Context, filePath string, destination
 
 	e.logger.Debug("starting .dmg extraction", logContext)
 
+	err := e.copyDMGApps(ctx, filePath, destination, progress)
+	if errors.Is(err, dmg.ErrUnsupported) && runtime.GOOS == "darwin" {
+		logContext["error"] = err.Error()
+		e.logger.Warn("falling back to hdiutil for .dmg extraction", logContext)
+		return e.attachDMG(ctx, filePath, destination)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	e.logger.Info("extraction of .dmg is complete", logContext)
+
+	return nil
+}
+
+func (e *Extractor) copyDMGApps(ctx context.Context, filePath string, destination string, progress *progressReporter) error {
+	image, err := dmg.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer image.Close()
+
+	fsys, err := image.FileSystem()
+	if err != nil {
+		return err
+	}
+
+	appFiles, err := fs.Glob(fsys, "*.app")
+	if err != nil {
+		return fmt.Errorf("could not search for app files: %w", err)
+	}
+
+	if len(appFiles) == 0 {
+		return fmt.Errorf("no app files found in the .dmg")
+	}
+
+	e.logger.Debug("found app files", map[string]interface{}{"appFiles": appFiles})
+
+	// Progress is measured against the size of the files to copy, reading the image's catalog is quick.
+	total, err := dmgAppsSize(fsys, appFiles)
+	if err != nil {
+		return err
+	}
+
+	progress.setTotal(total)
+
+	for _, appFile := range appFiles {
+		if err := fs.WalkDir(fsys, appFile, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := copyDMGEntry(fsys, destination, name, entry, progress); err != nil {
+				return err
+			}
+
+			progress.addEntry()
+			return nil
+		}); err != nil {
+			return fmt.Errorf("could not copy app files: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// copyDMGEntry writes a single entry of the image's volume below the destination, with the same checks as
+// archive entries.
+func copyDMGEntry(fsys *dmg.FileSystem, destination string, name string, entry fs.DirEntry, progress *progressReporter) error {
+	target, err := securePath(destination, name)
+	if err != nil {
+		return err
+	}
+
+	if err := checkNoLinks(destination, target); err != nil {
+		return err
+	}
+
+	switch {
+	case entry.IsDir():
+		return os.MkdirAll(target, 0755)
+	case entry.Type()&fs.ModeSymlink != 0:
+		linkName, err := fsys.ReadLink(name)
+		if err != nil {
+			return err
+		}
+
+		if err := checkLinkTarget(destination, name, linkName); err != nil {
+			return err
+		}
+
+		return os.Symlink(linkName, target)
+	case entry.Type().IsRegular():
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return writeFile(target, countingReader(file, progress), info.Mode().Perm())
+	default:
+		return nil
+	}
+}
+
+// dmgAppsSize returns the size of the regular files in the applications.
+func dmgAppsSize(fsys *dmg.FileSystem, appFiles []string) (int64, error) {
+	var total int64
+	for _, appFile := range appFiles {
+		if err := fs.WalkDir(fsys, appFile, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.Type().IsRegular() {
+				return err
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+
+			total += info.Size()
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	return total, nil
+}
+
+// attachDMG mounts the image with hdiutil and copies the applications out of it. It's only available on
+// macOS, for images the pure Go reader doesn't support.
+func (e *Extractor) attachDMG(ctx context.Context, filePath string, destination string) error {
+	logContext := map[string]interface{}{
+		"filePath":    filePath,
+		"destination": destination,
+	}
+
+	e.logger.Debug("starting .dmg extraction with hdiutil", logContext)
+
 	// Mount the DMG file
 	cmd := exec.CommandContext(ctx, "hdiutil", "attach", "-nobrowse", filePath)
 	helpers.SetupSysProcAttr(cmd)
@@ -0,0 +1,56 @@
+package dmg
+
+import "errors"
+
+var errADCCorrupt = errors.New("corrupt ADC data")
+
+// decodeADC decompresses Apple Data Compression, used by old disk images, into at most size bytes.
+func decodeADC(src []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for len(src) > 0 && len(out) < size {
+		op := src[0]
+		switch {
+		case op&0x80 != 0:
+			length := int(op&0x7f) + 1
+			if len(src) < 1+length {
+				return nil, errADCCorrupt
+			}
+
+			out = append(out, src[1:1+length]...)
+			src = src[1+length:]
+			continue
+		case op&0x40 != 0:
+			if len(src) < 3 {
+				return nil, errADCCorrupt
+			}
+
+			length := int(op&0x3f) + 4
+			distance := (int(src[1])<<8 | int(src[2])) + 1
+			src = src[3:]
+			if distance > len(out) {
+				return nil, errADCCorrupt
+			}
+
+			out = copyMatch(out, distance, length)
+		default:
+			if len(src) < 2 {
+				return nil, errADCCorrupt
+			}
+
+			length := int(op>>2) + 3
+			distance := (int(op&0x03)<<8 | int(src[1])) + 1
+			src = src[2:]
+			if distance > len(out) {
+				return nil, errADCCorrupt
+			}
+
+			out = copyMatch(out, distance, length)
+		}
+	}
+
+	if len(out) > size {
+		out = out[:size]
+	}
+
+	return out, nil
+}
@@ -0,0 +1,631 @@
+package dmg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	apfsContainerMagic = "NXSB"
+	apfsVolumeMagic    = "APSB"
+
+	apfsNodeHeaderSize = 56
+	apfsTreeInfoSize   = 40
+	apfsMaxFileSystems = 100
+
+	apfsObjectSuperblock = 0x1
+	apfsObjectTypeMask   = 0xffff
+
+	apfsNodeRoot  = 0x1
+	apfsNodeLeaf  = 0x2
+	apfsNodeFixed = 0x4
+
+	apfsOmapDeleted   = 0x1
+	apfsOmapEncrypted = 0x4
+
+	apfsIncompatCaseInsensitive          = 0x1
+	apfsIncompatNormalizationInsensitive = 0x8
+	apfsUnencrypted                      = 0x1
+
+	apfsTypeInode      = 3
+	apfsTypeXattr      = 4
+	apfsTypeFileExtent = 8
+	apfsTypeDirRecord  = 9
+
+	apfsRootDirID       = 2
+	apfsXattrStream     = 0x1
+	apfsXattrEmbedded   = 0x2
+	apfsInodeDstream    = 8
+	apfsInodeCompressed = 0x20
+
+	apfsSymlinkAttribute = "com.apple.fs.symlink"
+
+	// apfsMaxTreeDepth limits the height of B-trees, so a corrupt volume can't recurse forever.
+	apfsMaxTreeDepth = 16
+)
+
+type (
+	apfsContainer struct {
+		r         io.ReaderAt
+		blockSize int64
+	}
+
+	// apfsNode is a B-tree node, with its keys and values.
+	apfsNode struct {
+		leaf   bool
+		keys   [][]byte
+		values [][]byte
+	}
+
+	apfsInode struct {
+		privateID  uint64
+		mode       uint16
+		modTime    time.Time
+		compressed bool
+		size       int64
+	}
+
+	apfsDirEntry struct {
+		name   string
+		fileID uint64
+	}
+
+	apfsXattr struct {
+		flags uint16
+		data  []byte
+	}
+
+	apfsVolume struct {
+		container *apfsContainer
+		omap      map[uint64]uint64
+		hashed    bool
+		inodes    map[uint64]*apfsInode
+		children  map[uint64][]apfsDirEntry
+		extents   map[uint64][]extent
+		xattrs    map[uint64]map[string]apfsXattr
+	}
+)
+
+// openAPFS reads the first volume of the APFS container and returns its root directory.
+func openAPFS(r io.ReaderAt) (*node, error) {
+	header := make([]byte, 4096)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	blockSize := int64(binary.LittleEndian.Uint32(header[36:]))
+	if blockSize < 4096 || blockSize > 65536 || blockSize&(blockSize-1) != 0 {
+		return nil, fmt.Errorf("invalid APFS block size %d", blockSize)
+	}
+
+	c := &apfsContainer{r: r, blockSize: blockSize}
+	superblock, err := c.superblock()
+	if err != nil {
+		return nil, err
+	}
+
+	xid := binary.LittleEndian.Uint64(superblock[16:])
+	omap, err := c.objectMap(binary.LittleEndian.Uint64(superblock[160:]), xid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container object map: %w", err)
+	}
+
+	count := min(binary.LittleEndian.Uint32(superblock[180:]), apfsMaxFileSystems)
+	for i := uint32(0); i < count; i++ {
+		oid := binary.LittleEndian.Uint64(superblock[184+8*i:])
+		if oid == 0 {
+			continue
+		}
+
+		address, ok := omap[oid]
+		if !ok {
+			return nil, fmt.Errorf("volume %d not found in object map", oid)
+		}
+
+		volume, err := c.block(address)
+		if err != nil {
+			return nil, err
+		}
+
+		if string(volume[32:36]) != apfsVolumeMagic {
+			return nil, errors.New("invalid APFS volume superblock")
+		}
+
+		return c.openVolume(volume, xid)
+	}
+
+	return nil, errors.New("APFS container has no volumes")
+}
+
+func (c *apfsContainer) block(address uint64) ([]byte, error) {
+	block := make([]byte, c.blockSize)
+	if _, err := c.r.ReadAt(block, int64(address)*c.blockSize); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// superblock returns the container superblock of the latest checkpoint. Block zero holds a copy, which may
+// be older if the container wasn't unmounted cleanly.
+func (c *apfsContainer) superblock() ([]byte, error) {
+	latest, err := c.block(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(latest[32:36]) != apfsContainerMagic {
+		return nil, errors.New("invalid APFS container superblock")
+	}
+
+	// A set high bit means the checkpoint descriptor area is a B-tree rather than contiguous blocks.
+	descriptorBlocks := binary.LittleEndian.Uint32(latest[104:])
+	descriptorBase := binary.LittleEndian.Uint64(latest[112:])
+	if descriptorBlocks&0x80000000 != 0 {
+		return latest, nil
+	}
+
+	for i := uint64(0); i < uint64(descriptorBlocks); i++ {
+		block, err := c.block(descriptorBase + i)
+		if err != nil {
+			return nil, err
+		}
+
+		if binary.LittleEndian.Uint32(block[24:])&apfsObjectTypeMask != apfsObjectSuperblock ||
+			string(block[32:36]) != apfsContainerMagic || !validAPFSChecksum(block) {
+			continue
+		}
+
+		if binary.LittleEndian.Uint64(block[16:]) > binary.LittleEndian.Uint64(latest[16:]) {
+			latest = block
+		}
+	}
+
+	return latest, nil
+}
+
+// objectMap returns the physical addresses of the objects in the object map, as of the transaction.
+func (c *apfsContainer) objectMap(address uint64, xid uint64) (map[uint64]uint64, error) {
+	omap, err := c.block(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[uint64]uint64)
+	versions := make(map[uint64]uint64)
+	err = c.walkTree(binary.LittleEndian.Uint64(omap[48:]), nil, 16, 16, func(key []byte, value []byte) error {
+		oid := binary.LittleEndian.Uint64(key)
+		version := binary.LittleEndian.Uint64(key[8:])
+		if seen, ok := versions[oid]; version > xid || (ok && seen >= version) {
+			return nil
+		}
+
+		flags := binary.LittleEndian.Uint32(value)
+		if flags&apfsOmapEncrypted != 0 {
+			return fmt.Errorf("%w: encrypted objects", ErrUnsupported)
+		}
+
+		versions[oid] = version
+		if flags&apfsOmapDeleted != 0 {
+			delete(addresses, oid)
+			return nil
+		}
+
+		addresses[oid] = binary.LittleEndian.Uint64(value[8:])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// walkTree calls the function with every record of the B-tree. Child nodes of virtual trees are looked up in
+// the object map, physical trees pass nil. Fixed size trees pass the size of their keys and values.
+func (c *apfsContainer) walkTree(root uint64, omap map[uint64]uint64, keySize int, valueSize int, fn func(key []byte, value []byte) error) error {
+	var walk func(oid uint64, depth int) error
+	walk = func(oid uint64, depth int) error {
+		if depth > apfsMaxTreeDepth {
+			return errors.New("B-tree is nested too deeply")
+		}
+
+		address := oid
+		if omap != nil {
+			var ok bool
+			if address, ok = omap[oid]; !ok {
+				return fmt.Errorf("B-tree node %d not found in object map", oid)
+			}
+		}
+
+		block, err := c.block(address)
+		if err != nil {
+			return err
+		}
+
+		n, err := parseAPFSNode(block, keySize, valueSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range n.keys {
+			if n.leaf {
+				err = fn(n.keys[i], n.values[i])
+			} else if len(n.values[i]) < 8 {
+				err = errors.New("invalid B-tree index value")
+			} else {
+				err = walk(binary.LittleEndian.Uint64(n.values[i]), depth+1)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(root, 0)
+}
+
+func parseAPFSNode(block []byte, keySize int, valueSize int) (*apfsNode, error) {
+	flags := binary.LittleEndian.Uint16(block[32:])
+	count := int(binary.LittleEndian.Uint32(block[36:]))
+	tableStart := apfsNodeHeaderSize + int(binary.LittleEndian.Uint16(block[40:]))
+	keyStart := tableStart + int(binary.LittleEndian.Uint16(block[42:]))
+	valueEnd := len(block)
+	if flags&apfsNodeRoot != 0 {
+		valueEnd -= apfsTreeInfoSize
+	}
+
+	n := &apfsNode{leaf: flags&apfsNodeLeaf != 0}
+	fixed := flags&apfsNodeFixed != 0
+	if fixed && keySize == 0 {
+		return nil, errors.New("unexpected fixed size B-tree node")
+	}
+
+	if !n.leaf {
+		// Index nodes point to their children by object id.
+		valueSize = 8
+	}
+
+	entrySize := 8
+	if fixed {
+		entrySize = 4
+	}
+
+	if keyStart > valueEnd || tableStart+count*entrySize > keyStart {
+		return nil, errors.New("invalid B-tree node")
+	}
+
+	for i := 0; i < count; i++ {
+		entry := block[tableStart+i*entrySize:]
+		var keyOffset, keyLength, valueOffset, valueLength int
+		if fixed {
+			keyOffset, keyLength = int(binary.LittleEndian.Uint16(entry)), keySize
+			valueOffset, valueLength = int(binary.LittleEndian.Uint16(entry[2:])), valueSize
+		} else {
+			keyOffset, keyLength = int(binary.LittleEndian.Uint16(entry)), int(binary.LittleEndian.Uint16(entry[2:]))
+			valueOffset, valueLength = int(binary.LittleEndian.Uint16(entry[4:])), int(binary.LittleEndian.Uint16(entry[6:]))
+		}
+
+		key := keyStart + keyOffset
+		value := valueEnd - valueOffset
+		if key+keyLength > valueEnd || value < keyStart || value+valueLength > valueEnd {
+			return nil, errors.New("invalid B-tree node entry")
+		}
+
+		n.keys = append(n.keys, block[key:key+keyLength])
+		n.values = append(n.values, block[value:value+valueLength])
+	}
+
+	return n, nil
+}
+
+func (c *apfsContainer) openVolume(superblock []byte, xid uint64) (*node, error) {
+	if binary.LittleEndian.Uint64(superblock[264:])&apfsUnencrypted == 0 {
+		return nil, fmt.Errorf("%w: encrypted APFS volume", ErrUnsupported)
+	}
+
+	omap, err := c.objectMap(binary.LittleEndian.Uint64(superblock[128:]), xid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume object map: %w", err)
+	}
+
+	v := &apfsVolume{
+		container: c,
+		omap:      omap,
+		hashed:    binary.LittleEndian.Uint64(superblock[56:])&(apfsIncompatCaseInsensitive|apfsIncompatNormalizationInsensitive) != 0,
+		inodes:    make(map[uint64]*apfsInode),
+		children:  make(map[uint64][]apfsDirEntry),
+		extents:   make(map[uint64][]extent),
+		xattrs:    make(map[uint64]map[string]apfsXattr),
+	}
+
+	if err := c.walkTree(binary.LittleEndian.Uint64(superblock[136:]), omap, 0, 0, v.addRecord); err != nil {
+		return nil, fmt.Errorf("failed to read file system tree: %w", err)
+	}
+
+	for _, extents := range v.extents {
+		sort.Slice(extents, func(i, j int) bool {
+			return extents[i].logical < extents[j].logical
+		})
+	}
+
+	children, err := v.directory(apfsRootDirID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node{name: ".", mode: fs.ModeDir | 0755, children: children}, nil
+}
+
+func (v *apfsVolume) addRecord(key []byte, value []byte) error {
+	if len(key) < 8 {
+		return errors.New("invalid file system record")
+	}
+
+	header := binary.LittleEndian.Uint64(key)
+	id := header & 0x0fffffffffffffff
+
+	switch header >> 60 {
+	case apfsTypeInode:
+		if len(value) < 92 {
+			return errors.New("invalid inode record")
+		}
+
+		v.inodes[id] = &apfsInode{
+			privateID:  binary.LittleEndian.Uint64(value[8:]),
+			modTime:    time.Unix(0, int64(binary.LittleEndian.Uint64(value[24:]))).UTC(),
+			compressed: binary.LittleEndian.Uint32(value[68:])&apfsInodeCompressed != 0,
+			mode:       binary.LittleEndian.Uint16(value[80:]),
+			size:       apfsStreamSize(value[92:]),
+		}
+	case apfsTypeDirRecord:
+		name, err := v.dirRecordName(key)
+		if err != nil {
+			return err
+		}
+
+		if len(value) < 18 {
+			return errors.New("invalid directory record")
+		}
+
+		v.children[id] = append(v.children[id], apfsDirEntry{name: name, fileID: binary.LittleEndian.Uint64(value)})
+	case apfsTypeFileExtent:
+		if len(key) < 16 || len(value) < 16 {
+			return errors.New("invalid file extent record")
+		}
+
+		physical := int64(binary.LittleEndian.Uint64(value[8:])) * v.container.blockSize
+		if physical == 0 {
+			physical = -1
+		}
+
+		v.extents[id] = append(v.extents[id], extent{
+			logical:  int64(binary.LittleEndian.Uint64(key[8:])),
+			physical: physical,
+			length:   int64(binary.LittleEndian.Uint64(value) & 0x00ffffffffffffff),
+		})
+	case apfsTypeXattr:
+		if len(key) < 10 || len(value) < 4 {
+			return errors.New("invalid extended attribute record")
+		}
+
+		nameLength := int(binary.LittleEndian.Uint16(key[8:]))
+		dataLength := int(binary.LittleEndian.Uint16(value[2:]))
+		if len(key) < 10+nameLength || len(value) < 4+dataLength {
+			return errors.New("invalid extended attribute record")
+		}
+
+		if v.xattrs[id] == nil {
+			v.xattrs[id] = make(map[string]apfsXattr)
+		}
+
+		name := strings.TrimRight(string(key[10:10+nameLength]), "\x00")
+		v.xattrs[id][name] = apfsXattr{flags: binary.LittleEndian.Uint16(value), data: value[4 : 4+dataLength]}
+	}
+
+	return nil
+}
+
+// dirRecordName returns the name of a directory record. Volumes that ignore case or normalization store a
+// hash of the name in front of it.
+func (v *apfsVolume) dirRecordName(key []byte) (string, error) {
+	var name []byte
+	if v.hashed {
+		if len(key) < 12 {
+			return "", errors.New("invalid directory record key")
+		}
+
+		length := int(binary.LittleEndian.Uint32(key[8:]) & 0x3ff)
+		if len(key) < 12+length {
+			return "", errors.New("invalid directory record key")
+		}
+
+		name = key[12 : 12+length]
+	} else {
+		if len(key) < 10 {
+			return "", errors.New("invalid directory record key")
+		}
+
+		length := int(binary.LittleEndian.Uint16(key[8:]))
+		if len(key) < 10+length {
+			return "", errors.New("invalid directory record key")
+		}
+
+		name = key[10 : 10+length]
+	}
+
+	return strings.TrimRight(string(name), "\x00"), nil
+}
+
+// apfsStreamSize returns the size of the data stream in the extended fields of an inode, or zero if it has
+// none.
+func apfsStreamSize(fields []byte) int64 {
+	if len(fields) < 4 {
+		return 0
+	}
+
+	count := int(binary.LittleEndian.Uint16(fields))
+	if len(fields) < 4+4*count {
+		return 0
+	}
+
+	offset := 4 + 4*count
+	for i := 0; i < count; i++ {
+		header := fields[4+4*i:]
+		size := int(binary.LittleEndian.Uint16(header[2:]))
+		if header[0] == apfsInodeDstream && offset+8 <= len(fields) {
+			return int64(binary.LittleEndian.Uint64(fields[offset:]))
+		}
+
+		// Field data is aligned to eight bytes.
+		offset += (size + 7) &^ 7
+	}
+
+	return 0
+}
+
+func (v *apfsVolume) directory(id uint64, depth int) ([]*node, error) {
+	if depth > maxDepth {
+		return nil, errors.New("directories are nested too deeply")
+	}
+
+	var nodes []*node
+	for _, entry := range v.children[id] {
+		n, err := v.node(entry, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.name, err)
+		}
+
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return sortNodes(nodes)
+}
+
+func (v *apfsVolume) node(entry apfsDirEntry, depth int) (*node, error) {
+	inode, ok := v.inodes[entry.fileID]
+	if !ok {
+		return nil, fmt.Errorf("inode %d not found", entry.fileID)
+	}
+
+	n := &node{name: cleanName(entry.name), modTime: inode.modTime}
+	switch inode.mode & 0xf000 {
+	case 0x4000:
+		children, err := v.directory(entry.fileID, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		n.mode = fs.ModeDir | fileMode(inode.mode, 0755)
+		n.children = children
+	case 0xa000:
+		target, err := v.xattr(entry.fileID, apfsSymlinkAttribute)
+		if err != nil {
+			return nil, err
+		}
+
+		link, err := readLinkTarget(target.reader())
+		if err != nil {
+			return nil, err
+		}
+
+		n.mode = fs.ModeSymlink | 0777
+		n.link = link
+	case 0x8000:
+		n.mode = fileMode(inode.mode, 0644)
+		if err := v.setContent(n, entry.fileID, inode); err != nil {
+			return nil, err
+		}
+	default:
+		// Devices, sockets and pipes have no content to copy.
+		return nil, nil
+	}
+
+	return n, nil
+}
+
+func (v *apfsVolume) setContent(n *node, id uint64, inode *apfsInode) error {
+	if inode.compressed {
+		header, err := v.xattr(id, decmpfsAttribute)
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(header.reader())
+		if err != nil {
+			return err
+		}
+
+		size, open, err := decmpfsContent(data, func() (io.ReaderAt, int64, error) {
+			resource, err := v.xattr(id, resourceForkAttribute)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			return resource, resource.size, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		n.size = size
+		n.open = open
+		return nil
+	}
+
+	data := &extentReader{r: v.container.r, extents: v.extents[inode.privateID], size: inode.size}
+	n.size = data.size
+	n.open = func() (io.Reader, error) {
+		return data.reader(), nil
+	}
+
+	return nil
+}
+
+// xattr returns a reader for the extended attribute, whether it's stored in its record or a data stream.
+func (v *apfsVolume) xattr(id uint64, name string) (*extentReader, error) {
+	attribute, ok := v.xattrs[id][name]
+	if !ok {
+		return nil, fmt.Errorf("extended attribute %s not found", name)
+	}
+
+	switch {
+	case attribute.flags&apfsXattrEmbedded != 0:
+		return &extentReader{r: bytes.NewReader(attribute.data), extents: []extent{{length: int64(len(attribute.data))}}, size: int64(len(attribute.data))}, nil
+	case attribute.flags&apfsXattrStream != 0 && len(attribute.data) >= 16:
+		streamID := binary.LittleEndian.Uint64(attribute.data)
+		return &extentReader{r: v.container.r, extents: v.extents[streamID], size: int64(binary.LittleEndian.Uint64(attribute.data[8:]))}, nil
+	default:
+		return nil, fmt.Errorf("invalid extended attribute %s", name)
+	}
+}
+
+// validAPFSChecksum reports whether the Fletcher-64 checksum at the start of an object matches its content.
+func validAPFSChecksum(block []byte) bool {
+	return binary.LittleEndian.Uint64(block) == apfsChecksum(block[8:])
+}
+
+func apfsChecksum(data []byte) uint64 {
+	const mod = 0xffffffff
+	var sum1, sum2 uint64
+	for i := 0; i+4 <= len(data); i += 4 {
+		sum1 = (sum1 + uint64(binary.LittleEndian.Uint32(data[i:]))) % mod
+		sum2 = (sum2 + sum1) % mod
+	}
+
+	c1 := mod - (sum1+sum2)%mod
+	c2 := mod - (sum1+c1)%mod
+	return c2<<32 | c1
+}
@@ -0,0 +1,226 @@
+package dmg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// decmpfsAttribute is the extended attribute holding the header of a file with transparent compression.
+	decmpfsAttribute = "com.apple.decmpfs"
+	// resourceForkAttribute is the extended attribute APFS stores resource forks in.
+	resourceForkAttribute = "com.apple.ResourceFork"
+
+	decmpfsMagic      = "fpmc"
+	decmpfsHeaderSize = 16
+	decmpfsBlockSize  = 64 * 1024
+)
+
+// Compression types of a decmpfs header. Odd types store the data in the attribute, even types in the
+// resource fork.
+const (
+	decmpfsZlibAttribute  = 3
+	decmpfsZlibResource   = 4
+	decmpfsLZVNAttribute  = 7
+	decmpfsLZVNResource   = 8
+	decmpfsLZFSEAttribute = 11
+	decmpfsLZFSEResource  = 12
+)
+
+type (
+	// decmpfsBlock is a compressed block of the resource fork.
+	decmpfsBlock struct {
+		offset int64
+		length int64
+	}
+
+	// decmpfsReader decompresses the blocks of a resource fork in order.
+	decmpfsReader struct {
+		r       io.ReaderAt
+		blocks  []decmpfsBlock
+		decode  func(data []byte, size int) ([]byte, error)
+		size    int64
+		read    int64
+		current []byte
+	}
+)
+
+// decmpfsContent returns the uncompressed size of a file with transparent compression and a function opening
+// its content.
+func decmpfsContent(header []byte, resourceFork func() (io.ReaderAt, int64, error)) (int64, func() (io.Reader, error), error) {
+	if len(header) < decmpfsHeaderSize || string(header[:4]) != decmpfsMagic {
+		return 0, nil, errors.New("invalid compression header")
+	}
+
+	kind := binary.LittleEndian.Uint32(header[4:])
+	size := int64(binary.LittleEndian.Uint64(header[8:]))
+	data := header[decmpfsHeaderSize:]
+
+	switch kind {
+	case decmpfsZlibAttribute, decmpfsLZVNAttribute, decmpfsLZFSEAttribute:
+		return size, func() (io.Reader, error) {
+			content, err := decmpfsDecoder(kind)(data, int(size))
+			if err != nil {
+				return nil, err
+			}
+
+			if int64(len(content)) != size {
+				return nil, fmt.Errorf("compressed file has %d bytes, expected %d", len(content), size)
+			}
+
+			return bytes.NewReader(content), nil
+		}, nil
+	case decmpfsZlibResource, decmpfsLZVNResource, decmpfsLZFSEResource:
+		return size, func() (io.Reader, error) {
+			r, length, err := resourceFork()
+			if err != nil {
+				return nil, err
+			}
+
+			blocks, err := decmpfsBlocks(kind, r, length)
+			if err != nil {
+				return nil, err
+			}
+
+			if int64(len(blocks)) != (size+decmpfsBlockSize-1)/decmpfsBlockSize {
+				return nil, errors.New("compressed block count doesn't match the file size")
+			}
+
+			return &decmpfsReader{r: r, blocks: blocks, decode: decmpfsDecoder(kind), size: size}, nil
+		}, nil
+	default:
+		return 0, nil, fmt.Errorf("%w: compression type %d", ErrUnsupported, kind)
+	}
+}
+
+// decmpfsDecoder returns the function decompressing a block of the compression type. Each format marks blocks
+// that didn't compress well, which are stored as is after the marker.
+func decmpfsDecoder(kind uint32) func(data []byte, size int) ([]byte, error) {
+	return func(data []byte, size int) ([]byte, error) {
+		if len(data) == 0 {
+			return nil, nil
+		}
+
+		switch kind {
+		case decmpfsZlibAttribute, decmpfsZlibResource:
+			if data[0]&0x0f == 0x0f {
+				return data[1:], nil
+			}
+
+			return readAllLimit(func() (io.Reader, error) { return zlib.NewReader(bytes.NewReader(data)) }, size)
+		case decmpfsLZVNAttribute, decmpfsLZVNResource:
+			if data[0] == lzvnEndOfStream {
+				return data[1:], nil
+			}
+
+			return decodeLZVN(nil, data, size)
+		default:
+			if data[0] == 0xff {
+				return data[1:], nil
+			}
+
+			return decodeLZFSE(data, size)
+		}
+	}
+}
+
+// decmpfsBlocks reads the table of compressed blocks at the start of a resource fork.
+func decmpfsBlocks(kind uint32, r io.ReaderAt, length int64) ([]decmpfsBlock, error) {
+	if kind == decmpfsZlibResource {
+		// A classic resource fork with a single "cmpf" resource, which starts with the block table.
+		header := make([]byte, 4)
+		if _, err := r.ReadAt(header, 0); err != nil {
+			return nil, err
+		}
+
+		base := int64(binary.BigEndian.Uint32(header)) + 4
+		if _, err := r.ReadAt(header, base); err != nil {
+			return nil, err
+		}
+
+		count := int64(binary.LittleEndian.Uint32(header))
+		if count > length/8 {
+			return nil, errors.New("invalid compressed block table")
+		}
+
+		table := make([]byte, 8*count)
+		if _, err := r.ReadAt(table, base+4); err != nil {
+			return nil, err
+		}
+
+		blocks := make([]decmpfsBlock, count)
+		for i := range blocks {
+			blocks[i] = decmpfsBlock{
+				offset: base + int64(binary.LittleEndian.Uint32(table[8*i:])),
+				length: int64(binary.LittleEndian.Uint32(table[8*i+4:])),
+			}
+		}
+
+		return blocks, nil
+	}
+
+	// LZVN and LZFSE forks start with the offsets of the blocks, followed by the offset of the end.
+	header := make([]byte, 4)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	tableSize := int64(binary.LittleEndian.Uint32(header))
+	if tableSize < 4 || tableSize%4 != 0 || tableSize > length {
+		return nil, errors.New("invalid compressed block table")
+	}
+
+	table := make([]byte, tableSize)
+	if _, err := r.ReadAt(table, 0); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]decmpfsBlock, tableSize/4-1)
+	for i := range blocks {
+		start := int64(binary.LittleEndian.Uint32(table[4*i:]))
+		end := int64(binary.LittleEndian.Uint32(table[4*i+4:]))
+		if end < start {
+			return nil, errors.New("invalid compressed block table")
+		}
+
+		blocks[i] = decmpfsBlock{offset: start, length: end - start}
+	}
+
+	return blocks, nil
+}
+
+func (d *decmpfsReader) Read(p []byte) (int, error) {
+	for len(d.current) == 0 {
+		index := d.read / decmpfsBlockSize
+		if d.read >= d.size || index >= int64(len(d.blocks)) {
+			return 0, io.EOF
+		}
+
+		block := d.blocks[index]
+		data := make([]byte, block.length)
+		if _, err := d.r.ReadAt(data, block.offset); err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		size := int(min(decmpfsBlockSize, d.size-d.read))
+		current, err := d.decode(data, size)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress block %d: %w", index, err)
+		}
+
+		if len(current) != size {
+			return 0, fmt.Errorf("compressed block %d has %d bytes, expected %d", index, len(current), size)
+		}
+
+		d.current = current
+	}
+
+	n := copy(p, d.current)
+	d.current = d.current[n:]
+	d.read += int64(n)
+	return n, nil
+}
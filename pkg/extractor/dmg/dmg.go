@@ -0,0 +1,567 @@
+// Package dmg reads Apple disk images (UDIF) and the HFS+ or APFS file system inside them, so macOS builds
+// can be unpacked on any platform.
+package dmg
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+const (
+	sectorSize  = 512
+	trailerSize = 512
+
+	// cachedChunks is the number of decompressed chunks kept in memory. Chunks are at most a few megabytes.
+	cachedChunks = 8
+
+	// maxChunkSize bounds the compressed and decompressed size of a compressed chunk, which is read into memory
+	// whole. Images made by hdiutil use chunks of up to one megabyte.
+	maxChunkSize = 16 << 20
+
+	// maxSectors is the highest sector count whose size in bytes fits an int64.
+	maxSectors = math.MaxInt64 / sectorSize
+)
+
+// Chunk types of a block table.
+const (
+	chunkZero    uint32 = 0x00000000
+	chunkRaw     uint32 = 0x00000001
+	chunkIgnore  uint32 = 0x00000002
+	chunkADC     uint32 = 0x80000004
+	chunkZlib    uint32 = 0x80000005
+	chunkBzip2   uint32 = 0x80000006
+	chunkLZFSE   uint32 = 0x80000007
+	chunkLZMA    uint32 = 0x80000008
+	chunkComment uint32 = 0x7ffffffe
+	chunkEnd     uint32 = 0xffffffff
+)
+
+// xzMagic starts LZMA chunks written as xz streams rather than bare LZMA.
+var xzMagic = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+
+// ErrUnsupported is returned for images that are valid, but use features that are not implemented, such as
+// encryption.
+var ErrUnsupported = errors.New("unsupported disk image")
+
+type (
+	// Partition is a region of the disk described by the image.
+	Partition struct {
+		Name   string
+		Offset int64
+		Length int64
+	}
+
+	// Image is an opened UDIF disk image. It reads as the uncompressed disk it contains.
+	Image struct {
+		file       *os.File
+		chunks     []chunk
+		partitions []Partition
+		size       int64
+
+		mu    sync.Mutex
+		cache []*cachedChunk
+	}
+
+	// chunk is a run of sectors stored in the data fork of the image.
+	chunk struct {
+		kind             uint32
+		start            int64
+		length           int64
+		offset           int64
+		compressedLength int64
+	}
+
+	cachedChunk struct {
+		start int64
+		data  []byte
+	}
+
+	// trailer is the "koly" block at the end of every UDIF image.
+	trailer struct {
+		Signature             [4]byte
+		Version               uint32
+		HeaderSize            uint32
+		Flags                 uint32
+		RunningDataForkOffset uint64
+		DataForkOffset        uint64
+		DataForkLength        uint64
+		RsrcForkOffset        uint64
+		RsrcForkLength        uint64
+		SegmentNumber         uint32
+		SegmentCount          uint32
+		SegmentID             [16]byte
+		DataChecksumType      uint32
+		DataChecksumSize      uint32
+		DataChecksum          [32]uint32
+		XMLOffset             uint64
+		XMLLength             uint64
+		Reserved1             [120]byte
+		ChecksumType          uint32
+		ChecksumSize          uint32
+		Checksum              [32]uint32
+		ImageVariant          uint32
+		SectorCount           uint64
+		Reserved2             [3]uint32
+	}
+
+	// blockTable is the header of a "mish" block table, which maps a partition to chunks of the data fork.
+	blockTable struct {
+		Signature        [4]byte
+		Version          uint32
+		SectorNumber     uint64
+		SectorCount      uint64
+		DataOffset       uint64
+		BuffersNeeded    uint32
+		BlockDescriptors uint32
+		Reserved         [6]uint32
+		ChecksumType     uint32
+		ChecksumSize     uint32
+		Checksum         [32]uint32
+		ChunkCount       uint32
+	}
+
+	blockChunk struct {
+		Type             uint32
+		Comment          uint32
+		SectorNumber     uint64
+		SectorCount      uint64
+		CompressedOffset uint64
+		CompressedLength uint64
+	}
+)
+
+// Open opens the UDIF disk image at the path.
+func Open(path string) (*Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := newImage(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read disk image %s: %w", path, err)
+	}
+
+	return image, nil
+}
+
+func newImage(file *os.File) (*Image, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < trailerSize {
+		return nil, errors.New("file is too small")
+	}
+
+	var koly trailer
+	if err := binary.Read(io.NewSectionReader(file, info.Size()-trailerSize, trailerSize), binary.BigEndian, &koly); err != nil {
+		return nil, err
+	}
+
+	if string(koly.Signature[:]) != "koly" {
+		return nil, errors.New("missing UDIF trailer")
+	}
+
+	if koly.XMLLength == 0 {
+		return nil, fmt.Errorf("%w: no property list", ErrUnsupported)
+	}
+
+	if koly.XMLOffset+koly.XMLLength > uint64(info.Size()) {
+		return nil, errors.New("property list is out of bounds")
+	}
+
+	tables, err := readBlockTables(io.NewSectionReader(file, int64(koly.XMLOffset), int64(koly.XMLLength)))
+	if err != nil {
+		return nil, err
+	}
+
+	image := &Image{file: file}
+	for _, table := range tables {
+		partition, chunks, err := parseBlockTable(table.data, int64(koly.DataForkOffset))
+		if err != nil {
+			return nil, fmt.Errorf("invalid block table %q: %w", table.name, err)
+		}
+
+		partition.Name = table.name
+		image.partitions = append(image.partitions, partition)
+		image.chunks = append(image.chunks, chunks...)
+		image.size = max(image.size, partition.Offset+partition.Length)
+	}
+
+	sort.Slice(image.chunks, func(i, j int) bool {
+		return image.chunks[i].start < image.chunks[j].start
+	})
+
+	for i := 1; i < len(image.chunks); i++ {
+		previous := image.chunks[i-1]
+		if image.chunks[i].start < previous.start+previous.length {
+			return nil, errors.New("overlapping chunks")
+		}
+	}
+
+	for _, c := range image.chunks {
+		if c.offset < 0 || c.offset+c.compressedLength > info.Size() {
+			return nil, errors.New("chunk is out of bounds")
+		}
+	}
+
+	return image, nil
+}
+
+func parseBlockTable(data []byte, dataForkOffset int64) (Partition, []chunk, error) {
+	if dataForkOffset < 0 {
+		return Partition{}, nil, errors.New("data fork is outside of the image")
+	}
+
+	reader := bytes.NewReader(data)
+
+	var table blockTable
+	if err := binary.Read(reader, binary.BigEndian, &table); err != nil {
+		return Partition{}, nil, err
+	}
+
+	if string(table.Signature[:]) != "mish" {
+		return Partition{}, nil, errors.New("missing block table signature")
+	}
+
+	if table.SectorNumber > maxSectors || table.SectorCount > maxSectors-table.SectorNumber {
+		return Partition{}, nil, errors.New("partition is outside of the disk")
+	}
+
+	partition := Partition{
+		Offset: int64(table.SectorNumber) * sectorSize,
+		Length: int64(table.SectorCount) * sectorSize,
+	}
+
+	var chunks []chunk
+	for i := uint32(0); i < table.ChunkCount; i++ {
+		var entry blockChunk
+		if err := binary.Read(reader, binary.BigEndian, &entry); err != nil {
+			return Partition{}, nil, err
+		}
+
+		switch entry.Type {
+		case chunkComment, chunkEnd:
+			continue
+		case chunkZero, chunkIgnore, chunkRaw, chunkADC, chunkZlib, chunkBzip2, chunkLZFSE, chunkLZMA:
+		default:
+			return Partition{}, nil, fmt.Errorf("%w: chunk type %#x", ErrUnsupported, entry.Type)
+		}
+
+		if entry.SectorCount == 0 {
+			continue
+		}
+
+		if entry.SectorCount > table.SectorCount || entry.SectorNumber > table.SectorCount-entry.SectorCount {
+			return Partition{}, nil, errors.New("chunk is outside of its partition")
+		}
+
+		limit := uint64(math.MaxInt64 - dataForkOffset)
+		if entry.CompressedOffset > limit || entry.CompressedLength > limit-entry.CompressedOffset {
+			return Partition{}, nil, errors.New("chunk data is outside of the image")
+		}
+
+		// Zero and raw chunks are never read into memory whole, so only compressed chunks are bounded.
+		if entry.Type != chunkZero && entry.Type != chunkIgnore && entry.Type != chunkRaw {
+			if entry.SectorCount*sectorSize > maxChunkSize || entry.CompressedLength > maxChunkSize {
+				return Partition{}, nil, fmt.Errorf("chunk of %d sectors exceeds the maximum chunk size", entry.SectorCount)
+			}
+		}
+
+		chunks = append(chunks, chunk{
+			kind:             entry.Type,
+			start:            int64(table.SectorNumber+entry.SectorNumber) * sectorSize,
+			length:           int64(entry.SectorCount) * sectorSize,
+			offset:           dataForkOffset + int64(entry.CompressedOffset),
+			compressedLength: int64(entry.CompressedLength),
+		})
+	}
+
+	return partition, chunks, nil
+}
+
+// Close closes the image file.
+func (i *Image) Close() error {
+	return i.file.Close()
+}
+
+// Size returns the size of the uncompressed disk.
+func (i *Image) Size() int64 {
+	return i.size
+}
+
+// Partitions returns the regions of the disk listed by the image, in the order they are listed.
+func (i *Image) Partitions() []Partition {
+	return i.partitions
+}
+
+// ReadAt reads from the uncompressed disk. Sectors not covered by the image read as zeros.
+func (i *Image) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if off >= i.size {
+		return 0, io.EOF
+	}
+
+	var err error
+	if remaining := i.size - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+		err = io.EOF
+	}
+
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		index := sort.Search(len(i.chunks), func(j int) bool {
+			return i.chunks[j].start+i.chunks[j].length > pos
+		})
+
+		if index == len(i.chunks) || i.chunks[index].start > pos {
+			// A gap between chunks, up to the next chunk or the end of the read.
+			end := int64(len(p))
+			if index < len(i.chunks) {
+				end = min(end, i.chunks[index].start-off)
+			}
+
+			clear(p[n:end])
+			n = int(end)
+			continue
+		}
+
+		c := i.chunks[index]
+		count := int(min(int64(len(p)-n), c.start+c.length-pos))
+		if err := i.readChunk(c, p[n:n+count], pos-c.start); err != nil {
+			return n, err
+		}
+
+		n += count
+	}
+
+	return n, err
+}
+
+func (i *Image) readChunk(c chunk, p []byte, offset int64) error {
+	switch c.kind {
+	case chunkZero, chunkIgnore:
+		clear(p)
+		return nil
+	case chunkRaw:
+		// Raw chunks may be shorter than the sectors they cover, the rest of which are zeros.
+		available := max(0, min(int64(len(p)), c.compressedLength-offset))
+		if available > 0 {
+			if _, err := i.file.ReadAt(p[:available], c.offset+offset); err != nil {
+				return err
+			}
+		}
+
+		clear(p[available:])
+		return nil
+	}
+
+	data, err := i.chunkData(c)
+	if err != nil {
+		return err
+	}
+
+	copy(p, data[offset:])
+	return nil
+}
+
+// chunkData returns the decompressed data of the chunk, from the cache if it was read recently.
+func (i *Image) chunkData(c chunk) ([]byte, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for index, cached := range i.cache {
+		if cached.start == c.start {
+			copy(i.cache[1:index+1], i.cache[:index])
+			i.cache[0] = cached
+			return cached.data, nil
+		}
+	}
+
+	compressed := make([]byte, c.compressedLength)
+	if _, err := i.file.ReadAt(compressed, c.offset); err != nil {
+		return nil, err
+	}
+
+	data, err := decompressChunk(c.kind, compressed, int(c.length))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress chunk at %d: %w", c.start, err)
+	}
+
+	if len(i.cache) < cachedChunks {
+		i.cache = append(i.cache, nil)
+	}
+
+	copy(i.cache[1:], i.cache)
+	i.cache[0] = &cachedChunk{start: c.start, data: data}
+
+	return data, nil
+}
+
+// decompressChunk returns exactly size bytes of decompressed data. Short data is padded with zeros.
+func decompressChunk(kind uint32, compressed []byte, size int) ([]byte, error) {
+	var data []byte
+	var err error
+	switch kind {
+	case chunkZlib:
+		data, err = readAllLimit(func() (io.Reader, error) { return zlib.NewReader(bytes.NewReader(compressed)) }, size)
+	case chunkBzip2:
+		data, err = readAllLimit(func() (io.Reader, error) { return bzip2.NewReader(bytes.NewReader(compressed)), nil }, size)
+	case chunkLZMA:
+		data, err = readAllLimit(func() (io.Reader, error) {
+			if bytes.HasPrefix(compressed, xzMagic) {
+				return xz.NewReader(bytes.NewReader(compressed))
+			}
+
+			return lzma.NewReader(bytes.NewReader(compressed))
+		}, size)
+	case chunkADC:
+		data, err = decodeADC(compressed, size)
+	case chunkLZFSE:
+		data, err = decodeLZFSE(compressed, size)
+	default:
+		return nil, fmt.Errorf("%w: chunk type %#x", ErrUnsupported, kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < size {
+		data = append(data, make([]byte, size-len(data))...)
+	}
+
+	return data, nil
+}
+
+func readAllLimit(open func() (io.Reader, error), size int) ([]byte, error) {
+	reader, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// FileSystem returns the file system of the first HFS+ or APFS volume in the image.
+func (i *Image) FileSystem() (*FileSystem, error) {
+	for _, partition := range i.volumeCandidates() {
+		reader := io.NewSectionReader(i, partition.Offset, partition.Length)
+
+		var root *node
+		var err error
+		switch detectFileSystem(reader) {
+		case "hfs":
+			root, err = openHFS(reader)
+		case "apfs":
+			root, err = openAPFS(reader)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read partition %q: %w", partition.Name, err)
+		}
+
+		return &FileSystem{root: root}, nil
+	}
+
+	return nil, fmt.Errorf("%w: no HFS+ or APFS volume found", ErrUnsupported)
+}
+
+// volumeCandidates returns the partitions listed by the image, followed by any partitions of a GUID
+// partition table that the image lists as a single region.
+func (i *Image) volumeCandidates() []Partition {
+	candidates := append([]Partition{}, i.partitions...)
+	seen := make(map[int64]bool)
+	for _, partition := range i.partitions {
+		seen[partition.Offset] = true
+	}
+
+	for _, partition := range i.partitions {
+		for _, entry := range readGPT(io.NewSectionReader(i, partition.Offset, partition.Length)) {
+			entry.Offset += partition.Offset
+			if !seen[entry.Offset] && entry.Offset+entry.Length <= partition.Offset+partition.Length {
+				seen[entry.Offset] = true
+				candidates = append(candidates, entry)
+			}
+		}
+	}
+
+	return candidates
+}
+
+// readGPT returns the partitions of a GUID partition table at the start of the reader, if there is one.
+func readGPT(r io.ReaderAt) []Partition {
+	header := make([]byte, 92)
+	if _, err := r.ReadAt(header, sectorSize); err != nil || string(header[:8]) != "EFI PART" {
+		return nil
+	}
+
+	entriesLBA := binary.LittleEndian.Uint64(header[72:])
+	count := binary.LittleEndian.Uint32(header[80:])
+	entrySize := binary.LittleEndian.Uint32(header[84:])
+	if count > 256 || entrySize < 128 || entrySize > 4096 {
+		return nil
+	}
+
+	var partitions []Partition
+	entry := make([]byte, entrySize)
+	for index := uint32(0); index < count; index++ {
+		if _, err := r.ReadAt(entry, int64(entriesLBA)*sectorSize+int64(index)*int64(entrySize)); err != nil {
+			break
+		}
+
+		first := binary.LittleEndian.Uint64(entry[32:])
+		last := binary.LittleEndian.Uint64(entry[40:])
+		if bytes.Equal(entry[:16], make([]byte, 16)) || last < first {
+			continue
+		}
+
+		partitions = append(partitions, Partition{
+			Name:   strings.TrimRight(decodeUTF16(entry[56:128], binary.LittleEndian), "\x00"),
+			Offset: int64(first) * sectorSize,
+			Length: int64(last-first+1) * sectorSize,
+		})
+	}
+
+	return partitions
+}
+
+func detectFileSystem(r io.ReaderAt) string {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 32); err == nil && string(magic) == apfsContainerMagic {
+		return "apfs"
+	}
+
+	if _, err := r.ReadAt(magic[:2], 1024); err == nil && (string(magic[:2]) == hfsSignature || string(magic[:2]) == hfsxSignature) {
+		return "hfs"
+	}
+
+	return ""
+}
@@ -0,0 +1,159 @@
+package dmg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSystem(t *testing.T) {
+	entries := fixtureEntries()
+	content := make(map[string]string)
+	for _, entry := range entries {
+		content[entry.path] = entry.content
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.name, func(t *testing.T) {
+			image, err := Open(filepath.Join("testdata", f.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer image.Close()
+
+			fsys, err := image.FileSystem()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			found := make(map[string]bool)
+			err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil || path == "." {
+					return err
+				}
+
+				found[path] = true
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(found) != len(entries) {
+				t.Errorf("FileSystem() has %d entries, want %d: %v", len(found), len(entries), found)
+			}
+
+			for _, entry := range entries {
+				info, err := fsys.Stat(entry.path)
+				if err != nil {
+					t.Errorf("Stat(%q) error = %v", entry.path, err)
+					continue
+				}
+
+				switch {
+				case entry.dir:
+					if !info.IsDir() {
+						t.Errorf("Stat(%q) mode = %v, want a directory", entry.path, info.Mode())
+					}
+				case entry.link != "":
+					target, err := fsys.ReadLink(entry.path)
+					if err != nil || target != entry.link {
+						t.Errorf("ReadLink(%q) = %q, %v, want %q", entry.path, target, err, entry.link)
+					}
+				default:
+					want := content[entry.path]
+					if entry.hardLink != "" {
+						want = content[entry.hardLink]
+					}
+
+					file, err := fsys.Open(entry.path)
+					if err != nil {
+						t.Errorf("Open(%q) error = %v", entry.path, err)
+						continue
+					}
+
+					data, err := io.ReadAll(file)
+					file.Close()
+					if err != nil || string(data) != want {
+						t.Errorf("ReadAll(%q) = %d bytes, %v, want %d bytes", entry.path, len(data), err, len(want))
+					}
+
+					if info.Size() != int64(len(want)) {
+						t.Errorf("Stat(%q) size = %d, want %d", entry.path, info.Size(), len(want))
+					}
+
+					if entry.mode != 0 && info.Mode().Perm() != fs.FileMode(entry.mode) {
+						t.Errorf("Stat(%q) mode = %v, want %v", entry.path, info.Mode().Perm(), fs.FileMode(entry.mode))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	if _, err := Open(filepath.Join("testdata", "missing.dmg")); err == nil {
+		t.Error("Open() of a missing image succeeded")
+	}
+
+	if _, err := Open("dmg_test.go"); err == nil {
+		t.Error("Open() of a file without a trailer succeeded")
+	}
+}
+
+func TestParseBlockTableInvalid(t *testing.T) {
+	const sectors = 1 << 20
+
+	table := func(sectorNumber uint64, sectorCount uint64, chunks ...blockChunk) []byte {
+		header := blockTable{
+			Version:      1,
+			SectorNumber: sectorNumber,
+			SectorCount:  sectorCount,
+			ChunkCount:   uint32(len(chunks)),
+		}
+		copy(header.Signature[:], "mish")
+
+		var data bytes.Buffer
+		if err := binary.Write(&data, binary.BigEndian, &header); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := binary.Write(&data, binary.BigEndian, chunks); err != nil {
+			t.Fatal(err)
+		}
+
+		return data.Bytes()
+	}
+
+	tests := []struct {
+		name           string
+		data           []byte
+		dataForkOffset int64
+	}{
+		{"negative data fork", table(0, sectors), -1},
+		{"partition too large", table(0, math.MaxUint64/sectorSize), 0},
+		{"partition overflows", table(math.MaxInt64/sectorSize, 2), 0},
+		{"chunk overflows", table(0, sectors, blockChunk{Type: chunkRaw, SectorNumber: math.MaxUint64, SectorCount: 2}), 0},
+		{"chunk outside partition", table(0, sectors, blockChunk{Type: chunkZero, SectorNumber: sectors - 1, SectorCount: 2}), 0},
+		{"chunk data overflows", table(0, sectors, blockChunk{Type: chunkZlib, SectorCount: 1, CompressedOffset: math.MaxInt64, CompressedLength: 1}), 1},
+		{"chunk data negative", table(0, sectors, blockChunk{Type: chunkZlib, SectorCount: 1, CompressedOffset: 1 << 63}), 0},
+		{"chunk too large", table(0, sectors, blockChunk{Type: chunkZlib, SectorCount: maxChunkSize/sectorSize + 1, CompressedLength: 1}), 0},
+		{"compressed chunk too large", table(0, sectors, blockChunk{Type: chunkLZFSE, SectorCount: 1, CompressedLength: maxChunkSize + 1}), 0},
+	}
+
+	for _, test := range tests {
+		if _, _, err := parseBlockTable(test.data, test.dataForkOffset); err == nil {
+			t.Errorf("parseBlockTable() of %s succeeded", test.name)
+		}
+	}
+
+	// Chunks of zeros are never held in memory and may cover the whole partition.
+	data := table(0, sectors, blockChunk{Type: chunkIgnore, SectorCount: sectors})
+	if _, chunks, err := parseBlockTable(data, 0); err != nil || len(chunks) != 1 {
+		t.Errorf("parseBlockTable() = %d chunks, %v, want a single chunk", len(chunks), err)
+	}
+}
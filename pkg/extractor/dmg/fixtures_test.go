@@ -0,0 +1,1059 @@
+package dmg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/dsnet/compress/bzip2"
+)
+
+// The fixture images in testdata are written by this file, so they can be read without macOS. Regenerate them
+// with: go test ./pkg/extractor/dmg -run TestFixtures -update
+var update = flag.Bool("update", false, "regenerate the fixture images in testdata")
+
+const fixtureBlockSize = 4096
+
+type (
+	// fixtureEntry is a file, directory or symbolic link of the fixture volumes.
+	fixtureEntry struct {
+		path     string
+		dir      bool
+		link     string
+		content  string
+		mode     uint16
+		hardLink string
+		compress uint32
+	}
+
+	fixture struct {
+		name      string
+		apfs      bool
+		chunkType uint32
+	}
+)
+
+var fixtures = []fixture{
+	{name: "hfs-zlib.dmg", chunkType: chunkZlib},
+	{name: "hfs-bzip2.dmg", chunkType: chunkBzip2},
+	{name: "hfs-lzfse.dmg", chunkType: chunkLZFSE},
+	{name: "apfs-zlib.dmg", apfs: true, chunkType: chunkZlib},
+}
+
+func fixtureEntries() []fixtureEntry {
+	// Content that doesn't compress, large enough to span several allocation blocks.
+	executable := make([]byte, 10*fixtureBlockSize-123)
+	seed := uint32(1)
+	for i := range executable {
+		seed = seed*1664525 + 1013904223
+		executable[i] = byte(seed >> 24)
+	}
+
+	return []fixtureEntry{
+		{path: "Applications", link: "/Applications"},
+		{path: "README.txt", content: "Drag Blender to Applications.\n"},
+		{path: "Blender.app", dir: true},
+		{path: "Blender.app/Contents", dir: true},
+		{path: "Blender.app/Contents/Info.plist", content: "<plist version=\"1.0\"><dict/></plist>\n"},
+		{path: "Blender.app/Contents/MacOS", dir: true},
+		{path: "Blender.app/Contents/MacOS/Blender", content: string(executable), mode: 0755},
+		{path: "Blender.app/Contents/Resources", dir: true},
+		{path: "Blender.app/Contents/Resources/4.2", dir: true},
+		{path: "Blender.app/Contents/Resources/4.2/startup.py", content: "import bpy\n"},
+		{path: "Blender.app/Contents/Resources/startup.py", hardLink: "Blender.app/Contents/Resources/4.2/startup.py"},
+		{path: "Blender.app/Contents/Resources/current", link: "4.2"},
+		{path: "Blender.app/Contents/Resources/empty"},
+		{path: "Blender.app/Contents/Resources/zlib.txt", content: strings.Repeat("zlib compressed ", 300), compress: decmpfsZlibAttribute},
+		{path: "Blender.app/Contents/Resources/lzvn.txt", content: strings.Repeat("# lzvn"+strings.Repeat("-", 58)+"\n", 50), compress: decmpfsLZVNAttribute},
+		{path: "Blender.app/Contents/Resources/fork.bin", content: strings.Repeat("resource fork blocks ", 5000), compress: decmpfsZlibResource},
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	if !*update {
+		t.Skip("run with -update to regenerate the fixture images")
+	}
+
+	for _, f := range fixtures {
+		volume, name := buildHFS(t, fixtureEntries()), "disk image (Apple_HFS : 1)"
+		if f.apfs {
+			volume, name = buildAPFS(t, fixtureEntries()), "disk image (Apple_APFS : 1)"
+		}
+
+		if err := os.WriteFile(filepath.Join("testdata", f.name), buildUDIF(t, volume, name, f.chunkType), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// buildUDIF wraps the volume in a disk image, behind a protective MBR like the images hdiutil creates.
+func buildUDIF(t *testing.T, volume []byte, name string, chunkType uint32) []byte {
+	t.Helper()
+
+	mbr := make([]byte, sectorSize)
+	mbr[510], mbr[511] = 0x55, 0xaa
+
+	var dataFork bytes.Buffer
+	tables := []namedBlockTable{
+		{name: "Protective Master Boot Record (MBR : 0)", data: buildBlockTable(t, &dataFork, mbr, 0, chunkRaw)},
+		{name: name, data: buildBlockTable(t, &dataFork, volume, 1, chunkType)},
+	}
+
+	var plist strings.Builder
+	plist.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>resource-fork</key>
+	<dict>
+		<key>blkx</key>
+		<array>
+`)
+	for i, table := range tables {
+		encoded := base64.StdEncoding.EncodeToString(table.data)
+		var lines []string
+		for len(encoded) > 52 {
+			lines = append(lines, encoded[:52])
+			encoded = encoded[52:]
+		}
+
+		lines = append(lines, encoded)
+		fmt.Fprintf(&plist, `			<dict>
+				<key>Attributes</key>
+				<string>0x0050</string>
+				<key>CFName</key>
+				<string>%[1]s</string>
+				<key>Data</key>
+				<data>
+				%[2]s
+				</data>
+				<key>ID</key>
+				<string>%[3]d</string>
+				<key>Name</key>
+				<string>%[1]s</string>
+			</dict>
+`, table.name, strings.Join(lines, "\n\t\t\t\t"), i-1)
+	}
+	plist.WriteString("\t\t</array>\n\t</dict>\n</dict>\n</plist>\n")
+
+	koly := trailer{
+		Version:          4,
+		HeaderSize:       trailerSize,
+		Flags:            1,
+		DataForkLength:   uint64(dataFork.Len()),
+		SegmentNumber:    1,
+		SegmentCount:     1,
+		DataChecksumType: 2,
+		DataChecksumSize: 32,
+		XMLOffset:        uint64(dataFork.Len()),
+		XMLLength:        uint64(plist.Len()),
+		ImageVariant:     1,
+		SectorCount:      uint64(1 + len(volume)/sectorSize),
+	}
+	copy(koly.Signature[:], "koly")
+	koly.DataChecksum[0] = crc32.ChecksumIEEE(dataFork.Bytes())
+
+	image := bytes.NewBuffer(dataFork.Bytes())
+	image.WriteString(plist.String())
+	if err := binary.Write(image, binary.BigEndian, &koly); err != nil {
+		t.Fatal(err)
+	}
+
+	return image.Bytes()
+}
+
+// buildBlockTable appends the data to the data fork in chunks of 64 sectors, and returns their block table.
+func buildBlockTable(t *testing.T, dataFork *bytes.Buffer, data []byte, firstSector uint64, chunkType uint32) []byte {
+	t.Helper()
+
+	const chunkSectors = 64
+
+	sectors := uint64(len(data) / sectorSize)
+	header := blockTable{
+		Version:          1,
+		SectorNumber:     firstSector,
+		SectorCount:      sectors,
+		BuffersNeeded:    chunkSectors * 4,
+		BlockDescriptors: 1,
+		ChecksumType:     2,
+		ChecksumSize:     32,
+	}
+	copy(header.Signature[:], "mish")
+	header.Checksum[0] = crc32.ChecksumIEEE(data)
+
+	var chunks []blockChunk
+	for sector := uint64(0); sector < sectors; sector += chunkSectors {
+		count := min(chunkSectors, sectors-sector)
+		raw := data[sector*sectorSize : (sector+count)*sectorSize]
+		entry := blockChunk{
+			Type:             chunkType,
+			SectorNumber:     sector,
+			SectorCount:      count,
+			CompressedOffset: uint64(dataFork.Len()),
+		}
+
+		if bytes.Count(raw, []byte{0}) == len(raw) {
+			entry.Type = chunkZero
+		} else {
+			dataFork.Write(compressChunk(t, chunkType, raw))
+		}
+
+		entry.CompressedLength = uint64(dataFork.Len()) - entry.CompressedOffset
+		chunks = append(chunks, entry)
+	}
+
+	chunks = append(chunks, blockChunk{Type: chunkEnd, SectorNumber: sectors, CompressedOffset: uint64(dataFork.Len())})
+	header.ChunkCount = uint32(len(chunks))
+
+	var table bytes.Buffer
+	if err := binary.Write(&table, binary.BigEndian, &header); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := binary.Write(&table, binary.BigEndian, chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	return table.Bytes()
+}
+
+func compressChunk(t *testing.T, chunkType uint32, data []byte) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	switch chunkType {
+	case chunkRaw:
+		return data
+	case chunkZlib:
+		writer := zlib.NewWriter(&buffer)
+		writer.Write(data)
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case chunkBzip2:
+		writer, err := bzip2.NewWriter(&buffer, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer.Write(data)
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case chunkLZFSE:
+		// An LZVN block for the first half and an uncompressed block for the rest.
+		half := len(data) / 2
+		encoded := encodeLZVN(data[:half])
+		buffer.WriteString(lzfseCompressedVN)
+		binary.Write(&buffer, binary.LittleEndian, []uint32{uint32(half), uint32(len(encoded))})
+		buffer.Write(encoded)
+		buffer.WriteString(lzfseUncompressed)
+		binary.Write(&buffer, binary.LittleEndian, uint32(len(data)-half))
+		buffer.Write(data[half:])
+		buffer.WriteString(lzfseEndOfStream)
+	default:
+		t.Fatalf("unsupported chunk type %#x", chunkType)
+	}
+
+	return buffer.Bytes()
+}
+
+// encodeLZVN compresses runs of a repeated byte as matches one byte back and stores everything else as
+// literals.
+func encodeLZVN(data []byte) []byte {
+	var out []byte
+	literals := func(literal []byte) {
+		for len(literal) > 0 {
+			n := min(len(literal), 271)
+			if n < 16 {
+				out = append(out, 0xe0|byte(n))
+			} else {
+				out = append(out, 0xe0, byte(n-16))
+			}
+
+			out = append(out, literal[:n]...)
+			literal = literal[n:]
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(data); {
+		run := 1
+		for i+run < len(data) && data[i+run] == data[i] {
+			run++
+		}
+
+		if run < 8 {
+			i += run
+			continue
+		}
+
+		literals(data[start : i+1])
+
+		// A short match sets the distance, longer ones reuse it.
+		first := min(run-1, 10)
+		out = append(out, byte(first-3)<<3, 1)
+		for remaining := run - 1 - first; remaining > 0; {
+			n := min(remaining, 271)
+			if n < 16 {
+				out = append(out, 0xf0|byte(n))
+			} else {
+				out = append(out, 0xf0, byte(n-16))
+			}
+
+			remaining -= n
+		}
+
+		i += run
+		start = i
+	}
+
+	literals(data[start:])
+	return append(out, lzvnEndOfStream, 0, 0, 0, 0, 0, 0, 0)
+}
+
+// decmpfsFixture returns the compression header and resource fork of a compressed fixture entry.
+func decmpfsFixture(t *testing.T, entry fixtureEntry) ([]byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, decmpfsHeaderSize)
+	copy(header, decmpfsMagic)
+	binary.LittleEndian.PutUint32(header[4:], entry.compress)
+	binary.LittleEndian.PutUint64(header[8:], uint64(len(entry.content)))
+
+	compress := func(data []byte) []byte {
+		var buffer bytes.Buffer
+		writer := zlib.NewWriter(&buffer)
+		writer.Write(data)
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return buffer.Bytes()
+	}
+
+	switch entry.compress {
+	case decmpfsZlibAttribute:
+		return append(header, compress([]byte(entry.content))...), nil
+	case decmpfsLZVNAttribute:
+		return append(header, encodeLZVN([]byte(entry.content))...), nil
+	case decmpfsZlibResource:
+		var blocks [][]byte
+		for content := []byte(entry.content); len(content) > 0; {
+			n := min(len(content), decmpfsBlockSize)
+			blocks = append(blocks, compress(content[:n]))
+			content = content[n:]
+		}
+
+		// A resource fork header, the length of the resource and then the block table and blocks.
+		fork := make([]byte, 0x100+4+4+8*len(blocks))
+		binary.BigEndian.PutUint32(fork, 0x100)
+		binary.LittleEndian.PutUint32(fork[0x104:], uint32(len(blocks)))
+		offset := 4 + 8*len(blocks)
+		for i, block := range blocks {
+			binary.LittleEndian.PutUint32(fork[0x108+8*i:], uint32(offset))
+			binary.LittleEndian.PutUint32(fork[0x10c+8*i:], uint32(len(block)))
+			offset += len(block)
+			fork = append(fork, block...)
+		}
+		binary.BigEndian.PutUint32(fork[0x100:], uint32(len(fork)-0x104))
+
+		return header, fork
+	default:
+		t.Fatalf("unsupported compression type %d", entry.compress)
+		return nil, nil
+	}
+}
+
+type hfsImage struct {
+	t          *testing.T
+	data       []byte
+	next       uint32
+	catalog    [][]byte
+	extents    [][]byte
+	attributes [][]byte
+}
+
+// buildHFS writes an HFS+ volume with the entries. The executable is fragmented, so its extents continue in
+// the overflow file, and every record of the catalog is in a leaf node of its own chain.
+func buildHFS(t *testing.T, entries []fixtureEntry) []byte {
+	t.Helper()
+
+	const totalBlocks = 128
+	h := &hfsImage{t: t, data: make([]byte, totalBlocks*fixtureBlockSize), next: 16}
+	ids := map[string]uint32{"": hfsRootFolderID}
+	parent := func(p string) uint32 {
+		dir := filepath.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+
+		return ids[dir]
+	}
+
+	// Special files come first: extents, catalog and attributes, eight blocks each.
+	allocated := uint32(1 + 3*8)
+
+	privateID := uint32(15)
+	h.folder(hfsRootFolderID, hfsPrivateFolder, privateID, 0)
+	h.folder(1, "Fixture", hfsRootFolderID, 0755)
+
+	links := map[string]uint32{}
+	for _, entry := range entries {
+		id := h.next
+		h.next++
+		ids[entry.path] = id
+		name := filepath.Base(entry.path)
+
+		switch {
+		case entry.dir:
+			h.folder(parent(entry.path), name, id, 0755)
+		case entry.link != "":
+			data := h.fork(id, hfsDataFork, []byte(entry.link), &allocated, false)
+			h.file(parent(entry.path), name, id, 0xa1ed, 0, 0, "slnk", "rhap", data, hfsFork{})
+		case entry.hardLink != "":
+			// Both names become links to a file in the private folder.
+			target := links[entry.hardLink]
+			h.file(parent(entry.path), name, id, 0x81a4, 0, target, "hlnk", "hfs+", hfsFork{}, hfsFork{})
+		default:
+			mode := uint16(0x81a4)
+			if entry.mode != 0 {
+				mode = 0x8000 | entry.mode
+			}
+
+			var data, resource hfsFork
+			flags := uint8(0)
+			if entry.compress != 0 {
+				header, fork := decmpfsFixture(t, entry)
+				h.attribute(id, decmpfsAttribute, header)
+				resource = h.fork(id, hfsResourceFork, fork, &allocated, false)
+				flags = 0x20
+			} else {
+				data = h.fork(id, hfsDataFork, []byte(entry.content), &allocated, entry.mode != 0)
+			}
+
+			if entry.path == "Blender.app/Contents/Resources/4.2/startup.py" {
+				inode := h.next
+				h.next++
+				links[entry.path] = inode
+				h.file(privateID, fmt.Sprintf("iNode%d", inode), inode, mode, flags, 2, "", "", data, resource)
+				h.file(parent(entry.path), name, id, mode, 0, inode, "hlnk", "hfs+", hfsFork{}, hfsFork{})
+				continue
+			}
+
+			h.file(parent(entry.path), name, id, mode, flags, 0, "", "", data, resource)
+		}
+	}
+
+	header := hfsVolumeHeader{
+		Version:       4,
+		Attributes:    0x100,
+		BlockSize:     fixtureBlockSize,
+		TotalBlocks:   totalBlocks,
+		FreeBlocks:    totalBlocks - allocated,
+		NextCatalogID: h.next,
+		FileCount:     uint32(len(entries)),
+		ExtentsFile:   h.tree(1, h.extents),
+		CatalogFile:   h.tree(9, h.catalog),
+	}
+	copy(header.Signature[:], hfsSignature)
+	if len(h.attributes) > 0 {
+		header.AttributesFile = h.tree(17, h.attributes)
+	}
+
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.BigEndian, &header); err != nil {
+		t.Fatal(err)
+	}
+
+	copy(h.data[1024:], buffer.Bytes())
+	return h.data
+}
+
+func hfsKey(parent uint32, name string) []byte {
+	units := utf16.Encode([]rune(name))
+	key := make([]byte, 8+2*len(units))
+	binary.BigEndian.PutUint16(key, uint16(6+2*len(units)))
+	binary.BigEndian.PutUint32(key[2:], parent)
+	binary.BigEndian.PutUint16(key[6:], uint16(len(units)))
+	for i, unit := range units {
+		binary.BigEndian.PutUint16(key[8+2*i:], unit)
+	}
+
+	return key
+}
+
+func (h *hfsImage) thread(id uint32, parent uint32, name string, kind uint16) {
+	key := hfsKey(parent, name)
+	data := make([]byte, 8+len(key)-6)
+	binary.BigEndian.PutUint16(data, kind)
+	binary.BigEndian.PutUint32(data[4:], parent)
+	copy(data[8:], key[6:])
+	h.catalog = append(h.catalog, append(hfsKey(id, ""), data...))
+}
+
+func (h *hfsImage) folder(parent uint32, name string, id uint32, mode uint16) {
+	data := make([]byte, 88)
+	binary.BigEndian.PutUint16(data, hfsFolderRecord)
+	binary.BigEndian.PutUint32(data[8:], id)
+	binary.BigEndian.PutUint32(data[16:], hfsEpochOffset+1700000000)
+	if mode != 0 {
+		binary.BigEndian.PutUint16(data[42:], 0x4000|mode)
+	}
+
+	h.catalog = append(h.catalog, append(hfsKey(parent, name), data...))
+	h.thread(id, parent, name, 3)
+}
+
+func (h *hfsImage) file(parent uint32, name string, id uint32, mode uint16, flags uint8, special uint32, fileType string, creator string, data hfsFork, resource hfsFork) {
+	record := make([]byte, 88, 248)
+	binary.BigEndian.PutUint16(record, hfsFileRecord)
+	binary.BigEndian.PutUint32(record[8:], id)
+	binary.BigEndian.PutUint32(record[16:], hfsEpochOffset+1700000000)
+	record[41] = flags
+	binary.BigEndian.PutUint16(record[42:], mode)
+	binary.BigEndian.PutUint32(record[44:], special)
+	copy(record[48:], fileType)
+	copy(record[52:], creator)
+
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.BigEndian, &data)
+	binary.Write(&buffer, binary.BigEndian, &resource)
+	record = append(record, buffer.Bytes()...)
+
+	h.catalog = append(h.catalog, append(hfsKey(parent, name), record...))
+	h.thread(id, parent, name, 4)
+}
+
+func (h *hfsImage) attribute(id uint32, name string, value []byte) {
+	units := utf16.Encode([]rune(name))
+	key := make([]byte, 14+2*len(units))
+	binary.BigEndian.PutUint16(key, uint16(12+2*len(units)))
+	binary.BigEndian.PutUint32(key[4:], id)
+	binary.BigEndian.PutUint16(key[12:], uint16(len(units)))
+	for i, unit := range units {
+		binary.BigEndian.PutUint16(key[14+2*i:], unit)
+	}
+
+	data := make([]byte, 16, 16+len(value)+1)
+	binary.BigEndian.PutUint32(data, hfsInlineAttribute)
+	binary.BigEndian.PutUint32(data[12:], uint32(len(value)))
+	data = append(data, value...)
+	if len(data)%2 != 0 {
+		data = append(data, 0)
+	}
+
+	h.attributes = append(h.attributes, append(key, data...))
+}
+
+// fork writes the content to free blocks. Fragmented content leaves a gap after every block, and the extents
+// past the eighth go to the overflow file.
+func (h *hfsImage) fork(id uint32, forkType uint8, content []byte, allocated *uint32, fragmented bool) hfsFork {
+	blocks := uint32((len(content) + fixtureBlockSize - 1) / fixtureBlockSize)
+	fork := hfsFork{LogicalSize: uint64(len(content)), TotalBlocks: blocks, ClumpSize: fixtureBlockSize}
+
+	var extents []hfsExtent
+	if fragmented {
+		for i := uint32(0); i < blocks; i++ {
+			extents = append(extents, hfsExtent{StartBlock: *allocated, BlockCount: 1})
+			*allocated += 2
+		}
+	} else if blocks > 0 {
+		extents = append(extents, hfsExtent{StartBlock: *allocated, BlockCount: blocks})
+		*allocated += blocks
+	}
+
+	logical := 0
+	for _, x := range extents {
+		copy(h.data[int(x.StartBlock)*fixtureBlockSize:], content[logical:min(len(content), logical+int(x.BlockCount)*fixtureBlockSize)])
+		logical += int(x.BlockCount) * fixtureBlockSize
+	}
+
+	copy(fork.Extents[:], extents)
+	for start := 8; start < len(extents); start += 8 {
+		var record [8]hfsExtent
+		copy(record[:], extents[start:])
+
+		key := make([]byte, 12)
+		binary.BigEndian.PutUint16(key, 10)
+		key[2] = forkType
+		binary.BigEndian.PutUint32(key[4:], id)
+		binary.BigEndian.PutUint32(key[8:], uint32(start))
+
+		var buffer bytes.Buffer
+		binary.Write(&buffer, binary.BigEndian, &record)
+		h.extents = append(h.extents, append(key, buffer.Bytes()...))
+	}
+
+	return fork
+}
+
+// tree writes a B-tree of four nodes from the start block: a header node, an index node and leaf nodes.
+func (h *hfsImage) tree(start uint32, records [][]byte) hfsFork {
+	const nodeSize = 2 * fixtureBlockSize
+	const totalNodes = 4
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return bytes.Compare(records[i][2:], records[j][2:]) < 0
+	})
+
+	// Split the records between two leaf nodes.
+	leaves := [][][]byte{records[:len(records)/2], records[len(records)/2:]}
+	if len(records) < 2 {
+		leaves = [][][]byte{records}
+	}
+
+	nodes := make([]byte, totalNodes*nodeSize)
+	writeNode := func(number int, kind int8, height uint8, next uint32, previous uint32, records [][]byte) {
+		node := nodes[number*nodeSize : (number+1)*nodeSize]
+		binary.BigEndian.PutUint32(node, next)
+		binary.BigEndian.PutUint32(node[4:], previous)
+		node[8] = byte(kind)
+		node[9] = height
+		binary.BigEndian.PutUint16(node[10:], uint16(len(records)))
+
+		offset := 14
+		for i, record := range records {
+			binary.BigEndian.PutUint16(node[nodeSize-2*(i+1):], uint16(offset))
+			copy(node[offset:], record)
+			offset += len(record)
+			if offset > nodeSize-2*(len(records)+1) {
+				h.t.Fatalf("B-tree node %d is full", number)
+			}
+		}
+
+		binary.BigEndian.PutUint16(node[nodeSize-2*(len(records)+1):], uint16(offset))
+	}
+
+	// Leaves are nodes two and three, the index node one points to them.
+	var index [][]byte
+	for i, leaf := range leaves {
+		next, previous := uint32(0), uint32(0)
+		if i+1 < len(leaves) {
+			next = uint32(3 + i)
+		}
+
+		if i > 0 {
+			previous = uint32(1 + i)
+		}
+
+		writeNode(2+i, hfsLeafNode, 1, next, previous, leaf)
+		if len(leaf) > 0 {
+			keyLength := int(binary.BigEndian.Uint16(leaf[0]))
+			entry := append([]byte{}, leaf[0][:2+keyLength]...)
+			index = append(index, binary.BigEndian.AppendUint32(entry, uint32(2+i)))
+		}
+	}
+
+	writeNode(1, 0, 2, 0, 0, index)
+
+	// The header record, an empty user data record and the map of used nodes.
+	header := make([]byte, 106)
+	binary.BigEndian.PutUint16(header, 2)
+	binary.BigEndian.PutUint32(header[2:], 1)
+	binary.BigEndian.PutUint32(header[6:], uint32(len(records)))
+	binary.BigEndian.PutUint32(header[10:], 2)
+	binary.BigEndian.PutUint32(header[14:], uint32(1+len(leaves)))
+	binary.BigEndian.PutUint16(header[18:], nodeSize)
+	binary.BigEndian.PutUint16(header[20:], 516)
+	binary.BigEndian.PutUint32(header[22:], totalNodes)
+	binary.BigEndian.PutUint32(header[26:], uint32(totalNodes-2-len(leaves)))
+	binary.BigEndian.PutUint32(header[32:], nodeSize)
+	binary.BigEndian.PutUint32(header[38:], 6)
+	bitmap := make([]byte, nodeSize-256-8)
+	bitmap[0] = 0xff << (totalNodes - 2 - len(leaves) + 4)
+	writeNode(0, 1, 0, 0, 0, [][]byte{header, make([]byte, 128), bitmap})
+
+	copy(h.data[int(start)*fixtureBlockSize:], nodes)
+	return hfsFork{
+		LogicalSize: uint64(len(nodes)),
+		ClumpSize:   nodeSize,
+		TotalBlocks: uint32(len(nodes) / fixtureBlockSize),
+		Extents:     [8]hfsExtent{{StartBlock: start, BlockCount: uint32(len(nodes) / fixtureBlockSize)}},
+	}
+}
+
+type (
+	apfsImage struct {
+		t      *testing.T
+		blocks [][]byte
+	}
+
+	apfsRecord struct {
+		key   []byte
+		value []byte
+	}
+)
+
+// Object types and storage flags of APFS objects.
+const (
+	apfsObjectBTree     = 0x2
+	apfsObjectBTreeNode = 0x3
+	apfsObjectOmap      = 0xb
+	apfsObjectFS        = 0xd
+	apfsObjectFSTree    = 0xe
+	apfsObjectPhysical  = 0x40000000
+	apfsObjectEphemeral = 0x80000000
+)
+
+// buildAPFS writes an APFS container with one volume holding the entries. The superblock in block zero is
+// stale, so the reader has to find the one of the latest checkpoint.
+func buildAPFS(t *testing.T, entries []fixtureEntry) []byte {
+	t.Helper()
+
+	a := &apfsImage{t: t}
+	for i := 0; i < 10; i++ {
+		a.allocate()
+	}
+
+	const (
+		volumeOID = 1026
+		rootOID   = 1027
+		leafOID   = 1028
+	)
+
+	ids := map[string]uint64{"": apfsRootDirID}
+	parent := func(p string) uint64 {
+		dir := filepath.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+
+		return ids[dir]
+	}
+
+	next := uint64(16)
+	records := []apfsRecord{apfsInodeRecord(apfsRootDirID, 1, 0x41ed, 0, "root", -1)}
+	for _, entry := range entries {
+		name := filepath.Base(entry.path)
+		if entry.hardLink != "" {
+			records = append(records, apfsDirRecord(parent(entry.path), name, ids[entry.hardLink], 8))
+			continue
+		}
+
+		id := next
+		next++
+		ids[entry.path] = id
+
+		switch {
+		case entry.dir:
+			records = append(records, apfsInodeRecord(id, parent(entry.path), 0x41ed, 0, name, -1), apfsDirRecord(parent(entry.path), name, id, 4))
+		case entry.link != "":
+			records = append(records,
+				apfsInodeRecord(id, parent(entry.path), 0xa1ed, 0, name, -1),
+				apfsDirRecord(parent(entry.path), name, id, 10),
+				apfsXattrRecord(id, apfsSymlinkAttribute, apfsXattrEmbedded, append([]byte(entry.link), 0)),
+			)
+		case entry.compress != 0:
+			header, fork := decmpfsFixture(t, entry)
+			records = append(records,
+				apfsInodeRecord(id, parent(entry.path), 0x81a4, apfsInodeCompressed, name, -1),
+				apfsDirRecord(parent(entry.path), name, id, 8),
+				apfsXattrRecord(id, decmpfsAttribute, apfsXattrEmbedded, header),
+			)
+
+			if fork != nil {
+				streamID := next
+				next++
+				stream := make([]byte, 48)
+				binary.LittleEndian.PutUint64(stream, streamID)
+				binary.LittleEndian.PutUint64(stream[8:], uint64(len(fork)))
+				records = append(records, apfsXattrRecord(id, resourceForkAttribute, apfsXattrStream, stream))
+				records = append(records, a.extents(streamID, fork, false)...)
+			}
+		default:
+			mode := uint16(0x81a4)
+			if entry.mode != 0 {
+				mode = 0x8000 | entry.mode
+			}
+
+			size := int64(len(entry.content))
+			if size == 0 {
+				size = -1
+			}
+
+			records = append(records,
+				apfsInodeRecord(id, parent(entry.path), mode, 0, name, size),
+				apfsDirRecord(parent(entry.path), name, id, 8),
+			)
+			records = append(records, a.extents(id, []byte(entry.content), entry.mode != 0)...)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		ki, kj := records[i].key, records[j].key
+		oi, oj := binary.LittleEndian.Uint64(ki)&0x0fffffffffffffff, binary.LittleEndian.Uint64(kj)&0x0fffffffffffffff
+		if oi != oj {
+			return oi < oj
+		}
+
+		if ki[7]>>4 != kj[7]>>4 {
+			return ki[7]>>4 < kj[7]>>4
+		}
+
+		return bytes.Compare(ki[8:], kj[8:]) < 0
+	})
+
+	// The file system tree has a root index node over two leaves.
+	half := len(records) / 2
+	leaves := [][]apfsRecord{records[:half], records[half:]}
+	var index []apfsRecord
+	for i, leaf := range leaves {
+		child := make([]byte, 8)
+		binary.LittleEndian.PutUint64(child, uint64(leafOID+i))
+		index = append(index, apfsRecord{key: leaf[0].key, value: child})
+		a.node(8+i, uint64(leafOID+i), apfsObjectBTreeNode, apfsObjectFSTree, apfsNodeLeaf, 0, leaf, 0, 0)
+	}
+	a.node(7, rootOID, apfsObjectBTree, apfsObjectFSTree, apfsNodeRoot, 1, index, 0, 0)
+
+	// Volume object map.
+	a.omap(5, 6, map[uint64]uint64{rootOID: 7, leafOID: 8, leafOID + 1: 9})
+
+	volume := a.blocks[4]
+	binary.LittleEndian.PutUint64(volume[8:], volumeOID)
+	binary.LittleEndian.PutUint64(volume[16:], 2)
+	binary.LittleEndian.PutUint32(volume[24:], apfsObjectFS)
+	copy(volume[32:], apfsVolumeMagic)
+	binary.LittleEndian.PutUint64(volume[56:], apfsIncompatNormalizationInsensitive)
+	binary.LittleEndian.PutUint32(volume[116:], apfsObjectBTree)
+	binary.LittleEndian.PutUint64(volume[128:], 5)
+	binary.LittleEndian.PutUint64(volume[136:], rootOID)
+	binary.LittleEndian.PutUint64(volume[176:], next)
+	binary.LittleEndian.PutUint64(volume[264:], apfsUnencrypted)
+	copy(volume[704:], "Blender")
+	a.checksum(volume)
+
+	// Container object map, and the superblocks of the stale and the latest checkpoint.
+	a.omap(2, 3, map[uint64]uint64{volumeOID: 4})
+	for i, xid := range []uint64{1, 2} {
+		superblock := a.blocks[i]
+		binary.LittleEndian.PutUint64(superblock[8:], 1)
+		binary.LittleEndian.PutUint64(superblock[16:], xid)
+		binary.LittleEndian.PutUint32(superblock[24:], apfsObjectEphemeral|apfsObjectSuperblock)
+		copy(superblock[32:], apfsContainerMagic)
+		binary.LittleEndian.PutUint32(superblock[36:], fixtureBlockSize)
+		binary.LittleEndian.PutUint64(superblock[40:], uint64(len(a.blocks)))
+		binary.LittleEndian.PutUint64(superblock[88:], volumeOID+16)
+		binary.LittleEndian.PutUint64(superblock[96:], xid+1)
+		binary.LittleEndian.PutUint32(superblock[104:], 1)
+		binary.LittleEndian.PutUint64(superblock[112:], 1)
+		binary.LittleEndian.PutUint32(superblock[180:], apfsMaxFileSystems)
+		binary.LittleEndian.PutUint64(superblock[184:], volumeOID)
+		if xid == 2 {
+			binary.LittleEndian.PutUint64(superblock[160:], 2)
+		} else {
+			// An object map that a later transaction replaced, long since overwritten.
+			binary.LittleEndian.PutUint64(superblock[160:], 10)
+		}
+
+		a.checksum(superblock)
+	}
+
+	return bytes.Join(a.blocks, nil)
+}
+
+func (a *apfsImage) allocate() int {
+	a.blocks = append(a.blocks, make([]byte, fixtureBlockSize))
+	return len(a.blocks) - 1
+}
+
+func (a *apfsImage) checksum(block []byte) {
+	binary.LittleEndian.PutUint64(block, apfsChecksum(block[8:]))
+}
+
+// extents writes the content to new blocks and returns its file extent records. Split content is written as
+// two extents with a gap between them.
+func (a *apfsImage) extents(id uint64, content []byte, split bool) []apfsRecord {
+	parts := [][]byte{content}
+	if split {
+		parts = [][]byte{content[:2*fixtureBlockSize], content[2*fixtureBlockSize:]}
+	}
+
+	var records []apfsRecord
+	logical := uint64(0)
+	for _, part := range parts {
+		a.allocate()
+		first := len(a.blocks)
+		for written := 0; written < len(part); written += fixtureBlockSize {
+			copy(a.blocks[a.allocate()], part[written:])
+		}
+
+		key := apfsKey(id, apfsTypeFileExtent)
+		key = binary.LittleEndian.AppendUint64(key, logical)
+		value := make([]byte, 24)
+		binary.LittleEndian.PutUint64(value, uint64(len(part)))
+		binary.LittleEndian.PutUint64(value[8:], uint64(first))
+		records = append(records, apfsRecord{key: key, value: value})
+		logical += uint64(len(part))
+	}
+
+	return records
+}
+
+// omap writes an object map at the block, with its tree in the next one.
+func (a *apfsImage) omap(block int, tree int, addresses map[uint64]uint64) {
+	omap := a.blocks[block]
+	binary.LittleEndian.PutUint64(omap[8:], uint64(block))
+	binary.LittleEndian.PutUint64(omap[16:], 1)
+	binary.LittleEndian.PutUint32(omap[24:], apfsObjectPhysical|apfsObjectOmap)
+	binary.LittleEndian.PutUint32(omap[40:], apfsObjectPhysical|apfsObjectBTree)
+	binary.LittleEndian.PutUint64(omap[48:], uint64(tree))
+	a.checksum(omap)
+
+	oids := make([]uint64, 0, len(addresses))
+	for oid := range addresses {
+		oids = append(oids, oid)
+	}
+	sort.Slice(oids, func(i, j int) bool { return oids[i] < oids[j] })
+
+	var records []apfsRecord
+	for _, oid := range oids {
+		key := make([]byte, 16)
+		binary.LittleEndian.PutUint64(key, oid)
+		binary.LittleEndian.PutUint64(key[8:], 1)
+		value := make([]byte, 16)
+		binary.LittleEndian.PutUint32(value[4:], fixtureBlockSize)
+		binary.LittleEndian.PutUint64(value[8:], addresses[oid])
+		records = append(records, apfsRecord{key: key, value: value})
+	}
+
+	a.node(tree, uint64(tree), apfsObjectPhysical|apfsObjectBTree, apfsObjectOmap, apfsNodeRoot|apfsNodeLeaf|apfsNodeFixed, 0, records, 16, 16)
+}
+
+// node writes a B-tree node to the block. Fixed size nodes pass the size of their keys and values.
+func (a *apfsImage) node(block int, oid uint64, objectType uint32, subtype uint32, flags uint16, level uint16, records []apfsRecord, keySize int, valueSize int) {
+	b := a.blocks[block]
+	binary.LittleEndian.PutUint64(b[8:], oid)
+	binary.LittleEndian.PutUint64(b[16:], 1)
+	binary.LittleEndian.PutUint32(b[24:], objectType)
+	binary.LittleEndian.PutUint32(b[28:], subtype)
+	binary.LittleEndian.PutUint16(b[32:], flags)
+	binary.LittleEndian.PutUint16(b[34:], level)
+	binary.LittleEndian.PutUint32(b[36:], uint32(len(records)))
+
+	entrySize := 8
+	if flags&apfsNodeFixed != 0 {
+		entrySize = 4
+	}
+
+	tableLength := len(records) * entrySize
+	keyStart := apfsNodeHeaderSize + tableLength
+	valueEnd := len(b)
+	if flags&apfsNodeRoot != 0 {
+		valueEnd -= apfsTreeInfoSize
+	}
+
+	binary.LittleEndian.PutUint16(b[42:], uint16(tableLength))
+	keyOffset, valueOffset := 0, 0
+	for i, record := range records {
+		valueOffset += len(record.value)
+		if keyStart+keyOffset+len(record.key) > valueEnd-valueOffset {
+			a.t.Fatalf("APFS node %d is full", block)
+		}
+
+		copy(b[keyStart+keyOffset:], record.key)
+		copy(b[valueEnd-valueOffset:], record.value)
+
+		entry := b[apfsNodeHeaderSize+i*entrySize:]
+		binary.LittleEndian.PutUint16(entry, uint16(keyOffset))
+		if entrySize == 4 {
+			binary.LittleEndian.PutUint16(entry[2:], uint16(valueOffset))
+		} else {
+			binary.LittleEndian.PutUint16(entry[2:], uint16(len(record.key)))
+			binary.LittleEndian.PutUint16(entry[4:], uint16(valueOffset))
+			binary.LittleEndian.PutUint16(entry[6:], uint16(len(record.value)))
+		}
+
+		keyOffset += len(record.key)
+	}
+
+	binary.LittleEndian.PutUint16(b[44:], uint16(keyOffset))
+	binary.LittleEndian.PutUint16(b[46:], uint16(valueEnd-valueOffset-keyStart-keyOffset))
+	binary.LittleEndian.PutUint32(b[48:], 0xffff)
+	binary.LittleEndian.PutUint32(b[52:], 0xffff)
+
+	if flags&apfsNodeRoot != 0 {
+		info := b[valueEnd:]
+		binary.LittleEndian.PutUint32(info[4:], fixtureBlockSize)
+		binary.LittleEndian.PutUint32(info[8:], uint32(keySize))
+		binary.LittleEndian.PutUint32(info[12:], uint32(valueSize))
+	}
+
+	a.checksum(b)
+}
+
+func apfsKey(id uint64, kind uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, id|kind<<60)
+}
+
+// apfsInodeRecord returns an inode with its name and, unless the size is negative, a data stream.
+func apfsInodeRecord(id uint64, parent uint64, mode uint16, flags uint32, name string, size int64) apfsRecord {
+	value := make([]byte, 92)
+	binary.LittleEndian.PutUint64(value, parent)
+	binary.LittleEndian.PutUint64(value[8:], id)
+	binary.LittleEndian.PutUint64(value[24:], 1700000000*1e9)
+	binary.LittleEndian.PutUint32(value[56:], 1)
+	binary.LittleEndian.PutUint32(value[68:], flags)
+	binary.LittleEndian.PutUint32(value[72:], 501)
+	binary.LittleEndian.PutUint32(value[76:], 20)
+	binary.LittleEndian.PutUint16(value[80:], mode)
+
+	type field struct {
+		kind  uint8
+		flags uint8
+		data  []byte
+	}
+
+	fields := []field{{kind: 4, flags: 0x2, data: append([]byte(name), 0)}}
+	if size >= 0 {
+		stream := make([]byte, 40)
+		binary.LittleEndian.PutUint64(stream, uint64(size))
+		binary.LittleEndian.PutUint64(stream[8:], uint64((size+fixtureBlockSize-1)/fixtureBlockSize*fixtureBlockSize))
+		fields = append(fields, field{kind: apfsInodeDstream, flags: 0x20, data: stream})
+	}
+
+	var data []byte
+	headers := binary.LittleEndian.AppendUint16(nil, uint16(len(fields)))
+	for _, f := range fields {
+		headers = append(headers, f.kind, f.flags)
+		headers = binary.LittleEndian.AppendUint16(headers, uint16(len(f.data)))
+		data = append(data, f.data...)
+		data = append(data, make([]byte, (8-len(f.data)%8)%8)...)
+	}
+
+	headers = append(headers[:2], append(binary.LittleEndian.AppendUint16(nil, uint16(len(data))), headers[2:]...)...)
+	value = append(value, headers...)
+	value = append(value, data...)
+	return apfsRecord{key: apfsKey(id, apfsTypeInode), value: value}
+}
+
+func apfsDirRecord(parent uint64, name string, id uint64, kind uint16) apfsRecord {
+	key := apfsKey(parent, apfsTypeDirRecord)
+	key = binary.LittleEndian.AppendUint32(key, uint32(len(name)+1)|crc32.ChecksumIEEE([]byte(name))<<10)
+	key = append(append(key, name...), 0)
+
+	value := make([]byte, 18)
+	binary.LittleEndian.PutUint64(value, id)
+	binary.LittleEndian.PutUint16(value[16:], kind)
+	return apfsRecord{key: key, value: value}
+}
+
+func apfsXattrRecord(id uint64, name string, flags uint16, data []byte) apfsRecord {
+	key := apfsKey(id, apfsTypeXattr)
+	key = binary.LittleEndian.AppendUint16(key, uint16(len(name)+1))
+	key = append(append(key, name...), 0)
+
+	value := binary.LittleEndian.AppendUint16(nil, flags)
+	value = binary.LittleEndian.AppendUint16(value, uint16(len(data)))
+	return apfsRecord{key: key, value: append(value, data...)}
+}
@@ -0,0 +1,303 @@
+package dmg
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// maxDepth limits the nesting of directories, so a corrupt volume can't recurse forever.
+const maxDepth = 256
+
+type (
+	// FileSystem is a read-only view of the volume in an image. It implements fs.FS, except that symbolic
+	// links are never followed: they are listed with fs.ModeSymlink and their targets are read with ReadLink.
+	FileSystem struct {
+		root *node
+	}
+
+	// node is a file, directory or symbolic link of a volume.
+	node struct {
+		name     string
+		mode     fs.FileMode
+		size     int64
+		modTime  time.Time
+		children []*node
+		link     string
+		open     func() (io.Reader, error)
+	}
+
+	nodeInfo struct {
+		node *node
+	}
+
+	file struct {
+		node   *node
+		reader io.Reader
+		offset int
+	}
+
+	// extent maps a run of a file to the volume. Runs without an extent, or with a negative physical offset,
+	// read as zeros.
+	extent struct {
+		logical  int64
+		physical int64
+		length   int64
+	}
+
+	extentReader struct {
+		r       io.ReaderAt
+		extents []extent
+		size    int64
+	}
+)
+
+// Open opens the named file or directory.
+func (f *FileSystem) Open(name string) (fs.File, error) {
+	n, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{node: n}, nil
+}
+
+// ReadDir returns the entries of the named directory, sorted by name.
+func (f *FileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := f.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, nodeInfo{child})
+	}
+
+	return entries, nil
+}
+
+// Stat returns the file info of the named file, without following a final symbolic link.
+func (f *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	n, err := f.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeInfo{n}, nil
+}
+
+// ReadLink returns the target of the named symbolic link.
+func (f *FileSystem) ReadLink(name string) (string, error) {
+	n, err := f.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.New("not a symbolic link")}
+	}
+
+	return n.link, nil
+}
+
+func (f *FileSystem) lookup(op string, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := f.root
+	if name == "." {
+		return current, nil
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if !current.mode.IsDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		index := sort.Search(len(current.children), func(i int) bool {
+			return current.children[i].name >= part
+		})
+
+		if index == len(current.children) || current.children[index].name != part {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		current = current.children[index]
+	}
+
+	return current, nil
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return nodeInfo{f.node}, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if !f.node.mode.IsRegular() {
+		return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: errors.New("not a regular file")}
+	}
+
+	if f.reader == nil {
+		reader, err := f.node.open()
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.node.name, Err: err}
+		}
+
+		f.reader = reader
+	}
+
+	return f.reader.Read(p)
+}
+
+func (f *file) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !f.node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.node.name, Err: errors.New("not a directory")}
+	}
+
+	remaining := f.node.children[f.offset:]
+	if count > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if count > 0 && count < len(remaining) {
+		remaining = remaining[:count]
+	}
+
+	entries := make([]fs.DirEntry, 0, len(remaining))
+	for _, child := range remaining {
+		entries = append(entries, nodeInfo{child})
+	}
+
+	f.offset += len(remaining)
+	return entries, nil
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+func (i nodeInfo) Name() string               { return i.node.name }
+func (i nodeInfo) Size() int64                { return i.node.size }
+func (i nodeInfo) Mode() fs.FileMode          { return i.node.mode }
+func (i nodeInfo) ModTime() time.Time         { return i.node.modTime }
+func (i nodeInfo) IsDir() bool                { return i.node.mode.IsDir() }
+func (i nodeInfo) Sys() interface{}           { return nil }
+func (i nodeInfo) Type() fs.FileMode          { return i.node.mode.Type() }
+func (i nodeInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// sortNodes orders the children of a directory by name and rejects names that can't be written to disk.
+func sortNodes(nodes []*node) ([]*node, error) {
+	for _, n := range nodes {
+		if n.name == "" || n.name == "." || n.name == ".." || strings.ContainsAny(n.name, "/\x00") {
+			return nil, errors.New("invalid file name: " + n.name)
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].name < nodes[j].name
+	})
+
+	for i := 1; i < len(nodes); i++ {
+		if nodes[i].name == nodes[i-1].name {
+			return nil, errors.New("duplicate file name: " + nodes[i].name)
+		}
+	}
+
+	return nodes, nil
+}
+
+// fileMode converts the permission bits of a BSD mode, using the default if the volume doesn't store any.
+func fileMode(mode uint16, defaultPerm fs.FileMode) fs.FileMode {
+	if mode&0777 == 0 {
+		return defaultPerm
+	}
+
+	return fs.FileMode(mode & 0777)
+}
+
+// readLinkTarget reads the target of a symbolic link stored as the content of a file.
+func readLinkTarget(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\x00"), nil
+}
+
+func (e *extentReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= e.size {
+		return 0, io.EOF
+	}
+
+	var err error
+	if remaining := e.size - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+		err = io.EOF
+	}
+
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		index := sort.Search(len(e.extents), func(i int) bool {
+			return e.extents[i].logical+e.extents[i].length > pos
+		})
+
+		end := int64(len(p))
+		if index == len(e.extents) || e.extents[index].logical > pos || e.extents[index].physical < 0 {
+			if index < len(e.extents) && e.extents[index].logical > pos {
+				end = min(end, e.extents[index].logical-off)
+			} else if index < len(e.extents) {
+				end = min(end, e.extents[index].logical+e.extents[index].length-off)
+			}
+
+			clear(p[n:end])
+			n = int(end)
+			continue
+		}
+
+		x := e.extents[index]
+		end = min(end, x.logical+x.length-off)
+		if m, readErr := e.r.ReadAt(p[n:end], x.physical+pos-x.logical); m < int(end)-n {
+			if readErr == nil || readErr == io.EOF {
+				readErr = io.ErrUnexpectedEOF
+			}
+
+			return n + m, readErr
+		}
+
+		n = int(end)
+	}
+
+	return n, err
+}
+
+func (e *extentReader) reader() io.Reader {
+	return io.NewSectionReader(e, 0, e.size)
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+
+	return string(utf16.Decode(units))
+}
+
+// cleanName converts a name stored on a volume to one that can be used in a path.
+func cleanName(name string) string {
+	// The POSIX layer of macOS shows colons in stored names as slashes, and the other way around.
+	return strings.ReplaceAll(name, "/", ":")
+}
@@ -0,0 +1,518 @@
+package dmg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	hfsSignature  = "H+"
+	hfsxSignature = "HX"
+
+	hfsRootFolderID     = 2
+	hfsExtentsFileID    = 3
+	hfsCatalogFileID    = 4
+	hfsAttributesFileID = 8
+
+	hfsFolderRecord = 1
+	hfsFileRecord   = 2
+
+	hfsDataFork     = 0x00
+	hfsResourceFork = 0xff
+
+	hfsLeafNode        = -1
+	hfsInlineAttribute = 0x10
+
+	// hfsPrivateFolder holds the files that hard links point to.
+	hfsPrivateFolder = "\x00\x00\x00\x00HFS+ Private Data"
+	// hfsPrivateDirFolder holds the directories that directory hard links point to.
+	hfsPrivateDirFolder = ".HFS+ Private Directory Data\r"
+
+	// hfsEpochOffset is the number of seconds between 1904, when HFS time starts, and 1970.
+	hfsEpochOffset = 2082844800
+)
+
+type (
+	hfsExtent struct {
+		StartBlock uint32
+		BlockCount uint32
+	}
+
+	hfsFork struct {
+		LogicalSize uint64
+		ClumpSize   uint32
+		TotalBlocks uint32
+		Extents     [8]hfsExtent
+	}
+
+	hfsVolumeHeader struct {
+		Signature          [2]byte
+		Version            uint16
+		Attributes         uint32
+		LastMountedVersion uint32
+		JournalInfoBlock   uint32
+		CreateDate         uint32
+		ModifyDate         uint32
+		BackupDate         uint32
+		CheckedDate        uint32
+		FileCount          uint32
+		FolderCount        uint32
+		BlockSize          uint32
+		TotalBlocks        uint32
+		FreeBlocks         uint32
+		NextAllocation     uint32
+		RsrcClumpSize      uint32
+		DataClumpSize      uint32
+		NextCatalogID      uint32
+		WriteCount         uint32
+		EncodingsBitmap    uint64
+		FinderInfo         [8]uint32
+		AllocationFile     hfsFork
+		ExtentsFile        hfsFork
+		CatalogFile        hfsFork
+		AttributesFile     hfsFork
+		StartupFile        hfsFork
+	}
+
+	hfsForkKey struct {
+		fileID   uint32
+		forkType uint8
+	}
+
+	// hfsOverflow is a record of the extents overflow file, continuing a fork from the start block on.
+	hfsOverflow struct {
+		startBlock uint32
+		extents    [8]hfsExtent
+	}
+
+	// hfsRecord is a file or folder record of the catalog.
+	hfsRecord struct {
+		parentID   uint32
+		name       string
+		id         uint32
+		folder     bool
+		mode       uint16
+		modTime    time.Time
+		compressed bool
+		fileType   string
+		creator    string
+		special    uint32
+		data       hfsFork
+		resource   hfsFork
+	}
+
+	hfsVolume struct {
+		r         io.ReaderAt
+		blockSize int64
+		overflow  map[hfsForkKey][]hfsOverflow
+		children  map[uint32][]*hfsRecord
+		decmpfs   map[uint32][]byte
+	}
+
+	hfsBTree struct {
+		r          io.ReaderAt
+		nodeSize   int
+		firstLeaf  uint32
+		totalNodes uint32
+	}
+)
+
+// openHFS reads the catalog of the HFS+ or HFSX volume and returns its root folder.
+func openHFS(r io.ReaderAt) (*node, error) {
+	var header hfsVolumeHeader
+	if err := binary.Read(io.NewSectionReader(r, 1024, 512), binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.BlockSize < 512 || header.BlockSize&(header.BlockSize-1) != 0 {
+		return nil, fmt.Errorf("invalid HFS+ block size %d", header.BlockSize)
+	}
+
+	v := &hfsVolume{
+		r:         r,
+		blockSize: int64(header.BlockSize),
+		overflow:  make(map[hfsForkKey][]hfsOverflow),
+		children:  make(map[uint32][]*hfsRecord),
+		decmpfs:   make(map[uint32][]byte),
+	}
+
+	// The extents overflow file comes first, as the other special files may continue in it.
+	if header.ExtentsFile.LogicalSize > 0 {
+		if err := v.walkBTree(hfsExtentsFileID, header.ExtentsFile, v.addOverflow); err != nil {
+			return nil, fmt.Errorf("failed to read extents overflow file: %w", err)
+		}
+	}
+
+	if err := v.walkBTree(hfsCatalogFileID, header.CatalogFile, v.addCatalogRecord); err != nil {
+		return nil, fmt.Errorf("failed to read catalog file: %w", err)
+	}
+
+	if header.AttributesFile.LogicalSize > 0 {
+		if err := v.walkBTree(hfsAttributesFileID, header.AttributesFile, v.addAttribute); err != nil {
+			return nil, fmt.Errorf("failed to read attributes file: %w", err)
+		}
+	}
+
+	children, err := v.folder(hfsRootFolderID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node{name: ".", mode: fs.ModeDir | 0755, children: children}, nil
+}
+
+func (v *hfsVolume) walkBTree(fileID uint32, fork hfsFork, fn func(key []byte, data []byte) error) error {
+	reader, err := v.forkReader(fileID, hfsDataFork, fork)
+	if err != nil {
+		return err
+	}
+
+	tree, err := openHFSBTree(reader)
+	if err != nil {
+		return err
+	}
+
+	return tree.walkLeaves(fn)
+}
+
+func (v *hfsVolume) addOverflow(key []byte, data []byte) error {
+	if len(key) < 10 || len(data) < 64 {
+		return errors.New("invalid extents overflow record")
+	}
+
+	record := hfsOverflow{startBlock: binary.BigEndian.Uint32(key[6:])}
+	if err := binary.Read(bytes.NewReader(data), binary.BigEndian, &record.extents); err != nil {
+		return err
+	}
+
+	forkKey := hfsForkKey{fileID: binary.BigEndian.Uint32(key[2:]), forkType: key[0]}
+	v.overflow[forkKey] = append(v.overflow[forkKey], record)
+	return nil
+}
+
+func (v *hfsVolume) addCatalogRecord(key []byte, data []byte) error {
+	if len(key) < 6 || len(data) < 2 {
+		return errors.New("invalid catalog record")
+	}
+
+	nameLength := int(binary.BigEndian.Uint16(key[4:]))
+	if len(key) < 6+2*nameLength {
+		return errors.New("invalid catalog key")
+	}
+
+	record := &hfsRecord{
+		parentID: binary.BigEndian.Uint32(key),
+		name:     cleanName(decodeUTF16(key[6:6+2*nameLength], binary.BigEndian)),
+	}
+
+	switch binary.BigEndian.Uint16(data) {
+	case hfsFolderRecord:
+		if len(data) < 88 {
+			return errors.New("invalid folder record")
+		}
+
+		record.folder = true
+	case hfsFileRecord:
+		if len(data) < 248 {
+			return errors.New("invalid file record")
+		}
+
+		reader := bytes.NewReader(data[88:])
+		if err := binary.Read(reader, binary.BigEndian, &record.data); err != nil {
+			return err
+		}
+
+		if err := binary.Read(reader, binary.BigEndian, &record.resource); err != nil {
+			return err
+		}
+
+		record.compressed = data[41]&0x20 != 0
+		record.special = binary.BigEndian.Uint32(data[44:])
+		record.fileType = string(data[48:52])
+		record.creator = string(data[52:56])
+	default:
+		// Thread records only map an id back to its name.
+		return nil
+	}
+
+	record.id = binary.BigEndian.Uint32(data[8:])
+	record.modTime = time.Unix(int64(binary.BigEndian.Uint32(data[16:]))-hfsEpochOffset, 0).UTC()
+	record.mode = binary.BigEndian.Uint16(data[42:])
+
+	v.children[record.parentID] = append(v.children[record.parentID], record)
+	return nil
+}
+
+// addAttribute keeps the compression headers of compressed files. Other extended attributes are ignored.
+func (v *hfsVolume) addAttribute(key []byte, data []byte) error {
+	if len(key) < 12 || len(data) < 16 {
+		return nil
+	}
+
+	nameLength := int(binary.BigEndian.Uint16(key[10:]))
+	if len(key) < 12+2*nameLength || decodeUTF16(key[12:12+2*nameLength], binary.BigEndian) != decmpfsAttribute {
+		return nil
+	}
+
+	if binary.BigEndian.Uint32(data) != hfsInlineAttribute {
+		return fmt.Errorf("%w: compression header stored out of line", ErrUnsupported)
+	}
+
+	size := int(binary.BigEndian.Uint32(data[12:]))
+	if len(data) < 16+size {
+		return errors.New("invalid attribute record")
+	}
+
+	v.decmpfs[binary.BigEndian.Uint32(key[2:])] = append([]byte{}, data[16:16+size]...)
+	return nil
+}
+
+// folder returns the contents of the folder.
+func (v *hfsVolume) folder(id uint32, depth int) ([]*node, error) {
+	if depth > maxDepth {
+		return nil, errors.New("folders are nested too deeply")
+	}
+
+	var nodes []*node
+	for _, record := range v.children[id] {
+		if id == hfsRootFolderID && (record.name == hfsPrivateFolder || record.name == hfsPrivateDirFolder) {
+			continue
+		}
+
+		n, err := v.node(record, depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", record.name, err)
+		}
+
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return sortNodes(nodes)
+}
+
+func (v *hfsVolume) node(record *hfsRecord, depth int) (*node, error) {
+	n := &node{name: record.name, modTime: record.modTime}
+	if record.folder {
+		children, err := v.folder(record.id, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		n.mode = fs.ModeDir | fileMode(record.mode, 0755)
+		n.children = children
+		return n, nil
+	}
+
+	switch {
+	case record.fileType == "hlnk" && record.creator == "hfs+":
+		target, err := v.linkTarget(record.special)
+		if err != nil {
+			return nil, err
+		}
+
+		record = target
+	case record.fileType == "fdrp" && record.creator == "MACS":
+		return nil, fmt.Errorf("%w: directory hard links", ErrUnsupported)
+	}
+
+	switch record.mode & 0xf000 {
+	case 0xa000:
+		data, err := v.forkReader(record.id, hfsDataFork, record.data)
+		if err != nil {
+			return nil, err
+		}
+
+		link, err := readLinkTarget(data.reader())
+		if err != nil {
+			return nil, err
+		}
+
+		n.mode = fs.ModeSymlink | 0777
+		n.link = link
+	case 0x8000, 0:
+		n.mode = fileMode(record.mode, 0644)
+		if err := v.setContent(n, record); err != nil {
+			return nil, err
+		}
+	default:
+		// Devices, sockets and pipes have no content to copy.
+		return nil, nil
+	}
+
+	return n, nil
+}
+
+// linkTarget returns the file that a hard link with the number points to.
+func (v *hfsVolume) linkTarget(number uint32) (*hfsRecord, error) {
+	for _, folder := range v.children[hfsRootFolderID] {
+		if !folder.folder || folder.name != hfsPrivateFolder {
+			continue
+		}
+
+		name := "iNode" + strconv.FormatUint(uint64(number), 10)
+		for _, record := range v.children[folder.id] {
+			if record.name == name && !record.folder {
+				return record, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("hard link target %d not found", number)
+}
+
+func (v *hfsVolume) setContent(n *node, record *hfsRecord) error {
+	if record.compressed {
+		header, ok := v.decmpfs[record.id]
+		if !ok {
+			return errors.New("compressed file has no compression header")
+		}
+
+		size, open, err := decmpfsContent(header, func() (io.ReaderAt, int64, error) {
+			resource, err := v.forkReader(record.id, hfsResourceFork, record.resource)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			return resource, resource.size, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		n.size = size
+		n.open = open
+		return nil
+	}
+
+	data, err := v.forkReader(record.id, hfsDataFork, record.data)
+	if err != nil {
+		return err
+	}
+
+	n.size = data.size
+	n.open = func() (io.Reader, error) {
+		return data.reader(), nil
+	}
+
+	return nil
+}
+
+// forkReader returns a reader for the fork, including any extents in the overflow file.
+func (v *hfsVolume) forkReader(fileID uint32, forkType uint8, fork hfsFork) (*extentReader, error) {
+	reader := &extentReader{r: v.r, size: int64(fork.LogicalSize)}
+	blocks := uint32(0)
+	add := func(extents [8]hfsExtent) {
+		for _, x := range extents {
+			if x.BlockCount == 0 || blocks >= fork.TotalBlocks {
+				return
+			}
+
+			reader.extents = append(reader.extents, extent{
+				logical:  int64(blocks) * v.blockSize,
+				physical: int64(x.StartBlock) * v.blockSize,
+				length:   int64(x.BlockCount) * v.blockSize,
+			})
+			blocks += x.BlockCount
+		}
+	}
+
+	add(fork.Extents)
+	if blocks < fork.TotalBlocks {
+		records := v.overflow[hfsForkKey{fileID: fileID, forkType: forkType}]
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].startBlock < records[j].startBlock
+		})
+
+		for _, record := range records {
+			if record.startBlock != blocks {
+				return nil, fmt.Errorf("missing extents of file %d", fileID)
+			}
+
+			add(record.extents)
+		}
+	}
+
+	if int64(blocks)*v.blockSize < reader.size {
+		return nil, fmt.Errorf("missing extents of file %d", fileID)
+	}
+
+	return reader, nil
+}
+
+func openHFSBTree(r io.ReaderAt) (*hfsBTree, error) {
+	header := make([]byte, 14+106)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	tree := &hfsBTree{
+		r:          r,
+		firstLeaf:  binary.BigEndian.Uint32(header[24:]),
+		nodeSize:   int(binary.BigEndian.Uint16(header[32:])),
+		totalNodes: binary.BigEndian.Uint32(header[36:]),
+	}
+
+	if tree.nodeSize < 512 || tree.nodeSize&(tree.nodeSize-1) != 0 {
+		return nil, fmt.Errorf("invalid B-tree node size %d", tree.nodeSize)
+	}
+
+	return tree, nil
+}
+
+// walkLeaves calls the function with the key and data of every record, in the order of the leaf nodes.
+func (t *hfsBTree) walkLeaves(fn func(key []byte, data []byte) error) error {
+	buffer := make([]byte, t.nodeSize)
+	visited := uint32(0)
+	for id := t.firstLeaf; id != 0; id = binary.BigEndian.Uint32(buffer) {
+		if visited++; visited > t.totalNodes {
+			return errors.New("B-tree leaf nodes form a loop")
+		}
+
+		if _, err := t.r.ReadAt(buffer, int64(id)*int64(t.nodeSize)); err != nil {
+			return err
+		}
+
+		if int8(buffer[8]) != hfsLeafNode {
+			return fmt.Errorf("B-tree node %d is not a leaf node", id)
+		}
+
+		count := int(binary.BigEndian.Uint16(buffer[10:]))
+		if 14+2*(count+1) > t.nodeSize {
+			return fmt.Errorf("invalid B-tree node %d", id)
+		}
+
+		offset := func(i int) int {
+			return int(binary.BigEndian.Uint16(buffer[t.nodeSize-2*(i+1):]))
+		}
+
+		for i := 0; i < count; i++ {
+			start, end := offset(i), offset(i+1)
+			if start < 14 || end < start+2 || end > t.nodeSize-2*(count+1) {
+				return fmt.Errorf("invalid record in B-tree node %d", id)
+			}
+
+			record := buffer[start:end]
+			keyEnd := 2 + int(binary.BigEndian.Uint16(record))
+			if keyEnd > len(record) {
+				return fmt.Errorf("invalid record in B-tree node %d", id)
+			}
+
+			// Record data starts on an even offset.
+			dataStart := keyEnd + keyEnd%2
+			if err := fn(record[2:keyEnd], record[min(dataStart, len(record)):]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
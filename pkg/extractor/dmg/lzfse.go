@@ -0,0 +1,571 @@
+package dmg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Block magics of an LZFSE stream.
+const (
+	lzfseEndOfStream   = "bvx$"
+	lzfseUncompressed  = "bvx-"
+	lzfseCompressedV1  = "bvx1"
+	lzfseCompressedV2  = "bvx2"
+	lzfseCompressedVN  = "bvxn"
+	lzfseV2HeaderSize  = 32
+	lzfseLiteralStates = 1024
+	lzfseLStates       = 64
+	lzfseMStates       = 64
+	lzfseDStates       = 256
+	lzfseLiteralCount  = 256
+	lzfseLCount        = 20
+	lzfseMCount        = 20
+	lzfseDCount        = 64
+
+	lzvnEndOfStream = 0x06
+)
+
+var (
+	lzfseLExtraBits = [lzfseLCount]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 3, 5, 8}
+	lzfseLBase      = [lzfseLCount]int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 20, 28, 60}
+	lzfseMExtraBits = [lzfseMCount]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 5, 8, 11}
+	lzfseMBase      = [lzfseMCount]int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 24, 56, 312}
+	lzfseDExtraBits [lzfseDCount]uint8
+	lzfseDBase      [lzfseDCount]int32
+
+	errLZFSECorrupt = errors.New("corrupt LZFSE data")
+	errLZVNCorrupt  = errors.New("corrupt LZVN data")
+)
+
+type (
+	// fseEntry decodes a literal: the symbol of a state and how to reach the next state.
+	fseEntry struct {
+		bits   uint8
+		symbol uint8
+		delta  int32
+	}
+
+	// fseValueEntry decodes an L, M or D value: the next state and the value's extra bits come from the
+	// same read.
+	fseValueEntry struct {
+		totalBits uint8
+		valueBits uint8
+		delta     int32
+		base      int32
+	}
+
+	// fseReader reads the bits of an FSE stream, which is read backwards from its end.
+	fseReader struct {
+		data  []byte
+		pos   int
+		accum uint64
+		count int
+	}
+)
+
+func init() {
+	// Distances come in groups of four sharing the number of extra bits, each group covering four times as
+	// many distances as the one before it.
+	base := int32(0)
+	for i := range lzfseDBase {
+		extra := uint8(0)
+		if i >= 4 {
+			extra = uint8(i/4 - 1)
+		}
+
+		lzfseDExtraBits[i] = extra
+		lzfseDBase[i] = base
+		base += 1 << extra
+	}
+}
+
+// decodeLZFSE decompresses an LZFSE stream of at most size bytes.
+func decodeLZFSE(src []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for {
+		if len(src) < 4 {
+			return nil, errLZFSECorrupt
+		}
+
+		var err error
+		switch string(src[:4]) {
+		case lzfseEndOfStream:
+			return out, nil
+		case lzfseUncompressed:
+			if len(src) < 8 {
+				return nil, errLZFSECorrupt
+			}
+
+			n := int(binary.LittleEndian.Uint32(src[4:]))
+			if len(src) < 8+n || len(out)+n > size {
+				return nil, errLZFSECorrupt
+			}
+
+			out = append(out, src[8:8+n]...)
+			src = src[8+n:]
+		case lzfseCompressedVN:
+			if len(src) < 12 {
+				return nil, errLZFSECorrupt
+			}
+
+			raw := int(binary.LittleEndian.Uint32(src[4:]))
+			payload := int(binary.LittleEndian.Uint32(src[8:]))
+			if len(src) < 12+payload || len(out)+raw > size {
+				return nil, errLZFSECorrupt
+			}
+
+			if out, err = decodeLZVN(out, src[12:12+payload], len(out)+raw); err != nil {
+				return nil, err
+			}
+
+			src = src[12+payload:]
+		case lzfseCompressedV2:
+			if out, src, err = decodeLZFSEBlock(out, src, size); err != nil {
+				return nil, err
+			}
+		case lzfseCompressedV1:
+			return nil, fmt.Errorf("%w: LZFSE version 1 blocks", ErrUnsupported)
+		default:
+			return nil, errLZFSECorrupt
+		}
+	}
+}
+
+// decodeLZFSEBlock decodes a version 2 compressed block, returning the output and the rest of the stream.
+func decodeLZFSEBlock(out []byte, src []byte, size int) ([]byte, []byte, error) {
+	if len(src) < lzfseV2HeaderSize {
+		return nil, nil, errLZFSECorrupt
+	}
+
+	raw := int(binary.LittleEndian.Uint32(src[4:]))
+	v0 := binary.LittleEndian.Uint64(src[8:])
+	v1 := binary.LittleEndian.Uint64(src[16:])
+	v2 := binary.LittleEndian.Uint64(src[24:])
+	field := func(v uint64, offset uint, width uint) int {
+		return int((v >> offset) & (1<<width - 1))
+	}
+
+	literalCount := field(v0, 0, 20)
+	literalPayload := field(v0, 20, 20)
+	matchCount := field(v0, 40, 20)
+	literalBits := field(v0, 60, 3) - 7
+	literalStates := [4]int{field(v1, 0, 10), field(v1, 10, 10), field(v1, 20, 10), field(v1, 30, 10)}
+	lmdPayload := field(v1, 40, 20)
+	lmdBits := field(v1, 60, 3) - 7
+	headerSize := field(v2, 0, 32)
+	lState, mState, dState := field(v2, 32, 10), field(v2, 42, 10), field(v2, 52, 10)
+
+	if headerSize < lzfseV2HeaderSize || len(src) < headerSize+literalPayload+lmdPayload || len(out)+raw > size {
+		return nil, nil, errLZFSECorrupt
+	}
+
+	if lState >= lzfseLStates || mState >= lzfseMStates || dState >= lzfseDStates {
+		return nil, nil, errLZFSECorrupt
+	}
+
+	freq, err := decodeLZFSEFrequencies(src[lzfseV2HeaderSize:headerSize])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lFreq := freq[:lzfseLCount]
+	mFreq := freq[lzfseLCount : lzfseLCount+lzfseMCount]
+	dFreq := freq[lzfseLCount+lzfseMCount : lzfseLCount+lzfseMCount+lzfseDCount]
+	literalFreq := freq[lzfseLCount+lzfseMCount+lzfseDCount:]
+
+	literalTable, err := newFSETable(lzfseLiteralStates, literalFreq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lTable, err := newFSEValueTable(lzfseLStates, lFreq, lzfseLExtraBits[:], lzfseLBase[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mTable, err := newFSEValueTable(lzfseMStates, mFreq, lzfseMExtraBits[:], lzfseMBase[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dTable, err := newFSEValueTable(lzfseDStates, dFreq, lzfseDExtraBits[:], lzfseDBase[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload := src[headerSize:]
+
+	// Literals are decoded four at a time, with interleaved states.
+	literals := make([]byte, literalCount+4)
+	reader, err := newFSEReader(payload[:literalPayload], literalBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, state := range literalStates {
+		if state >= lzfseLiteralStates {
+			return nil, nil, errLZFSECorrupt
+		}
+	}
+
+	for i := 0; i < literalCount; i += 4 {
+		if err := reader.flush(); err != nil {
+			return nil, nil, err
+		}
+
+		for j := range literalStates {
+			entry := literalTable[literalStates[j]]
+			value, err := reader.pull(int(entry.bits))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			literals[i+j] = entry.symbol
+			literalStates[j] = int(entry.delta) + int(value)
+			if literalStates[j] < 0 || literalStates[j] >= lzfseLiteralStates {
+				return nil, nil, errLZFSECorrupt
+			}
+		}
+	}
+
+	reader, err = newFSEReader(payload[literalPayload:literalPayload+lmdPayload], lmdBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := len(out)
+	literal := 0
+	distance := -1
+	states := [3]int{lState, mState, dState}
+	tables := [3][]fseValueEntry{lTable, mTable, dTable}
+	limits := [3]int{lzfseLStates, lzfseMStates, lzfseDStates}
+	for i := 0; i < matchCount; i++ {
+		if err := reader.flush(); err != nil {
+			return nil, nil, err
+		}
+
+		var values [3]int
+		for j := range values {
+			entry := tables[j][states[j]]
+			read, err := reader.pull(int(entry.totalBits))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			states[j] = int(entry.delta) + int(read>>entry.valueBits)
+			if states[j] < 0 || states[j] >= limits[j] {
+				return nil, nil, errLZFSECorrupt
+			}
+
+			values[j] = int(entry.base) + int(read&(1<<entry.valueBits-1))
+		}
+
+		length, matchLength := values[0], values[1]
+		if values[2] != 0 {
+			distance = values[2]
+		}
+
+		if literal+length > literalCount || len(out)+length+matchLength > start+raw {
+			return nil, nil, errLZFSECorrupt
+		}
+
+		out = append(out, literals[literal:literal+length]...)
+		literal += length
+
+		if matchLength > 0 {
+			if distance <= 0 || distance > len(out) {
+				return nil, nil, errLZFSECorrupt
+			}
+
+			out = copyMatch(out, distance, matchLength)
+		}
+	}
+
+	if len(out) != start+raw {
+		return nil, nil, errLZFSECorrupt
+	}
+
+	return out, src[headerSize+literalPayload+lmdPayload:], nil
+}
+
+// decodeLZFSEFrequencies decodes the variable length frequencies of the L, M, D and literal symbols.
+func decodeLZFSEFrequencies(data []byte) ([]uint16, error) {
+	freq := make([]uint16, lzfseLCount+lzfseMCount+lzfseDCount+lzfseLiteralCount)
+	if len(data) == 0 {
+		return freq, nil
+	}
+
+	accum := uint32(0)
+	count := 0
+	for i := range freq {
+		for len(data) > 0 && count+8 <= 32 {
+			accum |= uint32(data[0]) << count
+			count += 8
+			data = data[1:]
+		}
+
+		value, n := decodeLZFSEFrequency(accum)
+		if n > count {
+			return nil, errLZFSECorrupt
+		}
+
+		freq[i] = value
+		accum >>= n
+		count -= n
+	}
+
+	if count >= 8 || len(data) != 0 {
+		return nil, errLZFSECorrupt
+	}
+
+	return freq, nil
+}
+
+// decodeLZFSEFrequency decodes a single frequency from the low bits, returning it and the number of bits used.
+func decodeLZFSEFrequency(bits uint32) (uint16, int) {
+	nbits := [32]int{
+		2, 3, 2, 5, 2, 3, 2, 8, 2, 3, 2, 5, 2, 3, 2, 14,
+		2, 3, 2, 5, 2, 3, 2, 8, 2, 3, 2, 5, 2, 3, 2, 14,
+	}
+	values := [32]uint16{
+		0, 2, 1, 4, 0, 3, 1, 0, 0, 2, 1, 5, 0, 3, 1, 0,
+		0, 2, 1, 6, 0, 3, 1, 0, 0, 2, 1, 7, 0, 3, 1, 0,
+	}
+
+	b := bits & 31
+	switch n := nbits[b]; n {
+	case 8:
+		return 8 + uint16((bits>>4)&0xf), n
+	case 14:
+		return 24 + uint16((bits>>4)&0x3ff), n
+	default:
+		return values[b], n
+	}
+}
+
+// fseShift returns the number of bits read for the states of a symbol with the frequency, such that
+// states <= freq << shift < 2 * states.
+func fseShift(freq int, states int) int {
+	return bits.LeadingZeros32(uint32(freq)) - bits.LeadingZeros32(uint32(states))
+}
+
+func newFSETable(states int, freq []uint16) ([]fseEntry, error) {
+	table := make([]fseEntry, 0, states)
+	for symbol, f := range freq {
+		if f == 0 {
+			continue
+		}
+
+		if len(table)+int(f) > states {
+			return nil, errLZFSECorrupt
+		}
+
+		k := fseShift(int(f), states)
+		j0 := ((2 * states) >> k) - int(f)
+		for j := 0; j < int(f); j++ {
+			entry := fseEntry{symbol: uint8(symbol)}
+			if j < j0 {
+				entry.bits = uint8(k)
+				entry.delta = int32(((int(f) + j) << k) - states)
+			} else {
+				entry.bits = uint8(k - 1)
+				entry.delta = int32((j - j0) << (k - 1))
+			}
+
+			table = append(table, entry)
+		}
+	}
+
+	return table[:states:states], nil
+}
+
+func newFSEValueTable(states int, freq []uint16, extraBits []uint8, base []int32) ([]fseValueEntry, error) {
+	table := make([]fseValueEntry, 0, states)
+	for symbol, f := range freq {
+		if f == 0 {
+			continue
+		}
+
+		if len(table)+int(f) > states {
+			return nil, errLZFSECorrupt
+		}
+
+		k := fseShift(int(f), states)
+		j0 := ((2 * states) >> k) - int(f)
+		for j := 0; j < int(f); j++ {
+			entry := fseValueEntry{valueBits: extraBits[symbol], base: base[symbol]}
+			if j < j0 {
+				entry.totalBits = uint8(k) + entry.valueBits
+				entry.delta = int32(((int(f) + j) << k) - states)
+			} else {
+				entry.totalBits = uint8(k-1) + entry.valueBits
+				entry.delta = int32((j - j0) << (k - 1))
+			}
+
+			table = append(table, entry)
+		}
+	}
+
+	return table[:states:states], nil
+}
+
+// newFSEReader starts reading the stream from its end. The first bits read are in the last byte, which has
+// -extra unused high bits.
+func newFSEReader(data []byte, extra int) (*fseReader, error) {
+	r := &fseReader{data: data, pos: len(data)}
+	if extra == 0 {
+		if r.pos < 7 {
+			return nil, errLZFSECorrupt
+		}
+
+		r.pos -= 7
+		var buffer [8]byte
+		copy(buffer[:], data[r.pos:r.pos+7])
+		r.accum = binary.LittleEndian.Uint64(buffer[:])
+		r.count = 56
+	} else {
+		if r.pos < 8 {
+			return nil, errLZFSECorrupt
+		}
+
+		r.pos -= 8
+		r.accum = binary.LittleEndian.Uint64(data[r.pos:])
+		r.count = 64 + extra
+	}
+
+	if r.count < 56 || r.count >= 64 || r.accum>>r.count != 0 {
+		return nil, errLZFSECorrupt
+	}
+
+	return r, nil
+}
+
+// flush refills the accumulator with whole bytes, so at least 56 bits are available.
+func (r *fseReader) flush() error {
+	n := (63 - r.count) &^ 7
+	if n == 0 {
+		return nil
+	}
+
+	r.pos -= n >> 3
+	if r.pos < 0 {
+		return errLZFSECorrupt
+	}
+
+	var buffer [8]byte
+	copy(buffer[:], r.data[r.pos:])
+	incoming := binary.LittleEndian.Uint64(buffer[:])
+	r.accum = r.accum<<n | incoming&(1<<n-1)
+	r.count += n
+	return nil
+}
+
+func (r *fseReader) pull(n int) (uint64, error) {
+	if n > r.count {
+		return 0, errLZFSECorrupt
+	}
+
+	r.count -= n
+	value := r.accum >> r.count
+	r.accum &= 1<<r.count - 1
+	return value, nil
+}
+
+// decodeLZVN decompresses an LZVN stream, appending to out until it holds size bytes or the stream ends.
+// Matches may refer to anything already in out.
+func decodeLZVN(out []byte, src []byte, size int) ([]byte, error) {
+	distance := 0
+	for len(src) > 0 && len(out) < size {
+		op := src[0]
+		literals, match, length := 0, 0, 1
+
+		switch {
+		case op == lzvnEndOfStream:
+			return out, nil
+		case op == 0x0e || op == 0x16:
+			// No operation.
+		case op >= 0xf0:
+			// A match with the previous distance.
+			match = int(op & 0x0f)
+			if op == 0xf0 {
+				if len(src) < 2 {
+					return nil, errLZVNCorrupt
+				}
+
+				match, length = int(src[1])+16, 2
+			}
+		case op >= 0xe0:
+			literals = int(op & 0x0f)
+			if op == 0xe0 {
+				if len(src) < 2 {
+					return nil, errLZVNCorrupt
+				}
+
+				literals, length = int(src[1])+16, 2
+			}
+		case op >= 0xa0 && op < 0xc0:
+			// A medium distance match.
+			if len(src) < 3 {
+				return nil, errLZVNCorrupt
+			}
+
+			operand := int(binary.LittleEndian.Uint16(src[1:]))
+			literals = int(op>>3) & 3
+			match = (int(op&7)<<2 | operand&3) + 3
+			distance = operand >> 2
+			length = 3
+		case (op >= 0x70 && op < 0x80) || (op >= 0xd0 && op < 0xe0) || op&7 == 6 && op < 0x40:
+			return nil, errLZVNCorrupt
+		default:
+			literals = int(op >> 6)
+			match = int(op>>3&7) + 3
+			switch op & 7 {
+			case 6:
+				// A match with the previous distance.
+			case 7:
+				if len(src) < 3 {
+					return nil, errLZVNCorrupt
+				}
+
+				distance = int(binary.LittleEndian.Uint16(src[1:]))
+				length = 3
+			default:
+				if len(src) < 2 {
+					return nil, errLZVNCorrupt
+				}
+
+				distance = int(op&7)<<8 | int(src[1])
+				length = 2
+			}
+		}
+
+		if len(src) < length+literals || len(out)+literals+match > size {
+			return nil, errLZVNCorrupt
+		}
+
+		out = append(out, src[length:length+literals]...)
+		src = src[length+literals:]
+
+		if match > 0 {
+			if distance <= 0 || distance > len(out) {
+				return nil, errLZVNCorrupt
+			}
+
+			out = copyMatch(out, distance, match)
+		}
+	}
+
+	return out, nil
+}
+
+// copyMatch appends length bytes copied from distance bytes back, which may overlap the bytes being appended.
+func copyMatch(out []byte, distance int, length int) []byte {
+	start := len(out) - distance
+	for i := 0; i < length; i++ {
+		out = append(out, out[start+i])
+	}
+
+	return out
+}
@@ -0,0 +1,143 @@
+package dmg
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// namedBlockTable is a "blkx" resource of the image's property list.
+type namedBlockTable struct {
+	name string
+	data []byte
+}
+
+// readBlockTables returns the block tables listed in the XML property list of an image.
+func readBlockTables(r io.Reader) ([]namedBlockTable, error) {
+	value, err := parsePlist(xml.NewDecoder(r))
+	if err != nil {
+		return nil, fmt.Errorf("invalid property list: %w", err)
+	}
+
+	root, _ := value.(map[string]interface{})
+	resources, _ := root["resource-fork"].(map[string]interface{})
+	entries, ok := resources["blkx"].([]interface{})
+	if !ok {
+		return nil, errors.New("property list has no block tables")
+	}
+
+	var tables []namedBlockTable
+	for _, entry := range entries {
+		resource, _ := entry.(map[string]interface{})
+		data, ok := resource["Data"].([]byte)
+		if !ok {
+			return nil, errors.New("block table has no data")
+		}
+
+		name, _ := resource["Name"].(string)
+		if name == "" {
+			name, _ = resource["CFName"].(string)
+		}
+
+		tables = append(tables, namedBlockTable{name: name, data: data})
+	}
+
+	return tables, nil
+}
+
+// parsePlist decodes the value of an XML property list. Dictionaries become maps, arrays slices, data byte
+// slices and everything else its text.
+func parsePlist(decoder *xml.Decoder) (interface{}, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local == "plist" {
+				continue
+			}
+
+			return parsePlistValue(decoder, start)
+		}
+	}
+}
+
+func parsePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := token.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+
+					continue
+				}
+
+				value, err := parsePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+
+				dict[key] = value
+			}
+		}
+	case "array":
+		var array []interface{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := token.(type) {
+			case xml.EndElement:
+				return array, nil
+			case xml.StartElement:
+				value, err := parsePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+
+				array = append(array, value)
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 0, 64)
+	default:
+		return text, nil
+	}
+}
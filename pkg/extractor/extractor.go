@@ -2,26 +2,33 @@ package extractor
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
-	"github.com/mholt/archiver/v3"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
 	"github.com/rocketblend/rocketblend/pkg/logger"
 	"github.com/rocketblend/rocketblend/pkg/types"
 )
 
+// StagingDirPrefix is the name prefix of the directories archives are extracted into before being moved into
+// the output path.
+const StagingDirPrefix = ".extracting-"
+
 type (
 	Options struct {
-		Cleanup bool
-		Logger  logger.Logger
+		Cleanup        bool
+		UpdateInterval time.Duration
+		Logger         logger.Logger
 	}
 
 	Option func(*Options)
 
 	Extractor struct {
-		cleanup bool
-		logger  logger.Logger
+		cleanup        bool
+		updateInterval time.Duration
+		logger         logger.Logger
 	}
 )
 
@@ -37,21 +44,33 @@ func WithCleanup() Option {
 	}
 }
 
+// WithUpdateInterval sets the frequency at which progress updates are sent. The default is 5 seconds.
+func WithUpdateInterval(updateInterval time.Duration) Option {
+	return func(o *Options) {
+		o.UpdateInterval = updateInterval
+	}
+}
+
 func New(opts ...Option) (*Extractor, error) {
 	options := &Options{
-		Cleanup: false,
-		Logger:  logger.NoOp(),
+		Cleanup:        false,
+		UpdateInterval: 5 * time.Second,
+		Logger:         logger.NoOp(),
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	options.Logger.Debug("initialising Extractor", map[string]interface{}{"cleanup": options.Cleanup})
+	options.Logger.Debug("initialising Extractor", map[string]interface{}{
+		"cleanup":        options.Cleanup,
+		"updateInterval": options.UpdateInterval,
+	})
 
 	return &Extractor{
-		cleanup: options.Cleanup,
-		logger:  options.Logger,
+		cleanup:        options.Cleanup,
+		updateInterval: options.UpdateInterval,
+		logger:         options.Logger,
 	}, nil
 }
 
@@ -67,16 +86,42 @@ func (e *Extractor) Extract(ctx context.Context, opts *types.ExtractOpts) error
 
 	e.logger.Info("extracting", logContext)
 
-	// mholt/archiver doesn't support .dmg files, so we need to handle them separately.
-	// This isn't a 100% golang solution, but it works for now.
-	var err error
-	switch strings.ToLower(filepath.Ext(opts.Path)) {
+	info, err := os.Stat(opts.Path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(opts.OutputPath, 0755); err != nil {
+		return err
+	}
+
+	// Staging directories left behind by interrupted extractions are never completed.
+	if err := removeStagingDirs(opts.OutputPath); err != nil {
+		return err
+	}
+
+	// Extract into a staging directory first, so an interrupted extraction never leaves files in the output.
+	stagingPath, err := os.MkdirTemp(opts.OutputPath, StagingDirPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingPath)
+
+	progress := newProgressReporter(ctx, opts.ProgressChan, e.updateInterval, info.Size())
+
+	// mholt/archiver doesn't support .dmg or .7z files, so we need to handle them separately.
+	switch helpers.ArchiveExtension(opts.Path) {
 	case ".dmg":
 		e.logger.Debug("extracting DMG file", logContext)
-		err = e.extractDMG(ctx, opts.Path, opts.OutputPath)
+		err = e.extractDMG(ctx, opts.Path, stagingPath, progress)
+	case ".7z":
+		e.logger.Debug("extracting 7z archive", logContext)
+		err = e.extractSevenZip(ctx, opts.Path, stagingPath, progress)
+	case "":
+		err = fmt.Errorf("unsupported archive format: %s", opts.Path)
 	default:
 		e.logger.Debug("extracting archive", logContext)
-		err = archiver.Unarchive(opts.Path, opts.OutputPath)
+		err = e.extractArchive(ctx, opts.Path, stagingPath, progress)
 	}
 	if err != nil {
 		logContext["error"] = err.Error()
@@ -84,6 +129,14 @@ func (e *Extractor) Extract(ctx context.Context, opts *types.ExtractOpts) error
 		return err
 	}
 
+	if err := e.moveEntries(stagingPath, opts.OutputPath); err != nil {
+		logContext["error"] = err.Error()
+		e.logger.Error("failed to move extracted files into place", logContext)
+		return err
+	}
+
+	progress.done()
+
 	if e.cleanup {
 		e.logger.Debug("cleaning up source file", logContext)
 		err = os.Remove(opts.Path)
@@ -98,3 +151,43 @@ func (e *Extractor) Extract(ctx context.Context, opts *types.ExtractOpts) error
 
 	return nil
 }
+
+// moveEntries renames each top-level entry of the staging directory into the output path, replacing any
+// entry of the same name left by an earlier extraction.
+func (e *Extractor) moveEntries(stagingPath string, outputPath string) error {
+	entries, err := os.ReadDir(stagingPath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		target := filepath.Join(outputPath, entry.Name())
+		if _, err := os.Lstat(target); err == nil {
+			e.logger.Debug("replacing existing extracted entry", map[string]interface{}{"path": target})
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		}
+
+		if err := os.Rename(filepath.Join(stagingPath, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeStagingDirs(outputPath string) error {
+	stagingPaths, err := filepath.Glob(filepath.Join(outputPath, StagingDirPrefix+"*"))
+	if err != nil {
+		return err
+	}
+
+	for _, stagingPath := range stagingPaths {
+		if err := os.RemoveAll(stagingPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,505 @@
+package extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+type tarEntry struct {
+	name     string
+	linkname string
+	typeflag byte
+	content  string
+}
+
+func writeTar(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Compress the archive according to its extension.
+	var compressed io.WriteCloser = nopWriteCloser{file}
+	switch name := strings.ToLower(path); {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		compressed = gzip.NewWriter(file)
+	case strings.HasSuffix(name, ".tar.zst"):
+		if compressed, err = zstd.NewWriter(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := tar.NewWriter(compressed)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Linkname: entry.linkname,
+			Typeflag: entry.typeflag,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+		}
+
+		if entry.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := writer.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		content := entry.content
+		switch entry.typeflag {
+		case tar.TypeDir:
+			header.SetMode(os.ModeDir | 0755)
+		case tar.TypeSymlink:
+			header.SetMode(os.ModeSymlink | 0777)
+			content = entry.linkname
+		default:
+			header.SetMode(0644)
+		}
+
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// writeSevenZip writes the entries to a 7z archive, stored without compression.
+func writeSevenZip(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+
+	number := func(b *bytes.Buffer, v uint64) {
+		if v < 0x80 {
+			b.WriteByte(byte(v))
+			return
+		}
+
+		b.WriteByte(0xff)
+		binary.Write(b, binary.LittleEndian, v)
+	}
+
+	bits := func(b *bytes.Buffer, values []bool) {
+		packed := make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if v {
+				packed[i/8] |= 0x80 >> (i % 8)
+			}
+		}
+
+		b.Write(packed)
+	}
+
+	property := func(b *bytes.Buffer, id byte, data []byte) {
+		b.WriteByte(id)
+		number(b, uint64(len(data)))
+		b.Write(data)
+	}
+
+	var packed, crcs bytes.Buffer
+	var sizes []uint64
+	var emptyStreams, emptyFiles []bool
+	var names, attributes bytes.Buffer
+	attributes.Write([]byte{1, 0})
+	for _, entry := range entries {
+		mode := uint32(0100644)
+		content := entry.content
+		switch entry.typeflag {
+		case tar.TypeDir:
+			mode = 040755
+		case tar.TypeSymlink:
+			mode, content = 0120777, entry.linkname
+		}
+
+		empty := content == ""
+		emptyStreams = append(emptyStreams, empty)
+		if empty {
+			emptyFiles = append(emptyFiles, entry.typeflag != tar.TypeDir)
+		} else {
+			sizes = append(sizes, uint64(len(content)))
+			packed.WriteString(content)
+			binary.Write(&crcs, binary.LittleEndian, crc32.ChecksumIEEE([]byte(content)))
+		}
+
+		for _, unit := range utf16.Encode([]rune(strings.TrimSuffix(entry.name, "/") + "\x00")) {
+			binary.Write(&names, binary.LittleEndian, unit)
+		}
+
+		attribute := mode<<16 | 0x8000
+		if entry.typeflag == tar.TypeDir {
+			attribute |= 0x10
+		}
+		binary.Write(&attributes, binary.LittleEndian, attribute)
+	}
+
+	var header bytes.Buffer
+	header.Write([]byte{0x01, 0x04})
+	header.Write([]byte{0x06, 0x00, 0x01, 0x09})
+	number(&header, uint64(packed.Len()))
+	header.Write([]byte{0x00, 0x07, 0x0b, 0x01, 0x00, 0x01, 0x01, 0x00, 0x0c})
+	number(&header, uint64(packed.Len()))
+	header.Write([]byte{0x00, 0x08, 0x0d})
+	number(&header, uint64(len(sizes)))
+	if len(sizes) > 1 {
+		header.WriteByte(0x09)
+		for _, size := range sizes[:len(sizes)-1] {
+			number(&header, size)
+		}
+	}
+	header.Write([]byte{0x0a, 0x01})
+	header.Write(crcs.Bytes())
+	header.Write([]byte{0x00, 0x00, 0x05})
+	number(&header, uint64(len(entries)))
+
+	var streams, files bytes.Buffer
+	bits(&streams, emptyStreams)
+	bits(&files, emptyFiles)
+	property(&header, 0x0e, streams.Bytes())
+	property(&header, 0x0f, files.Bytes())
+	property(&header, 0x11, append([]byte{0}, names.Bytes()...))
+	property(&header, 0x15, attributes.Bytes())
+	header.Write([]byte{0x00, 0x00})
+
+	start := make([]byte, 20)
+	binary.LittleEndian.PutUint64(start, uint64(packed.Len()))
+	binary.LittleEndian.PutUint64(start[8:], uint64(header.Len()))
+	binary.LittleEndian.PutUint32(start[16:], crc32.ChecksumIEEE(header.Bytes()))
+
+	var archive bytes.Buffer
+	archive.Write([]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c, 0, 4})
+	binary.Write(&archive, binary.LittleEndian, crc32.ChecksumIEEE(start))
+	archive.Write(start)
+	archive.Write(packed.Bytes())
+	archive.Write(header.Bytes())
+
+	if err := os.WriteFile(path, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			entries: []tarEntry{
+				{name: "blender/", typeflag: tar.TypeDir},
+				{name: "blender/blender", typeflag: tar.TypeReg, content: "binary"},
+				{name: "blender/current", linkname: "blender", typeflag: tar.TypeSymlink},
+			},
+		},
+		{
+			name: "path traversal",
+			entries: []tarEntry{
+				{name: "blender/blender", typeflag: tar.TypeReg, content: "binary"},
+				{name: "../evil", typeflag: tar.TypeReg, content: "evil"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "absolute link",
+			entries: []tarEntry{
+				{name: "blender/etc", linkname: "/etc", typeflag: tar.TypeSymlink},
+			},
+			wantErr: true,
+		},
+		{
+			name: "write through link",
+			entries: []tarEntry{
+				{name: "blender/up", linkname: ".", typeflag: tar.TypeSymlink},
+				{name: "blender/up/evil", typeflag: tar.TypeReg, content: "evil"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "escaping hard link",
+			entries: []tarEntry{
+				{name: "blender/passwd", linkname: "../../etc/passwd", typeflag: tar.TypeLink},
+			},
+			wantErr: true,
+		},
+	}
+
+	extractor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "build.tar")
+			writeTar(t, archivePath, tt.entries)
+
+			outputPath := filepath.Join(dir, "output")
+			err := extractor.Extract(context.Background(), &types.ExtractOpts{
+				Path:       archivePath,
+				OutputPath: outputPath,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+				t.Errorf("Extract() wrote outside of the output path")
+			}
+
+			entries, err := os.ReadDir(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Failed extractions leave nothing behind, not even their staging directory.
+			if tt.wantErr && len(entries) != 0 {
+				t.Errorf("Extract() left %d entries in the output path", len(entries))
+			}
+
+			if !tt.wantErr {
+				data, err := os.ReadFile(filepath.Join(outputPath, "blender", "blender"))
+				if err != nil || string(data) != "binary" {
+					t.Errorf("Extract() content = %q, %v, want %q", data, err, "binary")
+				}
+			}
+		})
+	}
+}
+
+func TestExtractFormats(t *testing.T) {
+	entries := []tarEntry{
+		{name: "blender/", typeflag: tar.TypeDir},
+		{name: "blender/blender", typeflag: tar.TypeReg, content: "binary"},
+		{name: "blender/current", linkname: "blender", typeflag: tar.TypeSymlink},
+	}
+
+	extractor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"build.tar.gz", "build.tgz", "build.tar.zst", "BUILD.TAR.GZ", "build.zip", "build.7z"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, name)
+			switch {
+			case strings.HasSuffix(name, ".7z"):
+				writeSevenZip(t, archivePath, entries)
+			case strings.HasSuffix(name, ".zip"):
+				writeZip(t, archivePath, entries)
+			default:
+				writeTar(t, archivePath, entries)
+			}
+
+			outputPath := filepath.Join(dir, "output")
+			if err := extractor.Extract(context.Background(), &types.ExtractOpts{
+				Path:       archivePath,
+				OutputPath: outputPath,
+			}); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(outputPath, "blender", "blender"))
+			if err != nil || string(data) != "binary" {
+				t.Errorf("Extract() content = %q, %v, want %q", data, err, "binary")
+			}
+
+			target, err := os.Readlink(filepath.Join(outputPath, "blender", "current"))
+			if err != nil || target != "blender" {
+				t.Errorf("Extract() link = %q, %v, want %q", target, err, "blender")
+			}
+		})
+	}
+}
+
+func TestExtractProgress(t *testing.T) {
+	entries := []tarEntry{
+		{name: "blender/", typeflag: tar.TypeDir},
+		{name: "blender/blender", typeflag: tar.TypeReg, content: strings.Repeat("binary", 10000)},
+		{name: "blender/current", linkname: "blender", typeflag: tar.TypeSymlink},
+	}
+
+	extractor, err := New(WithUpdateInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"build.tar.gz", "build.zip", "build.7z"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, name)
+			switch {
+			case strings.HasSuffix(name, ".7z"):
+				writeSevenZip(t, archivePath, entries)
+			case strings.HasSuffix(name, ".zip"):
+				writeZip(t, archivePath, entries)
+			default:
+				writeTar(t, archivePath, entries)
+			}
+
+			info, err := os.Stat(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			progress := make(chan types.ExtractProgress)
+			var updates []types.ExtractProgress
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for p := range progress {
+					updates = append(updates, p)
+				}
+			}()
+
+			err = extractor.Extract(context.Background(), &types.ExtractOpts{
+				Path:         archivePath,
+				OutputPath:   filepath.Join(dir, "output"),
+				ProgressChan: progress,
+			})
+			close(progress)
+			<-done
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			if len(updates) == 0 {
+				t.Fatal("Extract() sent no progress")
+			}
+
+			for i, p := range updates {
+				if p.Total != info.Size() || p.Current > p.Total || (i > 0 && (p.Current < updates[i-1].Current || p.Entries < updates[i-1].Entries)) {
+					t.Errorf("Extract() progress %d = %+v, want increasing up to %d bytes", i, p, info.Size())
+				}
+			}
+
+			last := updates[len(updates)-1]
+			if last.Current != info.Size() || last.Entries != int64(len(entries)) {
+				t.Errorf("Extract() final progress = %+v, want %d bytes and %d entries", last, info.Size(), len(entries))
+			}
+		})
+	}
+}
+
+func TestExtractSevenZipTraversal(t *testing.T) {
+	extractor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "build.7z")
+	writeSevenZip(t, archivePath, []tarEntry{
+		{name: "blender/etc", linkname: "../../etc", typeflag: tar.TypeSymlink},
+	})
+
+	if err := extractor.Extract(context.Background(), &types.ExtractOpts{
+		Path:       archivePath,
+		OutputPath: filepath.Join(dir, "output"),
+	}); err == nil || !strings.Contains(err.Error(), "illegal link target") {
+		t.Errorf("Extract() error = %v, want an illegal link target", err)
+	}
+}
+
+func TestExtractDMG(t *testing.T) {
+	extractor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, image := range []string{"hfs-zlib.dmg", "apfs-zlib.dmg"} {
+		t.Run(image, func(t *testing.T) {
+			outputPath := filepath.Join(t.TempDir(), "output")
+			err := extractor.Extract(context.Background(), &types.ExtractOpts{
+				Path:       filepath.Join("dmg", "testdata", image),
+				OutputPath: outputPath,
+			})
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			// Only the app is copied, not the link to /Applications next to it.
+			entries, err := os.ReadDir(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != 1 || entries[0].Name() != "Blender.app" {
+				t.Errorf("Extract() output = %v, want only Blender.app", entries)
+			}
+
+			info, err := os.Stat(filepath.Join(outputPath, "Blender.app", "Contents", "MacOS", "Blender"))
+			if err != nil || info.Mode().Perm() != 0755 {
+				t.Errorf("Extract() executable = %v, %v, want mode 0755", info, err)
+			}
+
+			target, err := os.Readlink(filepath.Join(outputPath, "Blender.app", "Contents", "Resources", "current"))
+			if err != nil || target != "4.2" {
+				t.Errorf("Extract() link = %q, %v, want %q", target, err, "4.2")
+			}
+
+			data, err := os.ReadFile(filepath.Join(outputPath, "Blender.app", "Contents", "Resources", "startup.py"))
+			if err != nil || string(data) != "import bpy\n" {
+				t.Errorf("Extract() hard link content = %q, %v", data, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+type (
+	// progressReporter counts what has been extracted and sends it at most once per interval.
+	progressReporter struct {
+		ctx      context.Context
+		progress chan<- types.ExtractProgress
+		interval time.Duration
+
+		mu       sync.Mutex
+		current  types.ExtractProgress
+		lastSent time.Time
+	}
+
+	// countingFile counts the bytes read from an archive.
+	countingFile struct {
+		file     *os.File
+		reporter *progressReporter
+	}
+)
+
+func newProgressReporter(ctx context.Context, progress chan<- types.ExtractProgress, interval time.Duration, total int64) *progressReporter {
+	return &progressReporter{
+		ctx:      ctx,
+		progress: progress,
+		interval: interval,
+		current:  types.ExtractProgress{Total: total},
+		lastSent: time.Now(),
+	}
+}
+
+// setTotal replaces the total when it isn't the size of the archive.
+func (p *progressReporter) setTotal(total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current.Total = total
+}
+
+// addBytes counts bytes processed, never beyond the total.
+func (p *progressReporter) addBytes(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current.Current = min(p.current.Current+n, p.current.Total)
+	p.sendLocked(false)
+}
+
+// addEntry counts an extracted entry.
+func (p *progressReporter) addEntry() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current.Entries++
+	p.sendLocked(false)
+}
+
+// done sends the final progress, with every byte processed.
+func (p *progressReporter) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current.Current = p.current.Total
+	p.sendLocked(true)
+}
+
+func (p *progressReporter) sendLocked(force bool) {
+	if p.progress == nil || (!force && time.Since(p.lastSent) < p.interval) {
+		return
+	}
+
+	p.lastSent = time.Now()
+	select {
+	case p.progress <- p.current:
+	case <-p.ctx.Done():
+	}
+}
+
+func (c *countingFile) Read(b []byte) (int, error) {
+	n, err := c.file.Read(b)
+	c.reporter.addBytes(int64(n))
+	return n, err
+}
+
+func (c *countingFile) ReadAt(b []byte, off int64) (int, error) {
+	n, err := c.file.ReadAt(b, off)
+	c.reporter.addBytes(int64(n))
+	return n, err
+}
+
+// countingReader counts the bytes of content copied out of a disk image.
+func countingReader(r io.Reader, reporter *progressReporter) io.Reader {
+	return readerFunc(func(b []byte) (int, error) {
+		n, err := r.Read(b)
+		reporter.addBytes(int64(n))
+		return n, err
+	})
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(b []byte) (int, error) { return f(b) }
@@ -0,0 +1,102 @@
+package farm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// The coordinator serves a small JSON API. Workers ask for the next chunk, report while rendering it and report
+// once it is done. A chunk that was handed to another worker in the meantime is answered with 409 Conflict, and
+// once every chunk is done the next chunk is answered with 410 Gone.
+const (
+	chunksPath       = "/chunks"
+	nextChunkPath    = "/chunks/next"
+	heartbeatPattern = "/chunks/{id}/heartbeat"
+	completePattern  = "/chunks/{id}/complete"
+)
+
+type (
+	nextChunkRequest struct {
+		Worker string `json:"worker"`
+	}
+
+	heartbeatRequest struct {
+		Worker string         `json:"worker"`
+		Events []eventMessage `json:"events,omitempty"`
+	}
+
+	completeRequest struct {
+		Worker string         `json:"worker"`
+		Error  string         `json:"error,omitempty"` // Empty if the chunk was rendered.
+		Events []eventMessage `json:"events,omitempty"`
+	}
+
+	// eventMessage is a Blender event sent by a worker, named by its type.
+	eventMessage struct {
+		Type  string          `json:"type"`
+		Event json.RawMessage `json:"event"`
+	}
+)
+
+// eventTypes are the Blender events workers send to the coordinator. Anything else stays with the worker.
+var eventTypes = registerEventTypes(
+	&types.ReadyEvent{},
+	&types.QuitEvent{},
+	&types.SavedFileEvent{},
+	&types.BlendFileSavedEvent{},
+	&types.RenderStartEvent{},
+	&types.RenderCompleteEvent{},
+	&types.RenderCancelEvent{},
+	&types.RenderingEvent{},
+	&types.SynchronizingEvent{},
+	&types.UpdatingEvent{},
+)
+
+func registerEventTypes(events ...types.BlenderEvent) map[string]reflect.Type {
+	registered := make(map[string]reflect.Type, len(events))
+	for _, event := range events {
+		t := reflect.TypeOf(event).Elem()
+		registered[t.Name()] = t
+	}
+
+	return registered
+}
+
+// encodeEvent returns the message for an event, or false if the event isn't sent to the coordinator.
+func encodeEvent(event types.BlenderEvent) (eventMessage, bool) {
+	t := reflect.TypeOf(event)
+	if t == nil || t.Kind() != reflect.Pointer || eventTypes[t.Elem().Name()] != t.Elem() {
+		return eventMessage{}, false
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return eventMessage{}, false
+	}
+
+	return eventMessage{
+		Type:  t.Elem().Name(),
+		Event: data,
+	}, true
+}
+
+func decodeEvent(message eventMessage) (types.BlenderEvent, error) {
+	t, ok := eventTypes[message.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type: %q", message.Type)
+	}
+
+	event := reflect.New(t).Interface()
+	if err := json.Unmarshal(message.Event, event); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", message.Type, err)
+	}
+
+	return event, nil
+}
+
+func (r *nextChunkRequest) workerName() string { return r.Worker }
+func (r *heartbeatRequest) workerName() string { return r.Worker }
+func (r *completeRequest) workerName() string  { return r.Worker }
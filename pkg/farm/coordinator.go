@@ -0,0 +1,319 @@
+package farm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type (
+	// Coordinator splits a frame range into chunks and hands them to workers over HTTP.
+	Coordinator struct {
+		logger           types.Logger
+		validator        types.Validator
+		heartbeatTimeout time.Duration
+		maxAttempts      int
+	}
+
+	// coordination is a single distributed render being coordinated.
+	coordination struct {
+		ctx               context.Context
+		logger            types.Logger
+		schedule          *schedule
+		opts              types.RenderOpts
+		heartbeatInterval time.Duration
+		eventChan         chan<- types.BlenderEvent
+	}
+)
+
+func NewCoordinator(opts ...Option) (*Coordinator, error) {
+	options := newOptions(opts...)
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	if options.HeartbeatTimeout <= 0 {
+		return nil, errors.New("heartbeat timeout must be greater than 0")
+	}
+
+	if options.MaxAttempts < 1 {
+		return nil, errors.New("max attempts must be at least 1")
+	}
+
+	return &Coordinator{
+		logger:           options.Logger,
+		validator:        options.Validator,
+		heartbeatTimeout: options.HeartbeatTimeout,
+		maxAttempts:      options.MaxAttempts,
+	}, nil
+}
+
+// Coordinate serves chunks to workers until every chunk is rendered or has failed, and every worker has been
+// told so or stopped asking. Chunks of workers that stop reporting are handed to another worker.
+func (c *Coordinator) Coordinate(ctx context.Context, opts *types.CoordinateOpts) (*types.CoordinateResult, error) {
+	if err := c.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	// Every chunk steps through its frames the same way the range was split.
+	renderOpts := opts.Opts
+	renderOpts.Step = opts.Step
+
+	co := &coordination{
+		ctx:               ctx,
+		logger:            c.logger,
+		schedule:          newSchedule(opts.Start, opts.End, opts.Step, opts.ChunkSize, c.maxAttempts),
+		opts:              renderOpts,
+		heartbeatInterval: c.heartbeatTimeout / 3,
+		eventChan:         opts.EventChan,
+	}
+
+	c.logger.Info("coordinating render", map[string]interface{}{
+		"address": opts.Listener.Addr().String(),
+		"start":   opts.Start,
+		"end":     opts.End,
+		"step":    opts.Step,
+		"chunks":  len(co.schedule.chunks),
+	})
+
+	server := &http.Server{
+		Handler: co.handler(),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(opts.Listener)
+	}()
+
+	ticker := time.NewTicker(co.heartbeatInterval)
+	defer ticker.Stop()
+
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			server.Close()
+			return nil, ctx.Err()
+		case err := <-serveErr:
+			return nil, err
+		case now := <-ticker.C:
+			for _, chunk := range co.schedule.expire(now, c.heartbeatTimeout) {
+				c.logger.Warn("worker stopped reporting", map[string]interface{}{
+					"chunk":  chunk.ID,
+					"worker": chunk.Worker,
+					"state":  chunk.State,
+				})
+
+				co.send(&types.ChunkEvent{Chunk: chunk})
+			}
+
+			done = co.schedule.drained(now, c.heartbeatTimeout)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return nil, err
+	}
+
+	chunks := co.schedule.snapshot()
+
+	failed := 0
+	for _, chunk := range chunks {
+		if chunk.State == types.ChunkStateFailed {
+			failed++
+		}
+	}
+
+	c.logger.Info("render coordinated", map[string]interface{}{
+		"chunks": len(chunks),
+		"failed": failed,
+	})
+
+	result := &types.CoordinateResult{
+		Chunks: chunks,
+	}
+
+	if failed > 0 {
+		return result, fmt.Errorf("%d of %d chunks failed", failed, len(chunks))
+	}
+
+	return result, nil
+}
+
+func (co *coordination) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+chunksPath, co.handleChunks)
+	mux.HandleFunc("POST "+nextChunkPath, co.handleNextChunk)
+	mux.HandleFunc("POST "+heartbeatPattern, co.handleHeartbeat)
+	mux.HandleFunc("POST "+completePattern, co.handleComplete)
+
+	return mux
+}
+
+func (co *coordination) handleChunks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, co.schedule.snapshot())
+}
+
+func (co *coordination) handleNextChunk(w http.ResponseWriter, r *http.Request) {
+	var request nextChunkRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	chunk, finished := co.schedule.next(request.Worker, time.Now())
+	if finished {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	if chunk == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	co.logger.Info("chunk assigned", map[string]interface{}{
+		"chunk":   chunk.ID,
+		"start":   chunk.Start,
+		"end":     chunk.End,
+		"worker":  chunk.Worker,
+		"attempt": chunk.Attempts,
+	})
+
+	co.send(&types.ChunkEvent{Chunk: *chunk})
+
+	writeJSON(w, &types.ChunkAssignment{
+		Chunk:             *chunk,
+		Opts:              co.opts,
+		HeartbeatInterval: co.heartbeatInterval,
+	})
+}
+
+func (co *coordination) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	id, ok := chunkID(w, r)
+	if !ok {
+		return
+	}
+
+	var request heartbeatRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	if err := co.schedule.heartbeat(id, request.Worker, time.Now()); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	co.forward(request.Worker, id, request.Events)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (co *coordination) handleComplete(w http.ResponseWriter, r *http.Request) {
+	id, ok := chunkID(w, r)
+	if !ok {
+		return
+	}
+
+	var request completeRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+
+	chunk, err := co.schedule.complete(id, request.Worker, request.Error, time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	co.forward(request.Worker, id, request.Events)
+
+	co.logger.Info("chunk finished", map[string]interface{}{
+		"chunk":  chunk.ID,
+		"worker": request.Worker,
+		"state":  chunk.State,
+		"error":  chunk.Error,
+	})
+
+	co.send(&types.ChunkEvent{Chunk: chunk})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forward sends the events reported by a worker on to the event channel.
+func (co *coordination) forward(worker string, id int, messages []eventMessage) {
+	for _, message := range messages {
+		event, err := decodeEvent(message)
+		if err != nil {
+			co.logger.Debug("ignoring worker event", map[string]interface{}{
+				"worker": worker,
+				"error":  err.Error(),
+			})
+
+			continue
+		}
+
+		co.send(&types.WorkerEvent{
+			Worker: worker,
+			Chunk:  id,
+			Event:  event,
+		})
+	}
+}
+
+func (co *coordination) send(event types.BlenderEvent) {
+	if co.eventChan == nil {
+		return
+	}
+
+	select {
+	case co.eventChan <- event:
+	case <-co.ctx.Done():
+	}
+}
+
+func chunkID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid chunk id", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{ workerName() string }) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return false
+	}
+
+	if v.workerName() == "" {
+		http.Error(w, "missing worker", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errChunkLost) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusNotFound)
+}
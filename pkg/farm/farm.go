@@ -0,0 +1,90 @@
+package farm
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/logger"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/rocketblend/rocketblend/pkg/validator"
+)
+
+type (
+	Options struct {
+		Logger    types.Logger
+		Validator types.Validator
+		Blender   types.Blender
+		Client    *http.Client
+
+		HeartbeatTimeout time.Duration
+		MaxAttempts      int
+		PollInterval     time.Duration
+	}
+
+	Option func(*Options)
+)
+
+func WithLogger(logger types.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithValidator(validator types.Validator) Option {
+	return func(o *Options) {
+		o.Validator = validator
+	}
+}
+
+// WithBlender sets what the worker renders chunks with.
+func WithBlender(blender types.Blender) Option {
+	return func(o *Options) {
+		o.Blender = blender
+	}
+}
+
+// WithHTTPClient sets the client the worker reaches the coordinator with. The default is http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.Client = client
+	}
+}
+
+// WithHeartbeatTimeout sets how long the coordinator waits for a worker to report before handing its chunk to
+// another worker. Workers are asked to report three times as often.
+func WithHeartbeatTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.HeartbeatTimeout = timeout
+	}
+}
+
+// WithMaxAttempts sets how many times the coordinator hands out a chunk before it is failed.
+func WithMaxAttempts(attempts int) Option {
+	return func(o *Options) {
+		o.MaxAttempts = attempts
+	}
+}
+
+// WithPollInterval sets how long the worker waits to ask again when every remaining chunk is being rendered.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.PollInterval = interval
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	options := &Options{
+		Logger:           logger.NoOp(),
+		Validator:        validator.New(),
+		Client:           http.DefaultClient,
+		HeartbeatTimeout: 30 * time.Second,
+		MaxAttempts:      3,
+		PollInterval:     2 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
@@ -0,0 +1,305 @@
+package farm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// fakeBlender renders by reporting events for every frame, recording the frames it rendered.
+type fakeBlender struct {
+	mu     sync.Mutex
+	frames map[int]int
+	delay  time.Duration
+}
+
+func (b *fakeBlender) Render(ctx context.Context, opts *types.RenderOpts) error {
+	for frame := opts.Start; frame <= opts.End; frame += opts.Step {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.delay):
+		}
+
+		opts.EventChan <- &types.RenderingEvent{RenderBase: types.RenderBase{Frame: frame}, Current: 1, Total: 1, Operation: "rendering"}
+		opts.EventChan <- &types.SavedFileEvent{Path: fmt.Sprintf("/output/%05d.png", frame), Frame: frame}
+
+		b.mu.Lock()
+		b.frames[frame]++
+		b.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (b *fakeBlender) Run(ctx context.Context, opts *types.RunOpts) error       { return nil }
+func (b *fakeBlender) Create(ctx context.Context, opts *types.CreateOpts) error { return nil }
+func (b *fakeBlender) Devices(ctx context.Context, opts *types.DevicesOpts) (*types.DevicesResult, error) {
+	return &types.DevicesResult{}, nil
+}
+
+func newTestCoordinator(t *testing.T, opts *types.CoordinateOpts) (string, <-chan *types.CoordinateResult, <-chan []types.BlenderEvent) {
+	t.Helper()
+
+	coordinator, err := NewCoordinator(WithHeartbeatTimeout(300*time.Millisecond), WithMaxAttempts(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventChan := make(chan types.BlenderEvent, 100)
+	opts.Listener = listener
+	opts.EventChan = eventChan
+
+	events := make(chan []types.BlenderEvent, 1)
+	go func() {
+		var received []types.BlenderEvent
+		for event := range eventChan {
+			received = append(received, event)
+		}
+
+		events <- received
+	}()
+
+	results := make(chan *types.CoordinateResult, 1)
+	go func() {
+		defer close(eventChan)
+
+		result, err := coordinator.Coordinate(context.Background(), opts)
+		if err != nil {
+			t.Errorf("Coordinate() returned unexpected error: %v", err)
+		}
+
+		results <- result
+	}()
+
+	return "http://" + listener.Addr().String(), results, events
+}
+
+func runWorkers(t *testing.T, url string, blender types.Blender, names ...string) {
+	t.Helper()
+
+	worker, err := NewWorker(WithBlender(blender), WithPollInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := worker.Work(context.Background(), &types.WorkOpts{
+				Name:        name,
+				Coordinator: url,
+				BlendFile: &types.BlendFile{
+					Path:         "shot.blend",
+					Dependencies: []*types.Installation{{Type: types.PackageBuild, Path: "blender"}},
+				},
+			}); err != nil {
+				t.Errorf("Work(%s) returned unexpected error: %v", name, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestCoordinateWorkers(t *testing.T) {
+	url, results, events := newTestCoordinator(t, &types.CoordinateOpts{
+		Start:     1,
+		End:       25,
+		Step:      1,
+		ChunkSize: 4,
+		Opts:      types.RenderOpts{Format: "PNG"},
+	})
+
+	blender := &fakeBlender{frames: make(map[int]int), delay: 5 * time.Millisecond}
+	runWorkers(t, url, blender, "worker-a", "worker-b", "worker-c")
+
+	result := <-results
+	if len(result.Chunks) != 7 {
+		t.Fatalf("got %d chunks, want 7", len(result.Chunks))
+	}
+
+	for _, chunk := range result.Chunks {
+		if chunk.State != types.ChunkStateComplete {
+			t.Errorf("chunk %d is %s, want complete", chunk.ID, chunk.State)
+		}
+	}
+
+	for frame := 1; frame <= 25; frame++ {
+		if blender.frames[frame] != 1 {
+			t.Errorf("frame %d rendered %d times, want once", frame, blender.frames[frame])
+		}
+	}
+
+	saved := make(map[int]bool)
+	for _, event := range <-events {
+		if workerEvent, ok := event.(*types.WorkerEvent); ok {
+			if savedEvent, ok := workerEvent.Event.(*types.SavedFileEvent); ok {
+				saved[savedEvent.Frame] = true
+			}
+		}
+	}
+
+	if len(saved) != 25 {
+		t.Errorf("coordinator received %d saved frames, want 25", len(saved))
+	}
+}
+
+func TestCoordinateReassignsStalledChunk(t *testing.T) {
+	url, results, _ := newTestCoordinator(t, &types.CoordinateOpts{
+		Start:     1,
+		End:       4,
+		Step:      1,
+		ChunkSize: 2,
+	})
+
+	// A worker takes the first chunk and is never heard from again.
+	resp, err := http.Post(url+nextChunkPath, "application/json", bytes.NewReader([]byte(`{"worker": "stalled"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("next chunk responded with %s", resp.Status)
+	}
+
+	blender := &fakeBlender{frames: make(map[int]int)}
+	runWorkers(t, url, blender, "worker-a")
+
+	result := <-results
+	first := result.Chunks[0]
+	if first.State != types.ChunkStateComplete || first.Worker != "worker-a" || first.Attempts != 2 {
+		t.Errorf("first chunk = %+v, want completed by worker-a on the second attempt", first)
+	}
+
+	if len(blender.frames) != 4 {
+		t.Errorf("rendered frames %v, want 1 to 4", blender.frames)
+	}
+}
+
+func TestScheduleChunks(t *testing.T) {
+	s := newSchedule(1, 20, 3, 3, 2)
+
+	var got [][2]int
+	for _, chunk := range s.snapshot() {
+		got = append(got, [2]int{chunk.Start, chunk.End})
+	}
+
+	want := [][2]int{{1, 7}, {10, 16}, {19, 19}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chunks = %v, want %v", got, want)
+	}
+}
+
+func TestScheduleRetriesFailedChunks(t *testing.T) {
+	s := newSchedule(1, 1, 1, 1, 2)
+	now := time.Now()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		chunk, finished := s.next("worker-a", now)
+		if chunk == nil || finished {
+			t.Fatalf("attempt %d: next() = %v, %t, want the chunk", attempt, chunk, finished)
+		}
+
+		if err := s.heartbeat(chunk.ID, "worker-b", now); err != errChunkLost {
+			t.Errorf("heartbeat() from another worker = %v, want %v", err, errChunkLost)
+		}
+
+		if _, err := s.complete(chunk.ID, "worker-a", "blender crashed", now); err != nil {
+			t.Fatalf("complete() returned unexpected error: %v", err)
+		}
+	}
+
+	chunks := s.snapshot()
+	if chunks[0].State != types.ChunkStateFailed || chunks[0].Error != "blender crashed" {
+		t.Errorf("chunk = %+v, want failed", chunks[0])
+	}
+
+	for _, worker := range []string{"worker-a", "worker-b"} {
+		if _, finished := s.next(worker, now); !finished {
+			t.Errorf("next() did not report the render finished")
+		}
+	}
+
+	if !s.drained(now, time.Second) {
+		t.Errorf("drained() = false after every worker was told no chunks are left")
+	}
+}
+
+func TestScheduleExpire(t *testing.T) {
+	s := newSchedule(1, 2, 1, 1, 3)
+	now := time.Now()
+
+	first, _ := s.next("worker-a", now)
+	second, _ := s.next("worker-b", now)
+	if err := s.heartbeat(second.ID, "worker-b", now.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := s.expire(now.Add(2*time.Second), time.Second)
+	if len(expired) != 1 || expired[0].ID != first.ID || expired[0].State != types.ChunkStatePending {
+		t.Fatalf("expire() = %+v, want only the first chunk back to pending", expired)
+	}
+
+	if err := s.heartbeat(first.ID, "worker-a", now.Add(2*time.Second)); err != errChunkLost {
+		t.Errorf("heartbeat() for an expired chunk = %v, want %v", err, errChunkLost)
+	}
+}
+
+func TestEventMessages(t *testing.T) {
+	events := []types.BlenderEvent{
+		&types.RenderingEvent{RenderBase: types.RenderBase{Frame: 3, Memory: "12m"}, Current: 5, Total: 64, Operation: "rendering"},
+		&types.SavedFileEvent{Path: "/output/00003.png", Frame: 3},
+		&types.QuitEvent{},
+	}
+
+	for _, event := range events {
+		message, ok := encodeEvent(event)
+		if !ok {
+			t.Errorf("encodeEvent(%#v) was not sent", event)
+			continue
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var received eventMessage
+		if err := json.Unmarshal(data, &received); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := decodeEvent(received)
+		if err != nil {
+			t.Errorf("decodeEvent(%s) returned unexpected error: %v", data, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, event) {
+			t.Errorf("decodeEvent(%s) = %#v, want %#v", data, got, event)
+		}
+	}
+
+	if _, ok := encodeEvent(fmt.Errorf("could not parse output")); ok {
+		t.Errorf("encodeEvent() sent an error")
+	}
+}
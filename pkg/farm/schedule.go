@@ -0,0 +1,211 @@
+package farm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// errChunkLost is returned for a chunk that is no longer rendered by the worker asking about it.
+var errChunkLost = errors.New("chunk is not assigned to worker")
+
+type (
+	// schedule tracks the chunks of a distributed render and the workers rendering them.
+	schedule struct {
+		mu          sync.Mutex
+		chunks      []*types.Chunk
+		heartbeats  map[int]time.Time
+		workers     map[string]*workerState
+		maxAttempts int
+	}
+
+	workerState struct {
+		lastSeen time.Time
+		released bool // Told that no chunks are left.
+	}
+)
+
+// newSchedule splits the frames from start to end into chunks of size frames.
+func newSchedule(start, end, step, size, maxAttempts int) *schedule {
+	var frames []int
+	for frame := start; frame <= end; frame += step {
+		frames = append(frames, frame)
+	}
+
+	s := &schedule{
+		heartbeats:  make(map[int]time.Time),
+		workers:     make(map[string]*workerState),
+		maxAttempts: maxAttempts,
+	}
+
+	for i := 0; i < len(frames); i += size {
+		s.chunks = append(s.chunks, &types.Chunk{
+			ID:    len(s.chunks) + 1,
+			Start: frames[i],
+			End:   frames[min(i+size, len(frames))-1],
+			State: types.ChunkStatePending,
+		})
+	}
+
+	return s
+}
+
+// next hands the first pending chunk to the worker. It returns no chunk if every remaining chunk is being
+// rendered, and reports whether every chunk is done.
+func (s *schedule) next(worker string, now time.Time) (*types.Chunk, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.seen(worker, now)
+	if s.finishedLocked() {
+		state.released = true
+		return nil, true
+	}
+
+	for _, chunk := range s.chunks {
+		if chunk.State != types.ChunkStatePending {
+			continue
+		}
+
+		chunk.State = types.ChunkStateRendering
+		chunk.Worker = worker
+		chunk.Attempts++
+		s.heartbeats[chunk.ID] = now
+
+		assigned := *chunk
+		return &assigned, false
+	}
+
+	return nil, false
+}
+
+// heartbeat records that the worker is still rendering the chunk.
+func (s *schedule) heartbeat(id int, worker string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen(worker, now)
+	if _, err := s.assignedLocked(id, worker); err != nil {
+		return err
+	}
+
+	s.heartbeats[id] = now
+	return nil
+}
+
+// complete records the outcome of rendering the chunk, handing it out again if it failed and has attempts left.
+func (s *schedule) complete(id int, worker string, message string, now time.Time) (types.Chunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen(worker, now)
+	chunk, err := s.assignedLocked(id, worker)
+	if err != nil {
+		return types.Chunk{}, err
+	}
+
+	delete(s.heartbeats, id)
+	if message == "" {
+		chunk.State = types.ChunkStateComplete
+		chunk.Error = ""
+	} else {
+		s.failLocked(chunk, message)
+	}
+
+	return *chunk, nil
+}
+
+// expire takes the chunks back from workers that haven't reported within the timeout.
+func (s *schedule) expire(now time.Time, timeout time.Duration) []types.Chunk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []types.Chunk
+	for _, chunk := range s.chunks {
+		if chunk.State != types.ChunkStateRendering || now.Sub(s.heartbeats[chunk.ID]) <= timeout {
+			continue
+		}
+
+		delete(s.heartbeats, chunk.ID)
+		s.failLocked(chunk, fmt.Sprintf("worker %s stopped reporting", chunk.Worker))
+		expired = append(expired, *chunk)
+	}
+
+	return expired
+}
+
+// drained reports whether every chunk is done and every worker was told so, or stopped asking.
+func (s *schedule) drained(now time.Time, timeout time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.finishedLocked() {
+		return false
+	}
+
+	for _, worker := range s.workers {
+		if !worker.released && now.Sub(worker.lastSeen) <= timeout {
+			return false
+		}
+	}
+
+	return true
+}
+
+// snapshot returns a copy of every chunk.
+func (s *schedule) snapshot() []types.Chunk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chunks := make([]types.Chunk, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		chunks = append(chunks, *chunk)
+	}
+
+	return chunks
+}
+
+func (s *schedule) seen(worker string, now time.Time) *workerState {
+	state, ok := s.workers[worker]
+	if !ok {
+		state = &workerState{}
+		s.workers[worker] = state
+	}
+
+	state.lastSeen = now
+	return state
+}
+
+func (s *schedule) assignedLocked(id int, worker string) (*types.Chunk, error) {
+	if id < 1 || id > len(s.chunks) {
+		return nil, fmt.Errorf("unknown chunk: %d", id)
+	}
+
+	chunk := s.chunks[id-1]
+	if chunk.State != types.ChunkStateRendering || chunk.Worker != worker {
+		return nil, errChunkLost
+	}
+
+	return chunk, nil
+}
+
+func (s *schedule) failLocked(chunk *types.Chunk, message string) {
+	chunk.Error = message
+	chunk.State = types.ChunkStatePending
+	if chunk.Attempts >= s.maxAttempts {
+		chunk.State = types.ChunkStateFailed
+	}
+}
+
+func (s *schedule) finishedLocked() bool {
+	for _, chunk := range s.chunks {
+		if chunk.State == types.ChunkStatePending || chunk.State == types.ChunkStateRendering {
+			return false
+		}
+	}
+
+	return true
+}
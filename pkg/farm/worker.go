@@ -0,0 +1,280 @@
+package farm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// maxRequestAttempts is how many times in a row the worker tries to reach the coordinator before giving up.
+const maxRequestAttempts = 3
+
+// errNoChunksLeft is returned once the coordinator has no chunks left to hand out.
+var errNoChunksLeft = errors.New("no chunks left")
+
+type (
+	// Worker renders chunks handed out by a coordinator.
+	Worker struct {
+		logger       types.Logger
+		validator    types.Validator
+		blender      types.Blender
+		client       *http.Client
+		pollInterval time.Duration
+	}
+)
+
+func NewWorker(opts ...Option) (*Worker, error) {
+	options := newOptions(opts...)
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	if options.Blender == nil {
+		return nil, errors.New("blender is nil")
+	}
+
+	if options.Client == nil {
+		return nil, errors.New("http client is nil")
+	}
+
+	return &Worker{
+		logger:       options.Logger,
+		validator:    options.Validator,
+		blender:      options.Blender,
+		client:       options.Client,
+		pollInterval: options.PollInterval,
+	}, nil
+}
+
+// Work asks the coordinator for chunks and renders them until it has none left.
+func (w *Worker) Work(ctx context.Context, opts *types.WorkOpts) error {
+	if err := w.validator.Validate(opts); err != nil {
+		return err
+	}
+
+	failures := 0
+	for {
+		assignment, err := w.nextChunk(ctx, opts)
+		if errors.Is(err, errNoChunksLeft) {
+			w.logger.Info("no chunks left", map[string]interface{}{"worker": opts.Name})
+			return nil
+		}
+
+		if err != nil {
+			if failures++; ctx.Err() != nil || failures >= maxRequestAttempts {
+				return err
+			}
+
+			w.logger.Warn("failed to reach coordinator", map[string]interface{}{
+				"worker": opts.Name,
+				"error":  err.Error(),
+			})
+		} else {
+			failures = 0
+		}
+
+		if assignment == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(w.pollInterval):
+			}
+
+			continue
+		}
+
+		if err := w.renderChunk(ctx, opts, assignment); err != nil {
+			return err
+		}
+	}
+}
+
+// renderChunk renders a chunk, reporting to the coordinator while it does. Rendering stops if the chunk is
+// handed to another worker. Only errors that stop the worker are returned, failed renders are reported.
+func (w *Worker) renderChunk(ctx context.Context, opts *types.WorkOpts, assignment *types.ChunkAssignment) error {
+	chunk := assignment.Chunk
+	w.logger.Info("rendering chunk", map[string]interface{}{
+		"worker": opts.Name,
+		"chunk":  chunk.ID,
+		"start":  chunk.Start,
+		"end":    chunk.End,
+	})
+
+	renderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	eventChan := make(chan types.BlenderEvent, 100)
+
+	renderOpts := assignment.Opts
+	renderOpts.Start = chunk.Start
+	renderOpts.End = chunk.End
+	if len(opts.Devices) > 0 {
+		renderOpts.Devices = opts.Devices
+	}
+
+	renderOpts.BlenderOpts = types.BlenderOpts{
+		Background: true,
+		BlendFile:  opts.BlendFile,
+		EventChan:  eventChan,
+	}
+
+	rendered := make(chan error, 1)
+	go func() {
+		rendered <- w.blender.Render(renderCtx, &renderOpts)
+	}()
+
+	interval := assignment.HeartbeatInterval
+	if interval <= 0 {
+		interval = w.pollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pending []eventMessage
+	collect := func(event types.BlenderEvent) {
+		w.forward(ctx, opts.EventChan, event)
+		if message, ok := encodeEvent(event); ok {
+			pending = append(pending, message)
+		}
+	}
+
+	lost := false
+	for {
+		select {
+		case event := <-eventChan:
+			collect(event)
+		case <-ticker.C:
+			if lost {
+				continue
+			}
+
+			err := w.post(ctx, fmt.Sprintf("%s/%d/heartbeat", chunksPath, chunk.ID), opts, &heartbeatRequest{
+				Worker: opts.Name,
+				Events: pending,
+			}, nil)
+			if errors.Is(err, errChunkLost) {
+				w.logger.Warn("chunk handed to another worker", map[string]interface{}{"worker": opts.Name, "chunk": chunk.ID})
+				lost = true
+				cancel()
+				continue
+			}
+
+			if err != nil {
+				w.logger.Warn("failed to report to coordinator", map[string]interface{}{"worker": opts.Name, "error": err.Error()})
+				continue
+			}
+
+			pending = nil
+		case err := <-rendered:
+			for drained := false; !drained; {
+				select {
+				case event := <-eventChan:
+					collect(event)
+				default:
+					drained = true
+				}
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if lost {
+				return nil
+			}
+
+			request := &completeRequest{
+				Worker: opts.Name,
+				Events: pending,
+			}
+
+			if err != nil {
+				w.logger.Warn("failed to render chunk", map[string]interface{}{"worker": opts.Name, "chunk": chunk.ID, "error": err.Error()})
+				request.Error = err.Error()
+			}
+
+			err = w.post(ctx, fmt.Sprintf("%s/%d/complete", chunksPath, chunk.ID), opts, request, nil)
+			if err != nil && !errors.Is(err, errChunkLost) {
+				return err
+			}
+
+			return nil
+		}
+	}
+}
+
+func (w *Worker) nextChunk(ctx context.Context, opts *types.WorkOpts) (*types.ChunkAssignment, error) {
+	var assignment types.ChunkAssignment
+	found := false
+	if err := w.post(ctx, nextChunkPath, opts, &nextChunkRequest{Worker: opts.Name}, func(body io.Reader) error {
+		found = true
+		return json.NewDecoder(body).Decode(&assignment)
+	}); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &assignment, nil
+}
+
+// post sends a request to the coordinator, decoding a response body with the given function.
+func (w *Worker) post(ctx context.Context, path string, opts *types.WorkOpts, request interface{}, decode func(io.Reader) error) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(opts.Coordinator, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if decode != nil {
+			return decode(resp.Body)
+		}
+
+		return nil
+	case http.StatusNoContent:
+		return nil
+	case http.StatusConflict:
+		return errChunkLost
+	case http.StatusGone:
+		return errNoChunksLeft
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("coordinator responded with %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+}
+
+func (w *Worker) forward(ctx context.Context, eventChan chan<- types.BlenderEvent, event types.BlenderEvent) {
+	if eventChan == nil {
+		return
+	}
+
+	select {
+	case eventChan <- event:
+	case <-ctx.Done():
+	}
+}
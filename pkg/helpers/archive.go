@@ -5,20 +5,25 @@ import (
 	"strings"
 )
 
-// SupportedArchiveExtensions is a list of supported archive extensions
-var SupportedArchiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tar.bz2", "tar.xz", ".dmg"}
+// SupportedArchiveExtensions is a list of supported archive extensions. Longer extensions come before the
+// shorter ones they end with.
+var SupportedArchiveExtensions = []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tgz", ".zip", ".tar", ".7z", ".dmg"}
 
-// IsSupportedArchive returns true if the file extension is a supported archive extension
-func IsSupportedArchive(filePath string) bool {
-	// get the file name from the path
-	_, fileName := filepath.Split(filePath)
+// ArchiveExtension returns the supported archive extension of the file in lower case, or an empty string if
+// it is not a supported archive.
+func ArchiveExtension(filePath string) string {
+	fileName := strings.ToLower(filepath.Base(filePath))
 
 	for _, extension := range SupportedArchiveExtensions {
-		// check if the file name ends with the extension
-		if strings.HasSuffix(fileName, extension) {
-			return true
+		if strings.HasSuffix(fileName, extension) && len(fileName) > len(extension) {
+			return extension
 		}
 	}
 
-	return false
+	return ""
+}
+
+// IsSupportedArchive returns true if the file extension is a supported archive extension
+func IsSupportedArchive(filePath string) bool {
+	return ArchiveExtension(filePath) != ""
 }
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,8 +25,16 @@ func Load[T any](validator types.Validator, filePath string) (*T, error) {
 		return nil, fmt.Errorf("failed to read file: %s", err)
 	}
 
+	return Decode[T](validator, f)
+}
+
+func Decode[T any](validator types.Validator, data []byte) (*T, error) {
+	if validator == nil {
+		return nil, errors.New("validator is required")
+	}
+
 	var result T
-	if err := json.Unmarshal(f, &result); err != nil {
+	if err := json.Unmarshal(data, &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal file: %s", err)
 	}
 
@@ -45,8 +54,12 @@ func Save[T any](validator types.Validator, filePath string, object *T, ensurePa
 		return fmt.Errorf("failed to validate object: %w", err)
 	}
 
-	bytes, err := json.MarshalIndent(object, "", "  ")
-	if err != nil {
+	// Keep version constraints such as ">=1.3 <2" readable in saved files.
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(object); err != nil {
 		return fmt.Errorf("failed to marshal object: %s", err)
 	}
 
@@ -62,7 +75,7 @@ func Save[T any](validator types.Validator, filePath string, object *T, ensurePa
 		}
 	}
 
-	if err := os.WriteFile(filePath, bytes, 0644); err != nil {
+	if err := os.WriteFile(filePath, bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), 0644); err != nil {
 		return fmt.Errorf("failed to write file: %s", err)
 	}
 
@@ -0,0 +1,42 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+)
+
+const DigestAlgorithmSHA256 = "sha256"
+
+// DigestBytes returns the sha256 digest of data in the form "sha256:<hex>".
+func DigestBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return DigestAlgorithmSHA256 + ":" + hex.EncodeToString(sum[:])
+}
+
+// DigestFile returns the sha256 digest of the file at path in the form "sha256:<hex>".
+func DigestFile(path string) (string, error) {
+	sum, err := HashFile(path, sha256.New())
+	if err != nil {
+		return "", err
+	}
+
+	return DigestAlgorithmSHA256 + ":" + sum, nil
+}
+
+// HashFile returns the hex encoded hash of the file at path.
+func HashFile(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
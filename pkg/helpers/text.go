@@ -3,6 +3,7 @@ package helpers
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -19,3 +20,50 @@ func PadWithZero(num int, padLength int) string {
 
 	return strings.Repeat("0", padLength-len(numStr)) + numStr // Pad and return
 }
+
+// ParseSize parses a size in bytes, such as "512", "10MB" or "1.5GiB". Units are case insensitive, decimal
+// units are powers of 1000 and binary units powers of 1024.
+func ParseSize(size string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40},
+		{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+		{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30}, {"t", 1 << 40},
+		{"b", 1},
+	}
+
+	value := strings.ToLower(strings.TrimSpace(size))
+	multiplier := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size: %q", size)
+	}
+
+	return int64(number * multiplier), nil
+}
+
+// FormatSize formats a size in bytes for display, using binary units.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,229 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+type (
+	// Git fetches libraries by cloning git repositories. Without a location, libraries are cloned from
+	// https://<library>.
+	Git struct {
+		logger    types.Logger
+		validator types.Validator
+		transport GitTransport
+		prefix    string
+		url       string
+		offline   bool
+	}
+)
+
+func NewGit(opts ...Option) (*Git, error) {
+	options := newOptions(opts...)
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	return &Git{
+		logger:    options.Logger,
+		validator: options.Validator,
+		transport: options.Transport,
+		prefix:    options.Prefix,
+		url:       options.URL,
+		offline:   options.Offline,
+	}, nil
+}
+
+// Sync clones the library if it does not exist locally and pulls the latest changes if an update is requested.
+func (g *Git) Sync(ctx context.Context, opts *types.SyncLibraryOpts) (*types.SyncLibraryResult, error) {
+	if err := g.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	result := &types.SyncLibraryResult{
+		Path: opts.Path,
+	}
+
+	if _, err := os.Stat(opts.Path); os.IsNotExist(err) {
+		if g.offline {
+			return nil, &types.OfflineError{References: []reference.Reference{opts.Reference}}
+		}
+
+		url := g.repoURL(opts.Library)
+		g.logger.Info("cloning repository", map[string]interface{}{"repoURL": url, "path": opts.Path, "reference": opts.Reference.String()})
+		auth, caBundle, proxy := g.transportOptions(url)
+		_, err := git.PlainCloneContext(ctx, opts.Path, false, &git.CloneOptions{
+			URL:          url,
+			Auth:         auth,
+			CABundle:     caBundle,
+			ProxyOptions: proxy,
+			// TODO: Fix this
+			// Progress: LoggerWriter{s.logger},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	if !opts.Update {
+		return result, nil
+	}
+
+	if err := g.pull(ctx, opts.Path, opts.Reference); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Revision returns the commit checked out in the library.
+func (g *Git) Revision(ctx context.Context, opts *types.LibraryRevisionOpts) (string, error) {
+	if err := g.validator.Validate(opts); err != nil {
+		return "", err
+	}
+
+	r, err := git.PlainOpen(opts.Path)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
+}
+
+// ReadFile reads a file as it was at the given commit, fetching from the remote if the commit is not
+// available locally.
+func (g *Git) ReadFile(ctx context.Context, opts *types.ReadLibraryFileOpts) ([]byte, error) {
+	if err := g.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	r, err := git.PlainOpen(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := plumbing.NewHash(opts.Revision)
+	c, err := r.CommitObject(hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		if g.offline {
+			return nil, &types.OfflineError{References: []reference.Reference{opts.Reference}}
+		}
+
+		g.logger.Info("fetching locked commit", map[string]interface{}{"path": opts.Path, "commit": opts.Revision, "reference": opts.Reference.String()})
+		if err := r.FetchContext(ctx, g.fetchOptions(r)); err != nil && err != git.NoErrAlreadyUpToDate {
+			return nil, err
+		}
+
+		c, err = r.CommitObject(hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return readCommitFile(c, opts.File)
+}
+
+// pull pulls the latest changes for the library. It does nothing while offline.
+func (g *Git) pull(ctx context.Context, repoPath string, ref reference.Reference) error {
+	if g.offline {
+		g.logger.Warn("offline, not pulling latest changes for repository", map[string]interface{}{"path": repoPath, "reference": ref.String()})
+		return nil
+	}
+
+	g.logger.Info("pulling latest changes for repository", map[string]interface{}{"path": repoPath, "reference": ref.String()})
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+
+	if err := r.FetchContext(ctx, g.fetchOptions(r)); err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	auth, caBundle, proxy := g.transportOptions(originURL(r))
+	if err := w.PullContext(ctx, &git.PullOptions{
+		Force:        true,
+		Auth:         auth,
+		CABundle:     caBundle,
+		ProxyOptions: proxy,
+		// Progress: LoggerWriter{s.logger},
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	return nil
+}
+
+func (g *Git) repoURL(library string) string {
+	if g.url == "" {
+		return "https://" + library
+	}
+
+	return location(g.prefix, g.url, library)
+}
+
+// transportOptions returns the credentials, certificate authorities and proxy for the repository URL.
+func (g *Git) transportOptions(url string) (transport.AuthMethod, []byte, transport.ProxyOptions) {
+	if g.transport == nil {
+		return nil, nil, transport.ProxyOptions{}
+	}
+
+	return g.transport.GitAuth(url), g.transport.CABundle(), g.transport.GitProxy()
+}
+
+func (g *Git) fetchOptions(r *git.Repository) *git.FetchOptions {
+	auth, caBundle, proxy := g.transportOptions(originURL(r))
+	return &git.FetchOptions{
+		Auth:         auth,
+		CABundle:     caBundle,
+		ProxyOptions: proxy,
+	}
+}
+
+// originURL returns the URL of the repository's origin remote, or an empty string if it has none.
+func originURL(r *git.Repository) string {
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+
+	return remote.Config().URLs[0]
+}
+
+func readCommitFile(c *object.Commit, filePath string) ([]byte, error) {
+	file, err := c.File(filePath)
+	if err != nil {
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, types.ErrFileNotFound
+		}
+
+		return nil, err
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(contents), nil
+}
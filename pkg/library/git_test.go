@@ -0,0 +1,112 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// commitFile writes a file to the repository and commits it, returning the commit hash.
+func commitFile(t *testing.T, r *git.Repository, dir string, name string, data string) string {
+	t.Helper()
+
+	filePath := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Add(name); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := w.Commit("update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash.String()
+}
+
+func TestGitSync(t *testing.T) {
+	root := t.TempDir()
+	originPath := filepath.Join(root, "packages")
+	origin, err := git.PlainInit(originPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packageFile := "builds/blender/4.2.2/" + types.PackageFileName
+	first := commitFile(t, origin, originPath, packageFile, `{"spec":"v1","type":"build"}`)
+
+	source, err := NewGit(WithLocation("studio.internal/pipeline", root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	libraryPath := filepath.Join(t.TempDir(), "studio.internal", "pipeline", "packages")
+	opts := &types.SyncLibraryOpts{
+		Reference: "studio.internal/pipeline/packages/builds/blender/4.2.2",
+		Library:   "studio.internal/pipeline/packages",
+		Path:      libraryPath,
+	}
+
+	result, err := source.Sync(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	if result.Path != libraryPath {
+		t.Errorf("Sync() path = %q, want %q", result.Path, libraryPath)
+	}
+
+	if revision, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: libraryPath}); err != nil || revision != first {
+		t.Errorf("Revision() = %q, %v, want %q", revision, err, first)
+	}
+
+	second := commitFile(t, origin, originPath, packageFile, `{"spec":"v1","type":"addon"}`)
+
+	// Without an update the clone stays where it is.
+	if _, err := source.Sync(context.Background(), opts); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	if revision, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: libraryPath}); err != nil || revision != first {
+		t.Errorf("Revision() = %q, %v, want %q before updating", revision, err, first)
+	}
+
+	opts.Update = true
+	if _, err := source.Sync(context.Background(), opts); err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	if revision, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: libraryPath}); err != nil || revision != second {
+		t.Errorf("Revision() = %q, %v, want %q after updating", revision, err, second)
+	}
+
+	data, err := source.ReadFile(context.Background(), &types.ReadLibraryFileOpts{
+		Reference: opts.Reference,
+		Path:      libraryPath,
+		Revision:  first,
+		File:      packageFile,
+	})
+	if err != nil || string(data) != `{"spec":"v1","type":"build"}` {
+		t.Errorf("ReadFile() = %s, %v, want the definition at the first commit", data, err)
+	}
+}
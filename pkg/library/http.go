@@ -0,0 +1,163 @@
+package library
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+type (
+	// HTTP fetches libraries from a static file server. The root of each library serves an index.json listing
+	// its packages, and every package serves its rocketpack.json and optional signature below the root.
+	HTTP struct {
+		logger    types.Logger
+		validator types.Validator
+		client    *http.Client
+		prefix    string
+		url       string
+		offline   bool
+		revisions revisions
+	}
+)
+
+func NewHTTP(opts ...Option) (*HTTP, error) {
+	options := newOptions(opts...)
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	if options.Client == nil {
+		return nil, errors.New("http client is nil")
+	}
+
+	if options.URL == "" {
+		return nil, errors.New("library URL is empty")
+	}
+
+	return &HTTP{
+		logger:    options.Logger,
+		validator: options.Validator,
+		client:    options.Client,
+		prefix:    options.Prefix,
+		url:       options.URL,
+		offline:   options.Offline,
+	}, nil
+}
+
+// Sync downloads every package definition listed in the library index if the library does not exist locally
+// or an update is requested.
+func (h *HTTP) Sync(ctx context.Context, opts *types.SyncLibraryOpts) (*types.SyncLibraryResult, error) {
+	if err := h.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	result := &types.SyncLibraryResult{
+		Path: opts.Path,
+	}
+
+	h.revisions.reset(opts.Path)
+
+	_, err := os.Stat(opts.Path)
+	missing := os.IsNotExist(err)
+	if !missing && !opts.Update {
+		return result, nil
+	}
+
+	if h.offline {
+		if missing {
+			return nil, &types.OfflineError{References: []reference.Reference{opts.Reference}}
+		}
+
+		h.logger.Warn("offline, not downloading latest library index", map[string]interface{}{"path": opts.Path, "reference": opts.Reference.String()})
+		return result, nil
+	}
+
+	url := location(h.prefix, h.url, opts.Library)
+	h.logger.Info("downloading library index", map[string]interface{}{"url": url, "path": opts.Path, "reference": opts.Reference.String()})
+
+	data, err := h.get(ctx, url+"/"+types.LibraryIndexFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var index types.LibraryIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid library index: %w", err)
+	}
+
+	if err := h.validator.Validate(&index); err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{types.LibraryIndexFileName: data}
+	for _, pack := range index.Packages {
+		packageFile := path.Join(pack, types.PackageFileName)
+		for _, file := range []string{packageFile, packageFile + types.SignatureFileExtension} {
+			data, err := h.get(ctx, url+"/"+file)
+			if errors.Is(err, types.ErrFileNotFound) && file != packageFile {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			files[file] = data
+		}
+	}
+
+	if err := replaceLibrary(opts.Path, files); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Revision returns a digest of the index and package definitions of the downloaded library, as of its last sync.
+func (h *HTTP) Revision(ctx context.Context, opts *types.LibraryRevisionOpts) (string, error) {
+	if err := h.validator.Validate(opts); err != nil {
+		return "", err
+	}
+
+	return h.revisions.get(ctx, opts.Path)
+}
+
+// ReadFile reads a file of the downloaded library if it is still at the revision. Static libraries have no
+// history, so earlier revisions cannot be read.
+func (h *HTTP) ReadFile(ctx context.Context, opts *types.ReadLibraryFileOpts) ([]byte, error) {
+	if err := h.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	return h.revisions.readFileAtRevision(ctx, opts)
+}
+
+func (h *HTTP) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", types.ErrFileNotFound, url)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,140 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestHTTPSync(t *testing.T) {
+	files := map[string]string{
+		"/packages/index.json":                           `{"packages":["builds/blender/4.2.2"]}`,
+		"/packages/builds/blender/4.2.2/rocketpack.json": `{"spec":"v1","type":"build"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	source, err := NewHTTP(WithLocation("studio.internal/pipeline", server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	libraryPath := filepath.Join(t.TempDir(), "studio.internal", "pipeline", "packages")
+	result, err := source.Sync(context.Background(), &types.SyncLibraryOpts{
+		Reference: reference.Reference("studio.internal/pipeline/packages/builds/blender/4.2.2"),
+		Library:   "studio.internal/pipeline/packages",
+		Path:      libraryPath,
+	})
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	if result.Path != libraryPath {
+		t.Errorf("Sync() path = %q, want %q", result.Path, libraryPath)
+	}
+
+	data, err := os.ReadFile(filepath.Join(libraryPath, "builds", "blender", "4.2.2", types.PackageFileName))
+	if err != nil {
+		t.Fatalf("package definition was not synced: %v", err)
+	}
+
+	if string(data) != files["/packages/builds/blender/4.2.2/rocketpack.json"] {
+		t.Errorf("package definition = %s, want %s", data, files["/packages/builds/blender/4.2.2/rocketpack.json"])
+	}
+
+	if _, err := os.Stat(filepath.Join(libraryPath, "builds", "blender", "4.2.2", types.PackageFileName+types.SignatureFileExtension)); !os.IsNotExist(err) {
+		t.Errorf("unexpected signature file, stat error = %v", err)
+	}
+}
+
+func TestHTTPRevision(t *testing.T) {
+	libraryPath := t.TempDir()
+	packagePath := filepath.Join(libraryPath, "builds", "blender", "4.2.2")
+	if err := os.MkdirAll(packagePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(packagePath, types.PackageFileName), []byte(`{"spec":"v1","type":"build"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewHTTP(WithLocation("studio.internal/pipeline", "https://studio.internal/libraries"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revision, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: libraryPath})
+	if err != nil {
+		t.Fatalf("Revision() unexpected error: %v", err)
+	}
+
+	if revision == "" {
+		t.Fatal("Revision() returned an empty revision")
+	}
+
+	data, err := source.ReadFile(context.Background(), &types.ReadLibraryFileOpts{
+		Reference: "studio.internal/pipeline/packages/builds/blender/4.2.2",
+		Path:      libraryPath,
+		Revision:  revision,
+		File:      "builds/blender/4.2.2/" + types.PackageFileName,
+	})
+	if err != nil || string(data) != `{"spec":"v1","type":"build"}` {
+		t.Errorf("ReadFile() = %s, %v, want the package definition", data, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(packagePath, types.PackageFileName), []byte(`{"spec":"v1","type":"addon"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The revision is only computed again once the library is synced.
+	if _, err := source.ReadFile(context.Background(), &types.ReadLibraryFileOpts{
+		Reference: "studio.internal/pipeline/packages/builds/blender/4.2.2",
+		Path:      libraryPath,
+		Revision:  revision,
+		File:      "builds/blender/4.2.2/" + types.PackageFileName,
+	}); err != nil {
+		t.Errorf("ReadFile() before syncing returned unexpected error: %v", err)
+	}
+
+	if _, err := source.Sync(context.Background(), &types.SyncLibraryOpts{
+		Reference: "studio.internal/pipeline/packages/builds/blender/4.2.2",
+		Library:   "studio.internal/pipeline/packages",
+		Path:      libraryPath,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: libraryPath})
+	if err != nil {
+		t.Fatalf("Revision() unexpected error: %v", err)
+	}
+
+	if changed == revision {
+		t.Errorf("Revision() = %q after the package changed, want a new revision", changed)
+	}
+
+	if _, err := source.ReadFile(context.Background(), &types.ReadLibraryFileOpts{
+		Reference: "studio.internal/pipeline/packages/builds/blender/4.2.2",
+		Path:      libraryPath,
+		Revision:  revision,
+		File:      "builds/blender/4.2.2/" + types.PackageFileName,
+	}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ReadFile() at an earlier revision error = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
@@ -0,0 +1,298 @@
+package library
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rocketblend/rocketblend/pkg/logger"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/rocketblend/rocketblend/pkg/validator"
+)
+
+type (
+	Options struct {
+		Logger    types.Logger
+		Validator types.Validator
+		Client    *http.Client
+		Transport GitTransport
+
+		Prefix  string
+		URL     string
+		Offline bool
+	}
+
+	Option func(*Options)
+
+	// GitTransport supplies the credentials, certificate authorities and proxy used to reach git remotes.
+	GitTransport interface {
+		GitAuth(url string) transport.AuthMethod
+		CABundle() []byte
+		GitProxy() transport.ProxyOptions
+	}
+
+	// revisions remembers the content revision of each library without history, so that it is computed once
+	// per sync instead of on every read.
+	revisions struct {
+		mu        sync.Mutex
+		revisions map[string]string
+	}
+)
+
+func WithLogger(logger types.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithValidator(validator types.Validator) Option {
+	return func(o *Options) {
+		o.Validator = validator
+	}
+}
+
+// WithHTTPClient sets the client used by the HTTP source. The default is http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Options) {
+		o.Client = client
+	}
+}
+
+// WithGitTransport sets how the git source reaches remotes. The default is anonymous access with the system
+// certificate authorities and the proxy from the environment.
+func WithGitTransport(transport GitTransport) Option {
+	return func(o *Options) {
+		o.Transport = transport
+	}
+}
+
+// WithLocation serves the libraries under the reference prefix from the given URL or directory. Libraries
+// below the prefix are found at the same relative path below the URL.
+func WithLocation(prefix string, url string) Option {
+	return func(o *Options) {
+		o.Prefix = prefix
+		o.URL = url
+	}
+}
+
+// WithOffline prevents the source from accessing the network.
+func WithOffline(offline bool) Option {
+	return func(o *Options) {
+		o.Offline = offline
+	}
+}
+
+// New creates the package source for the configured type.
+func New(source *types.PackageSourceConfig, opts ...Option) (types.PackageSource, error) {
+	switch source.Type {
+	case types.PackageSourceGit:
+		return NewGit(opts...)
+	case types.PackageSourceHTTP:
+		return NewHTTP(opts...)
+	case types.PackageSourceLocal:
+		return NewLocal(opts...)
+	default:
+		return nil, fmt.Errorf("unknown library source type: %q", source.Type)
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	options := &Options{
+		Logger:    logger.NoOp(),
+		Validator: validator.New(),
+		Client:    http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// location returns where a library is found relative to the configured prefix and URL. The prefix is matched
+// regardless of case.
+func location(prefix string, url string, library string) string {
+	rest := library
+	if len(library) >= len(prefix) && strings.EqualFold(library[:len(prefix)], prefix) {
+		rest = library[len(prefix):]
+	}
+
+	rest = strings.Trim(rest, "/")
+	if rest == "" {
+		return url
+	}
+
+	return strings.TrimSuffix(url, "/") + "/" + rest
+}
+
+// contentRevision identifies the state of a library without history by a digest of its index and package
+// definitions, and their paths.
+func contentRevision(ctx context.Context, libraryPath string) (string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(libraryPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if filePath != libraryPath && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !isLibraryFile(entry.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(libraryPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(rel)] = filePath
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		data, err := os.ReadFile(files[name])
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(data))
+		hash.Write(data)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// get returns the content revision of a library, computing it if it is not known since the last sync.
+func (r *revisions) get(ctx context.Context, libraryPath string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if revision, ok := r.revisions[libraryPath]; ok {
+		return revision, nil
+	}
+
+	revision, err := contentRevision(ctx, libraryPath)
+	if err != nil {
+		return "", err
+	}
+
+	if r.revisions == nil {
+		r.revisions = make(map[string]string)
+	}
+
+	r.revisions[libraryPath] = revision
+	return revision, nil
+}
+
+// reset forgets the revision of a library, so it is computed again on its next use.
+func (r *revisions) reset(libraryPath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.revisions, libraryPath)
+}
+
+// readFileAtRevision reads a file of a library without history, as long as the library is still at the
+// revision. Otherwise the revision cannot be read and errors.ErrUnsupported is returned.
+func (r *revisions) readFileAtRevision(ctx context.Context, opts *types.ReadLibraryFileOpts) ([]byte, error) {
+	revision, err := r.get(ctx, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if revision != opts.Revision {
+		return nil, fmt.Errorf("%w: library changed since revision %s", errors.ErrUnsupported, opts.Revision)
+	}
+
+	if !filepath.IsLocal(filepath.FromSlash(opts.File)) {
+		return nil, fmt.Errorf("invalid library file path: %q", opts.File)
+	}
+
+	file, err := os.Open(filepath.Join(opts.Path, filepath.FromSlash(opts.File)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, types.ErrFileNotFound
+		}
+
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// isLibraryFile reports whether a file is part of a library's content, as opposed to files that happen to be
+// stored next to it.
+func isLibraryFile(name string) bool {
+	return name == types.LibraryIndexFileName || name == types.PackageFileName || name == types.PackageFileName+types.SignatureFileExtension
+}
+
+// replaceLibrary writes the files of a library to a staging directory and swaps it in place of the
+// existing library, so a failed sync never leaves a partial library behind.
+func replaceLibrary(libraryPath string, files map[string][]byte) error {
+	stagingPath := libraryPath + ".sync"
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return err
+	}
+
+	for name, data := range files {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("invalid library file path: %q", name)
+		}
+
+		filePath := filepath.Join(stagingPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(stagingPath, 0755); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(libraryPath); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(libraryPath), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(stagingPath, libraryPath)
+}
@@ -0,0 +1,91 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+type (
+	// Local reads libraries where they are in a directory, such as a network share, so they can be used
+	// without a git server. Changes to the directory are seen right away, and change its revision once the
+	// library is synced again.
+	Local struct {
+		logger    types.Logger
+		validator types.Validator
+		prefix    string
+		dir       string
+		revisions revisions
+	}
+)
+
+func NewLocal(opts ...Option) (*Local, error) {
+	options := newOptions(opts...)
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	if options.URL == "" {
+		return nil, errors.New("library directory is empty")
+	}
+
+	return &Local{
+		logger:    options.Logger,
+		validator: options.Validator,
+		prefix:    options.Prefix,
+		dir:       strings.TrimPrefix(options.URL, "file://"),
+	}, nil
+}
+
+// Sync returns the library's directory, which is read in place instead of the requested path.
+func (l *Local) Sync(ctx context.Context, opts *types.SyncLibraryOpts) (*types.SyncLibraryResult, error) {
+	if err := l.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.FromSlash(location(l.prefix, filepath.ToSlash(l.dir), opts.Library))
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: library directory %s", types.ErrFileNotFound, dir)
+		}
+
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("library %s is not a directory", dir)
+	}
+
+	l.revisions.reset(dir)
+
+	l.logger.Debug("using library directory", map[string]interface{}{"dir": dir, "reference": opts.Reference.String()})
+
+	return &types.SyncLibraryResult{
+		Path: dir,
+	}, nil
+}
+
+// Revision returns a digest of the package definitions in the library directory, as of its last sync.
+func (l *Local) Revision(ctx context.Context, opts *types.LibraryRevisionOpts) (string, error) {
+	if err := l.validator.Validate(opts); err != nil {
+		return "", err
+	}
+
+	return l.revisions.get(ctx, opts.Path)
+}
+
+// ReadFile reads a file of the library directory if it is still at the revision. Library directories have no
+// history, so earlier revisions cannot be read.
+func (l *Local) ReadFile(ctx context.Context, opts *types.ReadLibraryFileOpts) ([]byte, error) {
+	if err := l.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	return l.revisions.readFileAtRevision(ctx, opts)
+}
@@ -0,0 +1,83 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestLocalSync(t *testing.T) {
+	root := t.TempDir()
+	packagePath := filepath.Join(root, "packages", "builds", "blender", "4.2.2")
+	if err := os.MkdirAll(packagePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(packagePath, types.PackageFileName), []byte(`{"spec":"v1","type":"build"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Configured prefixes are lower case, references keep theirs.
+	source, err := NewLocal(WithLocation("studio.internal/pipeline", "file://"+root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	libraryPath := filepath.Join(t.TempDir(), "Studio.Internal", "Pipeline", "packages")
+	opts := &types.SyncLibraryOpts{
+		Reference: "Studio.Internal/Pipeline/packages/builds/blender/4.2.2",
+		Library:   "Studio.Internal/Pipeline/packages",
+		Path:      libraryPath,
+	}
+
+	result, err := source.Sync(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Sync() unexpected error: %v", err)
+	}
+
+	if result.Path != filepath.Join(root, "packages") {
+		t.Errorf("Sync() path = %q, want the library directory %q", result.Path, filepath.Join(root, "packages"))
+	}
+
+	if _, err := os.Stat(libraryPath); !os.IsNotExist(err) {
+		t.Errorf("Sync() copied the library to %s, stat error = %v", libraryPath, err)
+	}
+
+	revision, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: result.Path})
+	if err != nil {
+		t.Fatalf("Revision() unexpected error: %v", err)
+	}
+
+	// Files next to the packages are not part of the library.
+	if err := os.WriteFile(filepath.Join(packagePath, "notes.txt"), []byte("draft"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := source.Sync(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if unchanged, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: result.Path}); err != nil || unchanged != revision {
+		t.Errorf("Revision() = %q, %v, want %q", unchanged, err, revision)
+	}
+
+	if err := os.WriteFile(filepath.Join(packagePath, types.PackageFileName+types.SignatureFileExtension), []byte("signature"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := source.Sync(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := source.Revision(context.Background(), &types.LibraryRevisionOpts{Path: result.Path}); err != nil || changed == revision {
+		t.Errorf("Revision() = %q, %v, want a new revision once the library changed", changed, err)
+	}
+
+	opts.Library = "Studio.Internal/Pipeline/missing"
+	if _, err := source.Sync(context.Background(), opts); err == nil {
+		t.Error("Sync() of a missing library directory did not return an error")
+	}
+}
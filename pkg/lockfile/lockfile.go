@@ -20,6 +20,7 @@ type Locker struct {
 	lockFilePath string
 	lockFile     *os.File
 	ticker       *time.Ticker
+	released     chan struct{} // Closed once the lock file is removed.
 	mutex        sync.Mutex
 }
 
@@ -57,6 +58,7 @@ func New(ctx context.Context, opts ...Option) (cancelFunc func(), err error) {
 	locker := &Locker{
 		logger:       options.Logger,
 		lockFilePath: options.Path,
+		released:     make(chan struct{}),
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -65,12 +67,28 @@ func New(ctx context.Context, opts ...Option) (cancelFunc func(), err error) {
 		return nil, err
 	}
 
-	// Return a function to cancel the context and release the lock.
+	// Return a function to cancel the context and release the lock, so that it can be taken again right away.
 	return func() {
 		cancel()
+		<-locker.released
 	}, nil
 }
 
+// IsLocked reports whether another process currently holds the lock at the path. Stale locks that have
+// not been refreshed within the execution timeout are not considered held.
+func IsLocked(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return time.Since(info.ModTime()) <= ExecutionTimeout, nil
+}
+
 // TODO: Look into using flock instead of a lock file.
 func (l *Locker) lock(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
@@ -128,6 +146,7 @@ func (l *Locker) manageLock(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			l.unlock()
+			close(l.released)
 			return
 		case <-l.ticker.C:
 			// Touch the lock file to update its modified time
@@ -0,0 +1,299 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/logger"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/rocketblend/rocketblend/pkg/validator"
+)
+
+// gitTokenUsername is sent with a token when its credential names no username. Git hosts ignore it, but
+// require one to be set.
+const gitTokenUsername = "x-access-token"
+
+type (
+	Options struct {
+		Logger          types.Logger
+		Validator       types.Validator
+		Proxy           string
+		CABundle        string
+		CredentialsFile string
+	}
+
+	Option func(*Options)
+
+	// credential is a resolved credential, with secrets read from the environment.
+	credential struct {
+		token    string
+		username string
+		password string
+	}
+
+	// Network provides the HTTP client and git transport settings for the configured proxy, certificate
+	// authorities and credentials.
+	Network struct {
+		logger      types.Logger
+		proxy       *url.URL
+		caBundle    []byte
+		credentials map[string]*credential
+		client      *http.Client
+	}
+
+	// authTransport adds the credentials of the request's host to requests that carry none.
+	authTransport struct {
+		network *Network
+		base    http.RoundTripper
+	}
+)
+
+func WithLogger(logger types.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithValidator(validator types.Validator) Option {
+	return func(o *Options) {
+		o.Validator = validator
+	}
+}
+
+// WithProxy sends every request through the proxy URL. The default is the proxy from the environment.
+func WithProxy(proxy string) Option {
+	return func(o *Options) {
+		o.Proxy = proxy
+	}
+}
+
+// WithCABundle trusts the certificate authorities in the PEM file in addition to the system ones.
+func WithCABundle(path string) Option {
+	return func(o *Options) {
+		o.CABundle = path
+	}
+}
+
+// WithCredentialsFile reads per host credentials from the file. A missing file means no credentials.
+func WithCredentialsFile(path string) Option {
+	return func(o *Options) {
+		o.CredentialsFile = path
+	}
+}
+
+func New(opts ...Option) (*Network, error) {
+	options := &Options{
+		Logger:    logger.NoOp(),
+		Validator: validator.New(),
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	network := &Network{
+		logger:      options.Logger,
+		credentials: make(map[string]*credential),
+	}
+
+	if options.Proxy != "" {
+		proxy, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+
+		network.proxy = proxy
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if network.proxy != nil {
+		transport.Proxy = http.ProxyURL(network.proxy)
+	}
+
+	if options.CABundle != "" {
+		caBundle, err := os.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle: %s", options.CABundle)
+		}
+
+		network.caBundle = caBundle
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if options.CredentialsFile != "" {
+		if err := network.loadCredentials(options.Validator, options.CredentialsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	network.client = &http.Client{
+		Transport: &authTransport{
+			network: network,
+			base:    transport,
+		},
+	}
+
+	options.Logger.Debug("initialising network", map[string]interface{}{
+		"proxy":       network.proxy != nil,
+		"caBundle":    options.CABundle,
+		"credentials": len(network.credentials),
+	})
+
+	return network, nil
+}
+
+// Client returns the HTTP client for downloads and package sources.
+func (n *Network) Client() *http.Client {
+	return n.client
+}
+
+// GitAuth returns the credentials for cloning or pulling the repository URL, or nil if there are none.
+func (n *Network) GitAuth(repoURL string) transport.AuthMethod {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil
+	}
+
+	cred := n.credential(u)
+	switch {
+	case cred == nil:
+		return nil
+	case cred.token != "":
+		// Git hosts expect tokens as the password of basic auth, not as a bearer token.
+		username := cred.username
+		if username == "" {
+			username = gitTokenUsername
+		}
+
+		return &githttp.BasicAuth{Username: username, Password: cred.token}
+	default:
+		return &githttp.BasicAuth{Username: cred.username, Password: cred.password}
+	}
+}
+
+// CABundle returns the extra certificate authorities in PEM format, or nil if there are none.
+func (n *Network) CABundle() []byte {
+	return n.caBundle
+}
+
+// GitProxy returns the proxy settings for git. Without a configured proxy git uses the environment.
+func (n *Network) GitProxy() transport.ProxyOptions {
+	if n.proxy == nil {
+		return transport.ProxyOptions{}
+	}
+
+	proxy := *n.proxy
+	proxy.User = nil
+
+	password, _ := n.proxy.User.Password()
+	return transport.ProxyOptions{
+		URL:      proxy.String(),
+		Username: n.proxy.User.Username(),
+		Password: password,
+	}
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	cred := t.network.credential(req.URL)
+	if cred == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it was given.
+	req = req.Clone(req.Context())
+	if cred.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cred.token)
+	} else {
+		req.SetBasicAuth(cred.username, cred.password)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// credential returns the credential for the URL's host, preferring one that names the port.
+func (n *Network) credential(u *url.URL) *credential {
+	if cred, ok := n.credentials[strings.ToLower(u.Host)]; ok {
+		return cred
+	}
+
+	return n.credentials[strings.ToLower(u.Hostname())]
+}
+
+func (n *Network) loadCredentials(validator types.Validator, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	credentials, err := helpers.Load[types.Credentials](validator, path)
+	if err != nil {
+		return fmt.Errorf("failed to load credentials: %w", err)
+	}
+
+	for host, cred := range credentials.Hosts {
+		// A missing secret only matters for the host it belongs to, so the others are still usable.
+		resolved, err := resolveCredential(cred)
+		if err != nil {
+			n.logger.Warn("skipping credentials", map[string]interface{}{"host": host, "error": err})
+			continue
+		}
+
+		n.credentials[strings.ToLower(host)] = resolved
+	}
+
+	return nil
+}
+
+// resolveCredential reads any secrets named by environment variable.
+func resolveCredential(cred *types.Credential) (*credential, error) {
+	resolved := &credential{
+		token:    cred.Token,
+		username: cred.Username,
+		password: cred.Password,
+	}
+
+	if cred.TokenEnv != "" {
+		resolved.token = os.Getenv(cred.TokenEnv)
+		if resolved.token == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", cred.TokenEnv)
+		}
+	}
+
+	if cred.PasswordEnv != "" {
+		resolved.password = os.Getenv(cred.PasswordEnv)
+		if resolved.password == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", cred.PasswordEnv)
+		}
+	}
+
+	if resolved.token == "" && resolved.username == "" {
+		return nil, errors.New("either a token or a username is required")
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,144 @@
+package network
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestClientCredentials(t *testing.T) {
+	var authorization string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	})
+
+	tokenServer := httptest.NewServer(handler)
+	defer tokenServer.Close()
+
+	basicServer := httptest.NewServer(handler)
+	defer basicServer.Close()
+
+	tokenURL, err := url.Parse(tokenServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ROCKETBLEND_TEST_TOKEN", "secret")
+
+	// The host with a port takes precedence over the bare host name.
+	data, err := json.Marshal(&types.Credentials{
+		Hosts: map[string]*types.Credential{
+			tokenURL.Host:         {TokenEnv: "ROCKETBLEND_TEST_TOKEN"},
+			tokenURL.Hostname():   {Username: "artist", Password: "hunter2"},
+			"missing.example.com": {TokenEnv: "ROCKETBLEND_TEST_MISSING"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), types.CredentialsFileName)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	network, err := New(WithCredentialsFile(path))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: tokenServer.URL, want: "Bearer secret"},
+		{url: basicServer.URL, want: "Basic YXJ0aXN0Omh1bnRlcjI="},
+	}
+
+	for _, tt := range tests {
+		resp, err := network.Client().Get(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if authorization != tt.want {
+			t.Errorf("Client() sent Authorization %q to %s, want %q", authorization, tt.url, tt.want)
+		}
+	}
+
+	if auth := network.GitAuth("https://other.example.com/repo"); auth != nil {
+		t.Errorf("GitAuth() = %v, want nil for a host without credentials", auth)
+	}
+}
+
+func TestGitAuth(t *testing.T) {
+	var mu sync.Mutex
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization = r.Header.Get("Authorization")
+		mu.Unlock()
+
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		credential *types.Credential
+		want       string
+	}{
+		{name: "token", credential: &types.Credential{Token: "secret"}, want: "Basic eC1hY2Nlc3MtdG9rZW46c2VjcmV0"},
+		{name: "token with username", credential: &types.Credential{Token: "secret", Username: "oauth2"}, want: "Basic b2F1dGgyOnNlY3JldA=="},
+		{name: "password", credential: &types.Credential{Username: "artist", Password: "hunter2"}, want: "Basic YXJ0aXN0Omh1bnRlcjI="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(&types.Credentials{
+				Hosts: map[string]*types.Credential{serverURL.Host: tt.credential},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), types.CredentialsFileName)
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			network, err := New(WithCredentialsFile(path))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			repoURL := server.URL + "/studio/library.git"
+			remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{repoURL}})
+
+			// The endpoint has no repository, only the credentials sent with the request matter.
+			_, _ = remote.List(&git.ListOptions{Auth: network.GitAuth(repoURL)})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if authorization != tt.want {
+				t.Errorf("GitAuth() sent Authorization %q, want %q", authorization, tt.want)
+			}
+		})
+	}
+}
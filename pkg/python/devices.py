@@ -0,0 +1,48 @@
+import bpy
+import sys
+import json
+
+# This script is run in the background to find which compute backends Cycles was
+# compiled with in this build, and the devices it finds for each of them. It writes
+# a single event in the same format as the events script and quits.
+#   E.g., ROCKETBLEND_EVENT {"type": "devices", "backends": [{"type": "cpu", "devices": [...]}]}
+
+PREFIX = "ROCKETBLEND_EVENT "
+
+BACKENDS = ["CUDA", "OPTIX", "HIP", "ONEAPI", "METAL"]
+
+def find_devices(preferences, backend: str) -> list[dict]:
+    """
+    Returns the devices of the given type, refreshing them the way the version of Cycles expects.
+    """
+    if hasattr(preferences, "get_devices_for_type"):
+        preferences.refresh_devices()
+        devices = preferences.get_devices_for_type(backend)
+    else:
+        preferences.get_devices()
+        devices = [device for device in preferences.devices if device.type == backend]
+
+    return [{"id": device.id, "name": device.name, "type": device.type} for device in devices]
+
+def find_backends() -> list[dict]:
+    addon = bpy.context.preferences.addons.get("cycles")
+    if addon is None:
+        return []
+
+    preferences = addon.preferences
+    backends = [{"type": "cpu", "devices": find_devices(preferences, "CPU")}]
+    for backend in BACKENDS:
+        # Backends that aren't compiled in aren't valid values of the enum.
+        try:
+            preferences.compute_device_type = backend
+        except TypeError:
+            continue
+
+        backends.append({"type": backend.lower(), "devices": find_devices(preferences, backend)})
+
+    return backends
+
+sys.stdout.write(PREFIX + json.dumps({"type": "devices", "backends": find_backends()}) + "\n")
+sys.stdout.flush()
+
+bpy.ops.wm.quit_blender()
@@ -7,3 +7,12 @@ var CreateScript string
 
 //go:embed startup.py
 var StartupScript string
+
+//go:embed events.py
+var EventsScript string
+
+//go:embed overrides.py
+var OverridesScript string
+
+//go:embed devices.py
+var DevicesScript string
@@ -0,0 +1,96 @@
+import bpy
+import sys
+import json
+
+from bpy.app.handlers import persistent
+
+# This script is run when Blender is started by RocketBlend, before any other script.
+# It registers handlers that report what Blender is doing as JSON on stdout, one event
+# per line. Each line starts with the prefix below so that it can be told apart from
+# Blender's own output, which is only parsed for builds where a handler is missing.
+#   E.g., ROCKETBLEND_EVENT {"type": "render_write", "frame": 12, "path": "/output/shot-00012.png"}
+
+PREFIX = "ROCKETBLEND_EVENT "
+
+def emit(event_type: str, **data) -> None:
+    """
+    Writes an event to stdout, flushing so that it is received while Blender is still busy.
+    """
+    data["type"] = event_type
+    sys.stdout.write(PREFIX + json.dumps(data) + "\n")
+    sys.stdout.flush()
+
+def find_scene(args: tuple):
+    """
+    Returns the scene passed to a handler, falling back to the scene of the current context.
+    """
+    for arg in args:
+        if isinstance(arg, bpy.types.Scene):
+            return arg
+
+    return bpy.context.scene
+
+def find_string(args: tuple) -> str:
+    """
+    Returns the first string passed to a handler. Newer builds pass the statistics or file
+    path to handlers that older builds called with the scene.
+    """
+    for arg in args:
+        if isinstance(arg, str):
+            return arg
+
+    return ""
+
+def current_frame(args: tuple) -> int:
+    scene = find_scene(args)
+    return scene.frame_current if scene is not None else 0
+
+@persistent
+def on_render_pre(*args) -> None:
+    emit("render_pre", frame=current_frame(args))
+
+@persistent
+def on_render_post(*args) -> None:
+    emit("render_post", frame=current_frame(args))
+
+@persistent
+def on_render_stats(*args) -> None:
+    emit("render_stats", frame=current_frame(args), stats=find_string(args))
+
+@persistent
+def on_render_write(*args) -> None:
+    scene = find_scene(args)
+    frame = current_frame(args)
+    path = ""
+    if scene is not None:
+        path = bpy.path.abspath(scene.render.frame_path(frame=frame))
+
+    emit("render_write", frame=frame, path=path)
+
+@persistent
+def on_render_cancel(*args) -> None:
+    emit("render_cancel", frame=current_frame(args))
+
+@persistent
+def on_save_post(*args) -> None:
+    emit("save_post", path=find_string(args) or bpy.data.filepath)
+
+HANDLERS = {
+    "render_pre": on_render_pre,
+    "render_post": on_render_post,
+    "render_stats": on_render_stats,
+    "render_write": on_render_write,
+    "render_cancel": on_render_cancel,
+    "save_post": on_save_post,
+}
+
+registered = []
+for name, handler in HANDLERS.items():
+    handlers = getattr(bpy.app.handlers, name, None)
+    if handlers is None:
+        continue
+
+    handlers.append(handler)
+    registered.append(name)
+
+emit("ready", version=bpy.app.version_string, handlers=registered)
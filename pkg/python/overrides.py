@@ -0,0 +1,90 @@
+import bpy
+import json
+
+# This script is run before rendering to override the render settings of the scene
+# without changing the blend file. Only the settings given are overridden. Anything
+# that can't be applied raises, which stops Blender before it renders.
+#   E.g., {"resolutionX": 1920, "resolutionY": 1080, "samples": 128, "camera": "Camera.001",
+#          "output": {"format": "OPEN_EXR_MULTILAYER", "colorDepth": "32", "exrCodec": "DWAA"}}
+
+settings = json.loads({{ .Settings }})
+
+scene = bpy.context.scene
+render = scene.render
+
+if settings.get("resolutionX"):
+    render.resolution_x = settings["resolutionX"]
+
+if settings.get("resolutionY"):
+    render.resolution_y = settings["resolutionY"]
+
+if settings.get("resolutionPercentage"):
+    render.resolution_percentage = settings["resolutionPercentage"]
+
+# The engine given on the command line is only set after this script has run.
+engine = settings.get("engine") or render.engine
+
+if settings.get("samples"):
+    if engine == "CYCLES":
+        scene.cycles.samples = settings["samples"]
+    elif engine.startswith("BLENDER_EEVEE"):
+        scene.eevee.taa_render_samples = settings["samples"]
+    else:
+        raise ValueError(f"samples can't be set for render engine {engine}")
+
+denoiser = settings.get("denoiser")
+if denoiser:
+    if denoiser == "NONE":
+        scene.cycles.use_denoising = False
+    else:
+        scene.cycles.use_denoising = True
+        scene.cycles.denoiser = denoiser
+
+camera = settings.get("camera")
+if camera:
+    obj = scene.objects.get(camera)
+    if obj is None or obj.type != "CAMERA":
+        raise ValueError(f"camera {camera} not found in scene {scene.name}")
+
+    scene.camera = obj
+
+view_layer = settings.get("viewLayer")
+if view_layer:
+    if scene.view_layers.get(view_layer) is None:
+        raise ValueError(f"view layer {view_layer} not found in scene {scene.name}")
+
+    for layer in scene.view_layers:
+        layer.use = layer.name == view_layer
+
+output = settings.get("output")
+if output:
+    image_settings = render.image_settings
+
+    # The depths and codecs available depend on the format, so it's set first.
+    image_settings.file_format = output["format"]
+
+    if output.get("colorDepth"):
+        image_settings.color_depth = output["colorDepth"]
+
+    if output.get("exrCodec"):
+        image_settings.exr_codec = output["exrCodec"]
+
+    if output.get("compression") is not None:
+        image_settings.compression = output["compression"]
+
+    if output.get("viewTransform"):
+        scene.view_settings.view_transform = output["viewTransform"]
+
+    ffmpeg = render.ffmpeg
+    if output.get("container"):
+        ffmpeg.format = output["container"]
+
+    if output.get("videoCodec"):
+        ffmpeg.codec = output["videoCodec"]
+
+    if output.get("bitrate"):
+        # A constant rate factor takes precedence over the bitrate.
+        if hasattr(ffmpeg, "constant_rate_factor"):
+            ffmpeg.constant_rate_factor = "NONE"
+
+        ffmpeg.video_bitrate = output["bitrate"]
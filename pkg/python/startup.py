@@ -74,9 +74,11 @@ class ArgumentParserForBlender(argparse.ArgumentParser):
         This method is expected to behave identically as in the superclass,
         except that the sys.argv list will be pre-processed using
         _get_argv_after_doubledash before. See the docstring of the class for
-        usage examples and details.
+        usage examples and details. Unknown arguments are ignored, as those
+        after '--' are shared with addons such as Cycles (--cycles-device).
         """
-        return super().parse_args(args=self._get_argv_after_doubledash())
+        args, _ = super().parse_known_args(args=self._get_argv_after_doubledash())
+        return args
 
 class Addon(object):
     """
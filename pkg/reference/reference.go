@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"path"
 	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/semver"
 )
 
+// RangeSeparator separates a reference path from a version constraint, e.g. "builds/blender@^4.2".
+const RangeSeparator = "@"
+
 type Reference string
 
 func (r *Reference) String() string {
@@ -16,7 +21,46 @@ func (r Reference) IsLocalOnly() bool {
 	return strings.HasPrefix(string(r), "local")
 }
 
+// IsRange reports whether the reference selects a version by constraint instead of naming one.
+func (r Reference) IsRange() bool {
+	return strings.Contains(string(r), RangeSeparator)
+}
+
+// Range splits a ranged reference into the path containing the versions and the version constraint.
+// The constraint is empty for a concrete reference.
+func (r Reference) Range() (Reference, string) {
+	base, constraint, _ := strings.Cut(string(r), RangeSeparator)
+	return Reference(base), constraint
+}
+
+// Parent returns the reference one level up, i.e. the path containing a concrete reference's sibling versions.
+func (r Reference) Parent() Reference {
+	base, _ := r.Range()
+	return Reference(path.Dir(string(base)))
+}
+
+// WithConstraint returns the ranged reference selecting versions under r that satisfy the constraint.
+func (r Reference) WithConstraint(constraint string) Reference {
+	base, _ := r.Range()
+	return Reference(string(base) + RangeSeparator + constraint)
+}
+
+// Join returns the reference for an element below r, such as a version directory.
+func (r Reference) Join(elem string) Reference {
+	base, _ := r.Range()
+	return Reference(path.Join(string(base), elem))
+}
+
 func (r Reference) Validate() error {
+	if r.IsRange() {
+		base, constraint := r.Range()
+		if _, err := semver.ParseConstraint(constraint); err != nil {
+			return fmt.Errorf("invalid reference: %s (%w)", r, err)
+		}
+
+		return base.Validate()
+	}
+
 	if r.IsLocalOnly() {
 		// Basic check for now, we can add more checks later.
 		if len(string(r)) <= len("local/") {
@@ -49,7 +93,8 @@ func (r Reference) GetRepo() (string, error) {
 		return "local/", nil
 	}
 
-	parts := strings.SplitN(string(r), "/", 4)
+	base, _ := r.Range()
+	parts := strings.SplitN(string(base), "/", 4)
 	return strings.Join(parts[:3], "/"), nil
 }
 
@@ -71,12 +116,14 @@ func (r Reference) GetRepoPath() (string, error) {
 		return "", err
 	}
 
+	// Ranged references resolve to the path containing the versions.
+	base, _ := r.Range()
 	if r.IsLocalOnly() {
 		// For local references, just return the entire reference as the path
-		return string(r)[len("local"):], nil // remove the 'local' from the beginning
+		return string(base)[len("local"):], nil // remove the 'local' from the beginning
 	}
 
-	parts := strings.SplitN(string(r), "/", 4)
+	parts := strings.SplitN(string(base), "/", 4)
 	return parts[3], nil
 }
 
@@ -92,14 +139,21 @@ func Parse(s string) (Reference, error) {
 
 // Aliased resolves a reference string using a map of aliases.
 // If the input string starts with an alias key, it expands the reference using the alias map.
+// Any version constraint is kept as is, e.g. "blender@^4.2".
 func Aliased(input string, aliases map[string]string) (Reference, error) {
+	input, constraint, ranged := strings.Cut(input, RangeSeparator)
+	suffix := ""
+	if ranged {
+		suffix = RangeSeparator + constraint
+	}
+
 	for fullPath, alias := range aliases {
 		if strings.HasPrefix(input, alias) {
 			remainingPath := strings.TrimPrefix(input, alias)
 			resolved := path.Join(fullPath, remainingPath)
-			return Parse(resolved)
+			return Parse(resolved + suffix)
 		}
 	}
 
-	return Parse(input)
+	return Parse(input + suffix)
 }
@@ -48,6 +48,16 @@ func TestParse(t *testing.T) {
 			input:     "///",
 			expectErr: true,
 		},
+		{
+			name:     "Valid ranged reference",
+			input:    "domain.com/base/repo/v1/builds/module@>=1.3 <2",
+			expected: "domain.com/base/repo/v1/builds/module@>=1.3 <2",
+		},
+		{
+			name:      "Ranged reference with invalid constraint",
+			input:     "domain.com/base/repo/v1/builds/module@latest",
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -117,6 +127,11 @@ func TestAliased(t *testing.T) {
 			input:    "builds",
 			expected: "domain.com/base/repo/v1/builds",
 		},
+		{
+			name:     "Alias with version constraint",
+			input:    "addons/theme@^2.3",
+			expected: "domain.com/base/repo/v1/addons/theme@^2.3",
+		},
 	}
 
 	for _, tt := range tests {
@@ -131,3 +146,55 @@ func TestAliased(t *testing.T) {
 		})
 	}
 }
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      reference.Reference
+		base       string
+		constraint string
+		parent     string
+		repoPath   string
+	}{
+		{
+			name:     "Concrete reference",
+			input:    "domain.com/base/repo/v1/builds/blender/4.2.1",
+			base:     "domain.com/base/repo/v1/builds/blender/4.2.1",
+			parent:   "domain.com/base/repo/v1/builds/blender",
+			repoPath: "v1/builds/blender/4.2.1",
+		},
+		{
+			name:       "Ranged reference",
+			input:      "domain.com/base/repo/v1/builds/blender@^4.2",
+			base:       "domain.com/base/repo/v1/builds/blender",
+			constraint: "^4.2",
+			parent:     "domain.com/base/repo/v1/builds",
+			repoPath:   "v1/builds/blender",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, constraint := tt.input.Range()
+			if string(base) != tt.base || constraint != tt.constraint {
+				t.Errorf("expected: %s %s, got: %s %s", tt.base, tt.constraint, base, constraint)
+			}
+
+			if tt.input.IsRange() != (tt.constraint != "") {
+				t.Errorf("expected IsRange: %v", tt.constraint != "")
+			}
+
+			if parent := tt.input.Parent(); string(parent) != tt.parent {
+				t.Errorf("expected parent: %s, got: %s", tt.parent, parent)
+			}
+
+			repoPath, err := tt.input.GetRepoPath()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if repoPath != tt.repoPath {
+				t.Errorf("expected repo path: %s, got: %s", tt.repoPath, repoPath)
+			}
+		})
+	}
+}
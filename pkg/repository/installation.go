@@ -2,11 +2,17 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/rocketblend/rocketblend/pkg/helpers"
 	"github.com/rocketblend/rocketblend/pkg/lockfile"
@@ -15,15 +21,25 @@ import (
 	"github.com/rocketblend/rocketblend/pkg/types"
 )
 
+const lockRetryInterval = 500 * time.Millisecond
+
 const (
 	LockFileName             = "reference.lock"
 	DownloadProgressFileName = "download-progress.json"
+	ArtifactFileName         = "artifact.json"
 )
 
 type (
 	getInstallationResult struct {
 		reference    reference.Reference
 		installation *types.Installation
+		digest       string
+	}
+
+	// artifact records the downloaded file an installation was extracted from.
+	artifact struct {
+		URI    string `json:"uri"`
+		Digest string `json:"digest"`
 	}
 )
 
@@ -32,13 +48,14 @@ func (r *Repository) GetInstallations(ctx context.Context, opts *types.GetInstal
 		return nil, err
 	}
 
-	installations, err := r.getInstallations(ctx, opts.Dependencies, opts.Fetch)
+	installations, locks, err := r.getInstallations(ctx, opts.Dependencies, opts.Locks, opts.Fetch, opts.RequireDigests, opts.Progress)
 	if err != nil {
 		return nil, err
 	}
 
 	return &types.GetInstallationsResult{
 		Installations: installations,
+		Locks:         locks,
 	}, nil
 }
 
@@ -54,36 +71,53 @@ func (r *Repository) RemoveInstallations(ctx context.Context, opts *types.Remove
 	return nil
 }
 
-func (r *Repository) getInstallations(ctx context.Context, dependencies []*types.Dependency, fetch bool) (map[reference.Reference]*types.Installation, error) {
+func (r *Repository) getInstallations(ctx context.Context, dependencies []*types.Dependency, locks map[reference.Reference]*types.LockedPackage, fetch bool, requireDigests bool, progress chan<- types.InstallationProgress) (map[reference.Reference]*types.Installation, map[reference.Reference]*types.LockedPackage, error) {
 	references := make([]reference.Reference, 0, len(dependencies))
 	for _, dep := range dependencies {
 		references = append(references, dep.Reference)
 	}
 
-	packs, err := r.getPackages(ctx, references, false)
-	if err != nil {
-		return nil, err
+	// While offline, keep going with the packages that were found so that missing artifacts are reported too.
+	offline := &offlineCollector{}
+	packageResults, err := r.getPackages(ctx, references, locks, false)
+	if err != nil && !offline.add(err) {
+		return nil, nil, err
+	}
+
+	packs := make(map[reference.Reference]*types.Package, len(packageResults))
+	packageLocks := make(map[reference.Reference]*types.LockedPackage, len(packageResults))
+	for ref, res := range packageResults {
+		packs[ref] = res.Package
+		packageLocks[ref] = res.Lock
 	}
 
 	for _, dep := range dependencies {
 		if pack, ok := packs[dep.Reference]; ok {
 			if pack.Type != dep.Type {
-				return nil, fmt.Errorf("dependency type mismatch: %s", dep.Reference.String())
+				return nil, nil, fmt.Errorf("dependency type mismatch: %s", dep.Reference.String())
 			}
-		} else {
-			return nil, fmt.Errorf("dependency not found: %s", dep.Reference.String())
+		} else if offline.error() == nil {
+			return nil, nil, fmt.Errorf("dependency not found: %s", dep.Reference.String())
 		}
 	}
 
+	if len(packs) == 0 {
+		return nil, nil, offline.error()
+	}
+
 	tasks := make([]taskrunner.Task[*getInstallationResult], 0, len(packs))
 	for ref, pack := range packs {
 		tasks = append(tasks, func(ctx context.Context) (*getInstallationResult, error) {
-			installation, err := r.getInstallation(ctx, ref, pack, fetch)
+			installation, digest, err := r.getInstallation(ctx, ref, pack, locks[ref], fetch, requireDigests, progress)
 			if err != nil {
+				if offline.add(err) {
+					return nil, nil
+				}
+
 				return nil, err
 			}
 
-			return &getInstallationResult{reference: ref, installation: installation}, nil
+			return &getInstallationResult{reference: ref, installation: installation, digest: digest}, nil
 		})
 	}
 	results, err := taskrunner.Run(ctx, &taskrunner.RunOpts[*getInstallationResult]{
@@ -91,18 +125,28 @@ func (r *Repository) getInstallations(ctx context.Context, dependencies []*types
 		Mode:  taskrunner.Concurrent,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if err := offline.error(); err != nil {
+		return nil, nil, err
 	}
 
 	installations := make(map[reference.Reference]*types.Installation, len(results))
 	for _, res := range results {
 		installations[res.reference] = res.installation
+		if lock, ok := packageLocks[res.reference]; ok {
+			lock.Artifact = res.digest
+		}
 	}
 
-	return installations, nil
+	return installations, packageLocks, nil
 }
 
-func (r *Repository) getInstallation(ctx context.Context, reference reference.Reference, pack *types.Package, fetch bool) (*types.Installation, error) {
+// getInstallation returns the installation for a package along with the digest of the artifact it was
+// installed from. If a lock with an artifact digest is provided, the installation must match it. If digests are
+// required, an artifact is only fetched if its source declares one. Progress is reported if the installation has to be fetched.
+func (r *Repository) getInstallation(ctx context.Context, reference reference.Reference, pack *types.Package, lock *types.LockedPackage, fetch bool, requireDigests bool, progress chan<- types.InstallationProgress) (*types.Installation, string, error) {
 	r.logger.Info("checking installation", map[string]interface{}{
 		"bundled":   pack.Bundled(),
 		"reference": reference.String(),
@@ -110,35 +154,85 @@ func (r *Repository) getInstallation(ctx context.Context, reference reference.Re
 	})
 
 	var resourcePath string
+	var digest string
 
 	// Bundled packages are not downloaded as they are already available within the build.
 	if !pack.Bundled() {
 		// TODO: Clean up this platform stuff.
 		source := pack.Source(types.Platform(r.platform.String()))
+		if source == nil {
+			return nil, "", fmt.Errorf("no source for platform %s: %s", r.platform.String(), reference.String())
+		}
 
 		installationPath := filepath.Join(r.installationPath, reference.String())
-		resourcePath = filepath.Join(installationPath, source.Resource)
+		if err := os.MkdirAll(installationPath, 0755); err != nil {
+			return nil, "", err
+		}
 
-		_, err := os.Stat(resourcePath)
+		// Finding, relinking and downloading the installation all happen under its lock, so that no other
+		// process sees it half linked.
+		cancel, err := r.waitLock(ctx, installationPath)
 		if err != nil {
-			if os.IsNotExist(err) && fetch {
-				packageFilePath := filepath.Join(r.packagePath, reference.String(), types.PackageFileName)
-
-				defer func() {
-					if err := touchFile(packageFilePath); err != nil {
-						r.logger.Error("failed to touch package", map[string]interface{}{
-							"error": err,
-							"path":  packageFilePath,
-						})
-					}
-				}()
-
-				err := r.downloadInstallation(ctx, source.URI, packageFilePath, installationPath)
-				if err != nil {
-					return nil, err
+			return nil, "", err
+		}
+		defer cancel()
+
+		expected := ""
+		if lock != nil {
+			expected = lock.Artifact
+		}
+
+		resourcePath, digest, err = r.findInstallation(installationPath, source, expected)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if resourcePath == "" {
+			// Remote artifacts cannot be downloaded while offline, local ones can still be copied.
+			if r.offline && (!fetch || source.URI == nil || source.URI.IsRemote()) {
+				return nil, "", &types.OfflineError{Artifacts: []string{offlineArtifact(reference, source)}}
+			}
+
+			if !fetch {
+				return nil, "", fmt.Errorf("%w: installation for %s", types.ErrFileNotFound, reference.String())
+			}
+
+			if requireDigests && source.URI != nil && source.SHA256 == "" && source.SHA512 == "" {
+				return nil, "", fmt.Errorf("%w: source of %s declares neither a sha256 nor a sha512 digest", types.ErrMissingDigest, reference.String())
+			}
+
+			packageFilePath := filepath.Join(r.packagePath, reference.String(), types.PackageFileName)
+
+			defer func() {
+				if err := touchFile(packageFilePath); err != nil {
+					r.logger.Error("failed to touch package", map[string]interface{}{
+						"error": err,
+						"path":  packageFilePath,
+					})
 				}
-			} else {
-				return nil, err
+			}()
+
+			digest, err = r.fetchArtifact(ctx, reference, source, packageFilePath, installationPath, expected, progress)
+			if err != nil {
+				return nil, "", err
+			}
+
+			storePath, err := r.storePath(digest)
+			if err != nil {
+				return nil, "", err
+			}
+
+			resourcePath, err = resolveResource(storePath, source)
+			if err != nil {
+				return nil, "", err
+			}
+		} else if expected != "" {
+			if digest == "" {
+				r.logger.Warn("unable to verify installation against lock, artifact digest unknown", map[string]interface{}{
+					"reference": reference.String(),
+				})
+			} else if digest != expected {
+				return nil, "", fmt.Errorf("%w: installation for %s", types.ErrDigestMismatch, reference.String())
 			}
 		}
 	}
@@ -148,11 +242,28 @@ func (r *Repository) getInstallation(ctx context.Context, reference reference.Re
 		Path:    resourcePath,
 		Name:    pack.Name,
 		Version: pack.Version,
-	}, nil
+	}, digest, nil
+}
+
+// installedDigest returns the recorded artifact digest for an installation, or an empty string if unknown.
+func (r *Repository) installedDigest(installationPath string) string {
+	record, err := helpers.Load[artifact](r.validator, filepath.Join(installationPath, ArtifactFileName))
+	if err != nil {
+		if !errors.Is(err, types.ErrFileNotFound) {
+			r.logger.Warn("failed to load artifact record", map[string]interface{}{
+				"error": err,
+				"path":  installationPath,
+			})
+		}
+
+		return ""
+	}
+
+	return record.Digest
 }
 
 func (r *Repository) removeInstallations(ctx context.Context, references []reference.Reference) error {
-	packs, err := r.getPackages(ctx, references, false)
+	packs, err := r.getPackages(ctx, references, nil, false)
 	if err != nil {
 		return err
 	}
@@ -174,21 +285,30 @@ func (r *Repository) removeInstallations(ctx context.Context, references []refer
 	return nil
 }
 
-func (r *Repository) downloadInstallation(ctx context.Context, downloadURI *types.URI, packageFilePath string, installationPath string) error {
-	if downloadURI == nil {
-		return fmt.Errorf("no download URI provided")
+// downloadInstallation downloads and verifies the artifact for an installation, stores it and links the
+// installation to it, returning its digest. If expected is not empty, the artifact must match it. The caller
+// holds the installation's lock.
+func (r *Repository) downloadInstallation(ctx context.Context, ref reference.Reference, source *types.Source, packageFilePath string, installationPath string, expected string, progress chan<- types.InstallationProgress) (string, error) {
+	if source == nil || source.URI == nil {
+		return "", fmt.Errorf("no download URI provided")
 	}
 
-	if err := os.MkdirAll(installationPath, 0755); err != nil {
-		r.logger.Error("failed to create installation path", map[string]interface{}{"error": err, "installationPath": installationPath})
-		return err
-	}
+	// The last update is repeated with each phase, so the downloaded size stays known after the download.
+	var last types.InstallationProgress
+	report := func(update types.InstallationProgress) {
+		last = update
+		if progress == nil {
+			return
+		}
 
-	cancel, err := r.lock(ctx, installationPath)
-	if err != nil {
-		return err
+		update.Reference = ref
+		select {
+		case progress <- update:
+		case <-ctx.Done():
+		}
 	}
-	defer cancel()
+
+	downloadURI := source.URI
 
 	downloadedFilePath := filepath.Join(installationPath, path.Base(downloadURI.Path))
 	r.logger.Info("downloading installation", map[string]interface{}{
@@ -198,7 +318,9 @@ func (r *Repository) downloadInstallation(ctx context.Context, downloadURI *type
 
 	progressFilePath := filepath.Join(installationPath, DownloadProgressFileName)
 	progressChan := make(chan types.Progress)
+	progressDone := make(chan struct{})
 	go func() {
+		defer close(progressDone)
 		for p := range progressChan {
 			if err := writeProgressToFile(progressFilePath, p); err != nil {
 				r.logger.Error("failed to write download progress file", map[string]interface{}{
@@ -215,31 +337,133 @@ func (r *Repository) downloadInstallation(ctx context.Context, downloadURI *type
 			}
 
 			r.logger.Info("download progress", map[string]interface{}{
-				"uri":     downloadURI.String(),
+				"uri":     p.URI,
+				"attempt": p.Attempt,
 				"total":   p.Total,
 				"current": p.Current,
 				"rate":    p.Speed,
 			})
+
+			eta := time.Duration(0)
+			if p.Speed > 0 && p.Total > 0 {
+				eta = time.Duration(float64(p.Total-p.Current) / p.Speed * float64(time.Second))
+			}
+
+			report(types.InstallationProgress{
+				Phase:   types.InstallationPhaseDownloading,
+				Current: p.Current,
+				Total:   p.Total,
+				Speed:   p.Speed,
+				ETA:     eta,
+				Attempt: p.Attempt,
+			})
 		}
 	}()
 
-	if err := r.downloader.Download(ctx, &types.DownloadOpts{
+	err := r.downloader.Download(ctx, &types.DownloadOpts{
 		URI:          downloadURI,
+		Mirrors:      source.Mirrors,
 		Path:         downloadedFilePath,
 		ProgressChan: progressChan,
-	}); err != nil {
-		return err
+	})
+	close(progressChan)
+	<-progressDone
+	if err != nil {
+		return "", err
 	}
 
-	if helpers.IsSupportedArchive(downloadedFilePath) {
-		if err := r.extractor.Extract(ctx, &types.ExtractOpts{
-			Path:       downloadedFilePath,
-			OutputPath: filepath.Dir(downloadedFilePath),
-		}); err != nil {
-			return err
+	report(types.InstallationProgress{
+		Phase:   types.InstallationPhaseVerifying,
+		Current: last.Current,
+		Total:   last.Total,
+		Attempt: last.Attempt,
+	})
+
+	if err := verifyChecksums(downloadedFilePath, source); err != nil {
+		r.logger.Error("downloaded artifact failed checksum verification", map[string]interface{}{
+			"error": err,
+			"uri":   downloadURI.String(),
+		})
+
+		if err := os.Remove(downloadedFilePath); err != nil {
+			r.logger.Error("failed to remove downloaded artifact", map[string]interface{}{
+				"error": err,
+				"path":  downloadedFilePath,
+			})
+		}
+
+		return "", err
+	}
+
+	digest, err := helpers.DigestFile(downloadedFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	if expected != "" && digest != expected {
+		r.logger.Error("downloaded artifact does not match lock", map[string]interface{}{
+			"uri":      downloadURI.String(),
+			"expected": expected,
+			"actual":   digest,
+		})
+
+		if err := os.Remove(downloadedFilePath); err != nil {
+			r.logger.Error("failed to remove downloaded artifact", map[string]interface{}{
+				"error": err,
+				"path":  downloadedFilePath,
+			})
 		}
+
+		return "", fmt.Errorf("%w: artifact %s", types.ErrDigestMismatch, downloadURI.String())
+	}
+
+	downloaded := last
+	report(types.InstallationProgress{
+		Phase:   types.InstallationPhaseExtracting,
+		Total:   downloaded.Total,
+		Attempt: downloaded.Attempt,
+	})
+
+	extractChan := make(chan types.ExtractProgress)
+	extractDone := make(chan struct{})
+	go func() {
+		defer close(extractDone)
+		for p := range extractChan {
+			r.logger.Debug("extraction progress", map[string]interface{}{
+				"entries": p.Entries,
+				"total":   p.Total,
+				"current": p.Current,
+			})
+
+			report(types.InstallationProgress{
+				Phase:   types.InstallationPhaseExtracting,
+				Current: p.Current,
+				Total:   p.Total,
+				Attempt: downloaded.Attempt,
+				Entries: p.Entries,
+			})
+		}
+	}()
+
+	err = r.storeArtifact(ctx, downloadedFilePath, source, digest, extractChan)
+	close(extractChan)
+	<-extractDone
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.linkInstallation(installationPath, source, digest); err != nil {
+		return "", err
 	}
 
+	report(types.InstallationProgress{
+		Phase:   types.InstallationPhaseComplete,
+		Current: downloaded.Current,
+		Total:   downloaded.Total,
+		Attempt: downloaded.Attempt,
+		Entries: last.Entries,
+	})
+
 	if err := os.Remove(progressFilePath); err != nil {
 		r.logger.Error("failed to remove download progress file", map[string]interface{}{
 			"error": err,
@@ -247,7 +471,7 @@ func (r *Repository) downloadInstallation(ctx context.Context, downloadURI *type
 		})
 	}
 
-	return nil
+	return digest, nil
 }
 
 func (r *Repository) removeInstallation(ctx context.Context, reference reference.Reference) error {
@@ -280,6 +504,61 @@ func (r *Repository) lock(ctx context.Context, dir string) (cancelFunc func(), e
 	return lockfile.New(ctx, lockfile.WithPath(filepath.Join(dir, LockFileName)), lockfile.WithLogger(r.logger))
 }
 
+// waitLock locks the directory like lock, waiting for another process that holds the lock to release it.
+func (r *Repository) waitLock(ctx context.Context, dir string) (cancelFunc func(), err error) {
+	for {
+		cancel, err := r.lock(ctx, dir)
+		if err == nil {
+			return cancel, nil
+		}
+
+		locked, lockErr := lockfile.IsLocked(filepath.Join(dir, LockFileName))
+		if lockErr != nil || !locked {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// verifyChecksums checks the file against the digests declared by the source.
+func verifyChecksums(filePath string, source *types.Source) error {
+	checksums := []struct {
+		algorithm string
+		expected  string
+		hash      func() hash.Hash
+	}{
+		{algorithm: "sha256", expected: source.SHA256, hash: sha256.New},
+		{algorithm: "sha512", expected: source.SHA512, hash: sha512.New},
+	}
+
+	for _, checksum := range checksums {
+		if checksum.expected == "" {
+			continue
+		}
+
+		actual, err := helpers.HashFile(filePath, checksum.hash())
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(actual, checksum.expected) {
+			return &types.ChecksumError{
+				URI:       source.URI.String(),
+				Algorithm: checksum.algorithm,
+				Expected:  checksum.expected,
+				Actual:    actual,
+			}
+		}
+	}
+
+	return nil
+}
+
 func writeProgressToFile(path string, progress types.Progress) error {
 	infoFile, err := os.Create(path)
 	if err != nil {
@@ -300,11 +579,16 @@ func writeProgressToFile(path string, progress types.Progress) error {
 }
 
 // touchFile used to trigger a file change event on the file system.
-// this seems to be the simplist cross platform way to do this.
+// this seems to be the simplist cross platform way to do this. Missing files, such as package definitions that
+// are read from a library directory in place, are left alone.
 func touchFile(path string) error {
 	tmpFileName := path + ".tmp"
 	originalFileName := path
 
+	if _, err := os.Stat(originalFileName); os.IsNotExist(err) {
+		return nil
+	}
+
 	if err := os.Rename(originalFileName, tmpFileName); err != nil {
 		return err
 	}
@@ -315,3 +599,12 @@ func touchFile(path string) error {
 
 	return nil
 }
+
+// offlineArtifact describes an artifact that is missing while offline.
+func offlineArtifact(ref reference.Reference, source *types.Source) string {
+	if source == nil || source.URI == nil {
+		return ref.String()
+	}
+
+	return fmt.Sprintf("%s (%s)", ref.String(), source.URI.String())
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestGetInstallationChecksumMismatch(t *testing.T) {
+	downloader := &fakeDownloader{}
+	extractor := &fakeExtractor{}
+	r := newTestRepository(t, downloader, extractor)
+	ref := reference.Reference("builds/blender/4.2.0")
+
+	pack := testPackage(t)
+	pack.Sources[0].SHA256 = strings.Repeat("0", 64)
+
+	_, _, err := r.getInstallation(context.Background(), ref, pack, nil, true, false, nil)
+
+	var checksumErr *types.ChecksumError
+	if !errors.As(err, &checksumErr) || !errors.Is(err, types.ErrDigestMismatch) {
+		t.Fatalf("getInstallation() error = %v, want a checksum error", err)
+	}
+
+	if checksumErr.Algorithm != "sha256" || checksumErr.Expected != pack.Sources[0].SHA256 {
+		t.Errorf("getInstallation() error = %+v, want the declared sha256", checksumErr)
+	}
+
+	installationPath := filepath.Join(r.installationPath, ref.String())
+	if _, err := os.Stat(filepath.Join(installationPath, "blender.zip")); !os.IsNotExist(err) {
+		t.Errorf("getInstallation() kept the artifact that failed verification: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(r.installationPath, StoreDirName)); !os.IsNotExist(err) {
+		t.Errorf("getInstallation() stored the artifact that failed verification: %v", err)
+	}
+
+	if got := extractor.extractions.Load(); got != 0 {
+		t.Errorf("artifact was extracted %d times, want 0", got)
+	}
+}
+
+func TestGetInstallationRequireDigests(t *testing.T) {
+	downloader := &fakeDownloader{}
+	r := newTestRepository(t, downloader, &fakeExtractor{})
+	ref := reference.Reference("builds/blender/4.2.0")
+
+	_, _, err := r.getInstallation(context.Background(), ref, testPackage(t), nil, true, true, nil)
+	if !errors.Is(err, types.ErrMissingDigest) {
+		t.Fatalf("getInstallation() error = %v, want %v", err, types.ErrMissingDigest)
+	}
+
+	if got := downloader.downloads.Load(); got != 0 {
+		t.Errorf("artifact was downloaded %d times, want 0", got)
+	}
+
+	pack := testPackage(t)
+	pack.Sources[0].SHA256 = strings.TrimPrefix(testDigest(t), "sha256:")
+	if _, _, err := r.getInstallation(context.Background(), ref, pack, nil, true, true, nil); err != nil {
+		t.Errorf("getInstallation() returned unexpected error for a source with a digest: %v", err)
+	}
+
+	// Installations are only checked before they are fetched, so existing ones keep resolving.
+	installed := reference.Reference("builds/blender/4.1.0")
+	if _, _, err := r.getInstallation(context.Background(), installed, testPackage(t), nil, true, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fetch := range []bool{true, false} {
+		if _, _, err := r.getInstallation(context.Background(), installed, testPackage(t), nil, fetch, true, nil); err != nil {
+			t.Errorf("getInstallation(fetch=%t) returned unexpected error for an installed artifact: %v", fetch, err)
+		}
+	}
+}
+
+func TestGetInstallationLockedArtifact(t *testing.T) {
+	ref := reference.Reference("builds/blender/4.2.0")
+	lock := &types.LockedPackage{
+		Reference: ref,
+		Type:      types.PackageBuild,
+		Artifact:  "sha256:" + strings.Repeat("0", 64),
+	}
+
+	t.Run("download", func(t *testing.T) {
+		extractor := &fakeExtractor{}
+		r := newTestRepository(t, &fakeDownloader{}, extractor)
+
+		_, _, err := r.getInstallation(context.Background(), ref, testPackage(t), lock, true, false, nil)
+		if !errors.Is(err, types.ErrDigestMismatch) {
+			t.Fatalf("getInstallation() error = %v, want %v", err, types.ErrDigestMismatch)
+		}
+
+		if _, err := os.Stat(filepath.Join(r.installationPath, ref.String(), "blender.zip")); !os.IsNotExist(err) {
+			t.Errorf("getInstallation() kept the artifact that does not match the lock: %v", err)
+		}
+
+		if got := extractor.extractions.Load(); got != 0 {
+			t.Errorf("artifact was extracted %d times, want 0", got)
+		}
+	})
+
+	t.Run("installed", func(t *testing.T) {
+		r := newTestRepository(t, &fakeDownloader{}, &fakeExtractor{})
+		if _, _, err := r.getInstallation(context.Background(), ref, testPackage(t), nil, true, false, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := r.getInstallation(context.Background(), ref, testPackage(t), lock, false, false, nil)
+		if !errors.Is(err, types.ErrDigestMismatch) {
+			t.Errorf("getInstallation() error = %v, want %v", err, types.ErrDigestMismatch)
+		}
+
+		locked := *lock
+		locked.Artifact = testDigest(t)
+		if _, _, err := r.getInstallation(context.Background(), ref, testPackage(t), &locked, false, false, nil); err != nil {
+			t.Errorf("getInstallation() returned unexpected error for the locked artifact: %v", err)
+		}
+	})
+}
+
+func TestGetInstallationProgress(t *testing.T) {
+	r := newTestRepository(t, &fakeDownloader{}, &fakeExtractor{})
+	ref := reference.Reference("builds/blender/4.2.0")
+
+	progress := make(chan types.InstallationProgress, 100)
+	if _, _, err := r.getInstallation(context.Background(), ref, testPackage(t), nil, true, false, progress); err != nil {
+		t.Fatalf("getInstallation() returned unexpected error: %v", err)
+	}
+	close(progress)
+
+	var phases []types.InstallationPhase
+	var last types.InstallationProgress
+	for update := range progress {
+		if update.Reference != ref {
+			t.Errorf("progress reference = %s, want %s", update.Reference, ref)
+		}
+
+		if len(phases) == 0 || phases[len(phases)-1] != update.Phase {
+			phases = append(phases, update.Phase)
+		}
+
+		last = update
+	}
+
+	want := []types.InstallationPhase{
+		types.InstallationPhaseDownloading,
+		types.InstallationPhaseVerifying,
+		types.InstallationPhaseExtracting,
+		types.InstallationPhaseComplete,
+	}
+	if !slices.Equal(phases, want) {
+		t.Fatalf("progress phases = %v, want %v", phases, want)
+	}
+
+	// The completed installation reports the size of the download rather than of the extraction.
+	size := int64(len(testArtifact))
+	if last.Current != size || last.Total != size || last.Entries != 1 {
+		t.Errorf("completed progress = %+v, want %d of %d bytes and 1 entry", last, size, size)
+	}
+}
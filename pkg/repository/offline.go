@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// offlineCollector gathers everything that is missing locally while offline, so that it can be reported
+// at once instead of failing on the first missing package.
+type offlineCollector struct {
+	mutex sync.Mutex
+	err   *types.OfflineError
+}
+
+// add records the error if it is an offline error and reports whether it was recorded.
+func (c *offlineCollector) add(err error) bool {
+	var offlineErr *types.OfflineError
+	if !errors.As(err, &offlineErr) {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err == nil {
+		c.err = &types.OfflineError{}
+	}
+
+	c.err.Merge(offlineErr)
+	return true
+}
+
+// error returns the collected offline error, or nil if nothing is missing.
+func (c *offlineCollector) error() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err == nil {
+		return nil
+	}
+
+	return c.err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// offlineSource serves the libraries already on disk and reports the others as missing, like a git source
+// would while offline.
+type offlineSource struct {
+	types.PackageSource
+}
+
+func (s *offlineSource) Sync(ctx context.Context, opts *types.SyncLibraryOpts) (*types.SyncLibraryResult, error) {
+	if _, err := os.Stat(opts.Path); err != nil {
+		return nil, &types.OfflineError{References: []reference.Reference{opts.Reference}}
+	}
+
+	return &types.SyncLibraryResult{Path: opts.Path}, nil
+}
+
+func (s *offlineSource) Revision(ctx context.Context, opts *types.LibraryRevisionOpts) (string, error) {
+	return "", nil
+}
+
+func TestGetInstallationsOffline(t *testing.T) {
+	downloader := &fakeDownloader{}
+	root := t.TempDir()
+	r, err := New(
+		WithDownloader(downloader),
+		WithExtractor(&fakeExtractor{}),
+		WithSource("", &offlineSource{}),
+		WithPackagePath(filepath.Join(root, "packages")),
+		WithInstallationPath(filepath.Join(root, "installations")),
+		WithOffline(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		build          = reference.Reference("github.com/studio/library/builds/blender/4.2.0")
+		missingAddon   = reference.Reference("github.com/studio/library/addons/missing/1.0.0")
+		missingLibrary = reference.Reference("github.com/studio/other/addons/tools/1.0.0")
+	)
+
+	pack := testPackage(t)
+	if err := r.insertPackage(context.Background(), build, pack); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.GetInstallations(context.Background(), &types.GetInstallationsOpts{
+		Dependencies: []*types.Dependency{
+			{Reference: build, Type: types.PackageBuild},
+			{Reference: missingAddon, Type: types.PackageAddon},
+			{Reference: missingLibrary, Type: types.PackageAddon},
+		},
+		Fetch: true,
+	})
+
+	if !errors.Is(err, types.ErrOffline) {
+		t.Fatalf("GetInstallations() error = %v, want %v", err, types.ErrOffline)
+	}
+
+	var offlineErr *types.OfflineError
+	if !errors.As(err, &offlineErr) {
+		t.Fatalf("GetInstallations() error = %v, want an offline error", err)
+	}
+
+	slices.Sort(offlineErr.References)
+	wantReferences := []reference.Reference{missingAddon, missingLibrary}
+	if !slices.Equal(offlineErr.References, wantReferences) {
+		t.Errorf("missing references = %v, want %v", offlineErr.References, wantReferences)
+	}
+
+	wantArtifacts := []string{offlineArtifact(build, pack.Sources[0])}
+	if !slices.Equal(offlineErr.Artifacts, wantArtifacts) {
+		t.Errorf("missing artifacts = %v, want %v", offlineErr.Artifacts, wantArtifacts)
+	}
+
+	if got := downloader.downloads.Load(); got != 0 {
+		t.Errorf("artifact was downloaded %d times while offline, want 0", got)
+	}
+}
@@ -2,12 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/rocketblend/rocketblend/pkg/helpers"
 	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/semver"
 	"github.com/rocketblend/rocketblend/pkg/taskrunner"
 	"github.com/rocketblend/rocketblend/pkg/types"
 )
@@ -16,6 +20,13 @@ type (
 	getPackageResult struct {
 		Reference reference.Reference
 		Package   *types.Package
+		Lock      *types.LockedPackage
+		Signer    *types.Signer
+	}
+
+	resolveReferenceResult struct {
+		Reference reference.Reference
+		Resolved  reference.Reference
 	}
 )
 
@@ -24,13 +35,72 @@ func (r *Repository) GetPackages(ctx context.Context, opts *types.GetPackagesOpt
 		return nil, err
 	}
 
-	packs, err := r.getPackages(ctx, opts.References, opts.Update)
+	results, err := r.getPackages(ctx, opts.References, opts.Locks, opts.Update)
 	if err != nil {
 		return nil, err
 	}
 
+	packs := make(map[reference.Reference]*types.Package, len(results))
+	locks := make(map[reference.Reference]*types.LockedPackage, len(results))
+	signers := make(map[reference.Reference]*types.Signer)
+	for ref, res := range results {
+		packs[ref] = res.Package
+		locks[ref] = res.Lock
+		if res.Signer != nil {
+			signers[ref] = res.Signer
+		}
+	}
+
 	return &types.GetPackagesResult{
-		Packs: packs,
+		Packs:   packs,
+		Locks:   locks,
+		Signers: signers,
+	}, nil
+}
+
+// ResolveReferences resolves ranged references to the highest matching version available in the library.
+// Concrete references resolve to themselves.
+func (r *Repository) ResolveReferences(ctx context.Context, opts *types.ResolveReferencesOpts) (*types.ResolveReferencesResult, error) {
+	if err := r.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	offline := &offlineCollector{}
+	tasks := make([]taskrunner.Task[*resolveReferenceResult], 0, len(opts.References))
+	for _, ref := range opts.References {
+		tasks = append(tasks, func(ctx context.Context) (*resolveReferenceResult, error) {
+			resolved, err := r.resolveReference(ctx, ref, opts.Update)
+			if err != nil {
+				if offline.add(err) {
+					return nil, nil
+				}
+
+				return nil, err
+			}
+
+			return &resolveReferenceResult{Reference: ref, Resolved: resolved}, nil
+		})
+	}
+
+	results, err := taskrunner.Run(ctx, &taskrunner.RunOpts[*resolveReferenceResult]{
+		Tasks: tasks,
+		Mode:  taskrunner.Concurrent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := offline.error(); err != nil {
+		return nil, err
+	}
+
+	references := make(map[reference.Reference]reference.Reference, len(results))
+	for _, res := range results {
+		references[res.Reference] = res.Resolved
+	}
+
+	return &types.ResolveReferencesResult{
+		References: references,
 	}, nil
 }
 
@@ -58,16 +128,23 @@ func (r *Repository) InsertPackages(ctx context.Context, opts *types.InsertPacka
 	return nil
 }
 
-func (r *Repository) getPackages(ctx context.Context, references []reference.Reference, update bool) (map[reference.Reference]*types.Package, error) {
+// getPackages loads the package definitions for the references. While offline, every reference that is not
+// available locally is reported in a single types.OfflineError, returned along with the packages that were found.
+func (r *Repository) getPackages(ctx context.Context, references []reference.Reference, locks map[reference.Reference]*types.LockedPackage, update bool) (map[reference.Reference]*getPackageResult, error) {
+	offline := &offlineCollector{}
 	tasks := make([]taskrunner.Task[*getPackageResult], 0, len(references))
 	for _, ref := range references {
 		tasks = append(tasks, func(ctx context.Context) (*getPackageResult, error) {
-			pack, err := r.getPackage(ctx, ref, update)
+			result, err := r.getPackage(ctx, ref, locks[ref], update)
 			if err != nil {
+				if offline.add(err) {
+					return nil, nil
+				}
+
 				return nil, err
 			}
 
-			return &getPackageResult{Reference: ref, Package: pack}, nil
+			return result, nil
 		})
 	}
 
@@ -79,12 +156,14 @@ func (r *Repository) getPackages(ctx context.Context, references []reference.Ref
 		return nil, err
 	}
 
-	packages := make(map[reference.Reference]*types.Package)
+	packages := make(map[reference.Reference]*getPackageResult, len(results))
 	for _, res := range results {
-		packages[res.Reference] = res.Package
+		if res != nil {
+			packages[res.Reference] = res
+		}
 	}
 
-	return packages, nil
+	return packages, offline.error()
 }
 
 func (r *Repository) removePackages(ctx context.Context, references []reference.Reference) error {
@@ -144,59 +223,356 @@ func (r *Repository) insertPackage(ctx context.Context, ref reference.Reference,
 	return nil
 }
 
-func (s *Repository) getPackage(ctx context.Context, ref reference.Reference, update bool) (*types.Package, error) {
+// getPackage loads the package definition for a reference. If a lock is provided, the definition is read
+// from the locked commit and verified against the locked digest instead of the working tree.
+func (s *Repository) getPackage(ctx context.Context, ref reference.Reference, lock *types.LockedPackage, update bool) (*getPackageResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
 	s.logger.Info("processing reference", map[string]interface{}{
 		"reference": ref.String(),
+		"locked":    lock != nil,
 	})
 
-	repo, err := ref.GetRepo()
-	if err != nil {
-		s.logger.Error("error getting repository", map[string]interface{}{"error": err, "reference": ref.String()})
-		return nil, err
+	if ref.IsRange() {
+		return nil, fmt.Errorf("%w: %s", types.ErrUnresolvedReference, ref.String())
 	}
 
-	repoPath := filepath.Join(s.packagePath, repo)
-	packagePath := filepath.Join(s.packagePath, ref.String(), types.PackageFileName)
-
-	// The repository does not exist locally, clone it
-	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-		repoURL, err := ref.GetRepoURL()
+	var data, signature []byte
+	var err error
+	commit := ""
+	switch {
+	case ref.IsLocalOnly():
+		// Local packages have no source to sync from
+		data, signature, err = s.readPackageFile(ref, filepath.Join(s.packagePath, ref.String(), types.PackageFileName))
+		if err != nil {
+			return nil, err
+		}
+	case lock != nil && lock.Commit != "":
+		source, libraryPath, err := s.syncLibrary(ctx, ref, false)
 		if err != nil {
-			s.logger.Error("error getting repository URL", map[string]interface{}{"error": err, "reference": ref.String()})
 			return nil, err
 		}
 
-		s.logger.Info("cloning repository", map[string]interface{}{"repoURL": repoURL, "path": repoPath, "reference": ref.String()})
-		if err := s.cloneRepo(ctx, repoPath, repoURL); err != nil {
+		commit = lock.Commit
+		data, signature, err = s.readPackageAtRevision(ctx, source, libraryPath, ref, commit)
+		if errors.Is(err, errors.ErrUnsupported) {
+			// The source does not keep history, the locked digest still guards the definition
+			data, signature, commit, err = s.readLatestPackage(ctx, source, libraryPath, ref)
+		}
+		if err != nil {
+			s.logger.Error("error reading locked package", map[string]interface{}{
+				"error":     err,
+				"reference": ref.String(),
+				"commit":    lock.Commit,
+			})
+
 			return nil, err
 		}
-	}
+	default:
+		source, libraryPath, err := s.syncLibrary(ctx, ref, update)
+		if err != nil {
+			return nil, err
+		}
+
+		// The package may have been added since the library was last synced, fetch the latest changes
+		data, signature, commit, err = s.readLatestPackage(ctx, source, libraryPath, ref)
+		if errors.Is(err, types.ErrFileNotFound) && !update && !s.offline {
+			if source, libraryPath, err = s.syncLibrary(ctx, ref, true); err != nil {
+				return nil, err
+			}
 
-	// Check if the file exists in the repository
-	if _, err := os.Stat(packagePath); os.IsNotExist(err) || update {
-		// The file does not exist or forced update, pull the latest changes
-		s.logger.Info("pulling latest changes for repository", map[string]interface{}{"path": packagePath, "reference": ref.String()})
-		if err := s.pullChanges(ctx, repoPath); err != nil {
+			data, signature, commit, err = s.readLatestPackage(ctx, source, libraryPath, ref)
+		}
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	pack, err := helpers.Load[types.Package](s.validator, packagePath)
+	digest := helpers.DigestBytes(data)
+	if lock != nil && lock.Digest != digest {
+		s.logger.Error("package definition does not match lock", map[string]interface{}{
+			"reference": ref.String(),
+			"expected":  lock.Digest,
+			"actual":    digest,
+		})
+
+		return nil, fmt.Errorf("%w: package definition for %s", types.ErrDigestMismatch, ref.String())
+	}
+
+	signer, err := s.verifyPackage(ctx, ref, data, signature)
+	if err != nil {
+		s.logger.Error("error verifying package", map[string]interface{}{
+			"error":     err,
+			"reference": ref.String(),
+		})
+
+		return nil, err
+	}
+
+	pack, err := helpers.Decode[types.Package](s.validator, data)
 	if err != nil {
 		s.logger.Error("error loading package", map[string]interface{}{
 			"error":     err,
 			"reference": ref.String(),
-			"path":      packagePath,
 		})
 
 		return nil, err
 	}
 
-	return pack, nil
+	return &getPackageResult{
+		Reference: ref,
+		Package:   pack,
+		Lock: &types.LockedPackage{
+			Reference: ref,
+			Type:      pack.Type,
+			Commit:    commit,
+			Digest:    digest,
+		},
+		Signer: signer,
+	}, nil
+}
+
+// resolveReference picks the highest version directory under a ranged reference that satisfies its
+// constraint. Only directories containing a package definition are considered.
+func (s *Repository) resolveReference(ctx context.Context, ref reference.Reference, update bool) (reference.Reference, error) {
+	if !ref.IsRange() {
+		return ref, nil
+	}
+
+	base, rawConstraint := ref.Range()
+	constraint, err := semver.ParseConstraint(rawConstraint)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.packagePath, base.String())
+	if !ref.IsLocalOnly() {
+		_, libraryPath, err := s.syncLibrary(ctx, ref, update)
+		if err != nil {
+			return "", err
+		}
+
+		if dir, err = libraryDir(libraryPath, base); err != nil {
+			return "", err
+		}
+	}
+
+	version, err := highestVersion(dir, constraint)
+	if err != nil {
+		return "", err
+	}
+
+	// Nothing matches locally, the library may be out of date.
+	if version == "" && !update && !ref.IsLocalOnly() && !s.offline {
+		if _, _, err := s.syncLibrary(ctx, ref, true); err != nil {
+			return "", err
+		}
+
+		if version, err = highestVersion(dir, constraint); err != nil {
+			return "", err
+		}
+	}
+
+	if version == "" {
+		if s.offline && !ref.IsLocalOnly() {
+			return "", &types.OfflineError{References: []reference.Reference{ref}}
+		}
+
+		return "", fmt.Errorf("%w: %s", types.ErrNoMatchingVersion, ref.String())
+	}
+
+	resolved := base.Join(version)
+	s.logger.Debug("resolved reference", map[string]interface{}{
+		"reference": ref.String(),
+		"resolved":  resolved.String(),
+	})
+
+	return resolved, nil
+}
+
+// highestVersion returns the name of the highest version directory in dir that satisfies the constraint, or an
+// empty string if none do.
+func highestVersion(dir string, constraint *semver.Constraint) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	name := ""
+	var highest *semver.Version
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		version, err := semver.Parse(entry.Name())
+		if err != nil || !constraint.Check(*version) {
+			continue
+		}
+
+		if err := helpers.FileExists(filepath.Join(dir, entry.Name(), types.PackageFileName)); err != nil {
+			continue
+		}
+
+		if highest == nil || version.GreaterThan(*highest) {
+			highest = version
+			name = entry.Name()
+		}
+	}
+
+	return name, nil
+}
+
+// source returns the package source for a library. The source with the longest prefix matching the library,
+// regardless of case, is used, falling back to the default source.
+func (s *Repository) source(library string) types.PackageSource {
+	library = strings.ToLower(library)
+
+	match := ""
+	for prefix := range s.sources {
+		if len(prefix) > len(match) && (library == prefix || strings.HasPrefix(library, prefix+"/")) {
+			match = prefix
+		}
+	}
+
+	return s.sources[match]
+}
+
+// syncLibrary makes the library of a reference available locally, fetching the latest changes if update is
+// set. It returns the source of the library and the path to read it from.
+func (s *Repository) syncLibrary(ctx context.Context, ref reference.Reference, update bool) (types.PackageSource, string, error) {
+	library, err := ref.GetRepo()
+	if err != nil {
+		s.logger.Error("error getting repository", map[string]interface{}{"error": err, "reference": ref.String()})
+		return nil, "", err
+	}
+
+	source := s.source(library)
+	result, err := source.Sync(ctx, &types.SyncLibraryOpts{
+		Reference: ref,
+		Library:   library,
+		Path:      filepath.Join(s.packagePath, library),
+		Update:    update,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return source, result.Path, nil
+}
+
+// libraryDir returns the directory of a reference within the library read from libraryPath.
+func libraryDir(libraryPath string, ref reference.Reference) (string, error) {
+	repoPath, err := ref.GetRepoPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(libraryPath, filepath.FromSlash(repoPath)), nil
+}
+
+// readLatestPackage reads the package definition and its signature, if any, from the library read from
+// libraryPath, along with the library's current revision.
+func (s *Repository) readLatestPackage(ctx context.Context, source types.PackageSource, libraryPath string, ref reference.Reference) ([]byte, []byte, string, error) {
+	dir, err := libraryDir(libraryPath, ref)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	data, signature, err := s.readPackageFile(ref, filepath.Join(dir, types.PackageFileName))
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	revision, err := source.Revision(ctx, &types.LibraryRevisionOpts{Path: libraryPath})
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return data, signature, revision, nil
+}
+
+// readPackageFile reads the package definition and its signature, if any, from the local library.
+func (s *Repository) readPackageFile(ref reference.Reference, packagePath string) ([]byte, []byte, error) {
+	if err := helpers.FileExists(packagePath); err != nil {
+		if s.offline && !ref.IsLocalOnly() {
+			return nil, nil, &types.OfflineError{References: []reference.Reference{ref}}
+		}
+
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(packagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature, err := os.ReadFile(packagePath + types.SignatureFileExtension)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	return data, signature, nil
+}
+
+// readPackageAtRevision reads the package definition and its signature, if any, for a reference as they were
+// at the given revision of the library.
+func (s *Repository) readPackageAtRevision(ctx context.Context, source types.PackageSource, libraryPath string, ref reference.Reference, revision string) ([]byte, []byte, error) {
+	filePath, err := ref.GetRepoPath()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	packageFilePath := path.Join(filePath, types.PackageFileName)
+	data, err := source.ReadFile(ctx, &types.ReadLibraryFileOpts{
+		Reference: ref,
+		Path:      libraryPath,
+		Revision:  revision,
+		File:      packageFilePath,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature, err := source.ReadFile(ctx, &types.ReadLibraryFileOpts{
+		Reference: ref,
+		Path:      libraryPath,
+		Revision:  revision,
+		File:      packageFilePath + types.SignatureFileExtension,
+	})
+	if err != nil && !errors.Is(err, types.ErrFileNotFound) {
+		return nil, nil, err
+	}
+
+	return data, signature, nil
+}
+
+// verifyPackage checks the detached signature of a package definition. Verification is skipped for local
+// references and when no verifier is configured.
+func (s *Repository) verifyPackage(ctx context.Context, ref reference.Reference, data []byte, signature []byte) (*types.Signer, error) {
+	if s.verifier == nil || ref.IsLocalOnly() {
+		return nil, nil
+	}
+
+	if len(signature) == 0 {
+		return nil, fmt.Errorf("%w: package definition for %s", types.ErrMissingSignature, ref.String())
+	}
+
+	result, err := s.verifier.Verify(ctx, &types.VerifyOpts{
+		Data:      data,
+		Signature: signature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("package definition for %s: %w", ref.String(), err)
+	}
+
+	return result.Signer, nil
 }
 
 func (s *Repository) removePackage(ctx context.Context, reference reference.Reference) error {
@@ -235,37 +611,3 @@ func (s *Repository) removePackage(ctx context.Context, reference reference.Refe
 
 	return nil
 }
-
-func (s *Repository) cloneRepo(ctx context.Context, repoPath string, repoURL string) error {
-	_, err := git.PlainCloneContext(ctx, repoPath, false, &git.CloneOptions{
-		URL: repoURL,
-		// TODO: Fix this
-		// Progress: LoggerWriter{s.logger},
-	})
-	return err
-}
-
-func (s *Repository) pullChanges(ctx context.Context, repoPath string) error {
-	r, err := git.PlainOpen(repoPath)
-	if err != nil {
-		return err
-	}
-
-	if err := r.FetchContext(ctx, &git.FetchOptions{}); err != nil && err != git.NoErrAlreadyUpToDate {
-		return err
-	}
-
-	w, err := r.Worktree()
-	if err != nil {
-		return err
-	}
-
-	if err := w.PullContext(ctx, &git.PullOptions{
-		Force: true,
-		// Progress: LoggerWriter{s.logger},
-	}); err != nil && err != git.NoErrAlreadyUpToDate {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestSourceIgnoresCase(t *testing.T) {
+	pipeline := &fakeSource{}
+	root := t.TempDir()
+	r, err := New(
+		WithDownloader(&fakeDownloader{}),
+		WithExtractor(&fakeExtractor{}),
+		WithSource("", &fakeSource{}),
+		WithSource("studio.internal/pipeline", pipeline),
+		WithPackagePath(filepath.Join(root, "packages")),
+		WithInstallationPath(filepath.Join(root, "installations")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		library string
+		want    types.PackageSource
+	}{
+		{"studio.internal/pipeline/packages", pipeline},
+		{"Studio.Internal/Pipeline/packages", pipeline},
+		{"studio.internal/pipeline-old/packages", r.sources[""]},
+		{"github.com/rocketblend/official-library", r.sources[""]},
+	}
+
+	for _, test := range tests {
+		if got := r.source(test.library); got != test.want {
+			t.Errorf("source(%q) = %p, want %p", test.library, got, test.want)
+		}
+	}
+}
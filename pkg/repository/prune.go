@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/lockfile"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// PruneInstallations removes every installation that does not belong to one of the kept references, followed by
+// the stored artifacts no remaining installation links to. Installations locked by another process, such as one
+// that is still downloading, are skipped.
+func (r *Repository) PruneInstallations(ctx context.Context, opts *types.PruneOpts) (*types.PruneResult, error) {
+	if err := r.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	installations, err := r.findInstallations()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]struct{}, len(opts.Keep))
+	for _, ref := range opts.Keep {
+		keep[filepath.Join(r.installationPath, ref.String())] = struct{}{}
+	}
+
+	result := &types.PruneResult{
+		Removed: []*types.PrunedPath{},
+	}
+
+	// Stored artifacts that are still linked, or may be about to be, must stay.
+	linked := make(map[string]struct{})
+	downloading := false
+	for _, installationPath := range installations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		digest := r.installedDigest(installationPath)
+		if isKept(keep, installationPath) {
+			linked[digest] = struct{}{}
+			continue
+		}
+
+		size, err := dirSize(installationPath)
+		if err != nil {
+			return nil, err
+		}
+
+		pruned := &types.PrunedPath{Path: installationPath, Size: size}
+		locked, err := lockfile.IsLocked(filepath.Join(installationPath, LockFileName))
+		if err != nil {
+			return nil, err
+		}
+
+		if locked {
+			r.logger.Info("installation is locked, skipping", map[string]interface{}{"path": installationPath})
+			result.Skipped = append(result.Skipped, pruned)
+			linked[digest] = struct{}{}
+			downloading = true
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := r.pruneInstallation(ctx, installationPath); err != nil {
+				r.logger.Warn("failed to remove installation, skipping", map[string]interface{}{"error": err, "path": installationPath})
+				result.Skipped = append(result.Skipped, pruned)
+				linked[digest] = struct{}{}
+				continue
+			}
+		}
+
+		result.Removed = append(result.Removed, pruned)
+		result.Size += size
+	}
+
+	// A download in progress links its artifact only once it is stored.
+	if downloading {
+		r.logger.Info("downloads in progress, skipping stored artifacts")
+		return result, nil
+	}
+
+	entries, err := r.findStoreEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make([]string, 0, len(entries))
+	for digest := range entries {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+
+	for _, digest := range digests {
+		if _, ok := linked[digest]; ok {
+			continue
+		}
+
+		storePath := entries[digest]
+		size, err := dirSize(storePath)
+		if err != nil {
+			return nil, err
+		}
+
+		pruned := &types.PrunedPath{Path: storePath, Size: size}
+		locked, err := lockfile.IsLocked(filepath.Join(storePath, LockFileName))
+		if err != nil {
+			return nil, err
+		}
+
+		if locked {
+			result.Skipped = append(result.Skipped, pruned)
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := r.pruneInstallation(ctx, storePath); err != nil {
+				r.logger.Warn("failed to remove stored artifact, skipping", map[string]interface{}{"error": err, "path": storePath})
+				result.Skipped = append(result.Skipped, pruned)
+				continue
+			}
+		}
+
+		result.Removed = append(result.Removed, pruned)
+		result.Size += size
+	}
+
+	return result, nil
+}
+
+// PrunePackages removes every cached library that none of the kept references belong to. Local packages are
+// never removed, as they cannot be fetched again.
+func (r *Repository) PrunePackages(ctx context.Context, opts *types.PruneOpts) (*types.PruneResult, error) {
+	if err := r.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]struct{}, len(opts.Keep))
+	for _, ref := range opts.Keep {
+		if ref.IsLocalOnly() {
+			continue
+		}
+
+		library, err := ref.GetRepo()
+		if err != nil {
+			return nil, err
+		}
+
+		keep[filepath.Join(r.packagePath, library)] = struct{}{}
+	}
+
+	libraries, err := r.findLibraries()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.PruneResult{
+		Removed: []*types.PrunedPath{},
+	}
+
+	for _, libraryPath := range libraries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if isKept(keep, libraryPath) {
+			continue
+		}
+
+		size, err := dirSize(libraryPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !opts.DryRun {
+			r.logger.Info("removing library", map[string]interface{}{"path": libraryPath})
+			if err := os.RemoveAll(libraryPath); err != nil {
+				return nil, err
+			}
+
+			removeEmptyParents(libraryPath, r.packagePath)
+		}
+
+		result.Removed = append(result.Removed, &types.PrunedPath{Path: libraryPath, Size: size})
+		result.Size += size
+	}
+
+	return result, nil
+}
+
+func (r *Repository) pruneInstallation(ctx context.Context, installationPath string) error {
+	cancel, err := r.lock(ctx, installationPath)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	r.logger.Info("removing installation", map[string]interface{}{"path": installationPath})
+	if err := os.RemoveAll(installationPath); err != nil {
+		return err
+	}
+
+	removeEmptyParents(installationPath, r.installationPath)
+
+	return nil
+}
+
+// findInstallations returns the directories in the installation store that hold an installation. A directory
+// is an installation if it holds download state or belongs to a known package.
+func (r *Repository) findInstallations() ([]string, error) {
+	installations := []string{}
+	err := filepath.WalkDir(r.installationPath, func(dirPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() || dirPath == r.installationPath {
+			return nil
+		}
+
+		if dirPath == filepath.Join(r.installationPath, StoreDirName) {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(r.installationPath, dirPath)
+		if err != nil {
+			return err
+		}
+
+		markers := []string{
+			filepath.Join(dirPath, ArtifactFileName),
+			filepath.Join(dirPath, LockFileName),
+			filepath.Join(dirPath, DownloadProgressFileName),
+			filepath.Join(r.packagePath, rel, types.PackageFileName),
+		}
+
+		for _, marker := range markers {
+			if helpers.FileExists(marker) == nil {
+				installations = append(installations, dirPath)
+				return filepath.SkipDir
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return installations, nil
+}
+
+// findLibraries returns the directories of the libraries in the package store, e.g.
+// "<packagePath>/github.com/rocketblend/official-library".
+func (r *Repository) findLibraries() ([]string, error) {
+	// Local packages live directly below the local directory rather than in libraries.
+	localPath := filepath.Join(r.packagePath, "local")
+
+	dirs := []string{r.packagePath}
+	for depth := 0; depth < 3; depth++ {
+		next := []string{}
+		for _, dir := range dirs {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, entry := range entries {
+				entryPath := filepath.Join(dir, entry.Name())
+				if entry.IsDir() && entryPath != localPath {
+					next = append(next, entryPath)
+				}
+			}
+		}
+
+		dirs = next
+	}
+
+	return dirs, nil
+}
+
+// isKept reports whether the path is kept or contains a kept path.
+func isKept(keep map[string]struct{}, path string) bool {
+	if _, ok := keep[path]; ok {
+		return true
+	}
+
+	for kept := range keep {
+		if strings.HasPrefix(kept, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dirSize returns the total size of the regular files below a directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+// removeEmptyParents removes the empty directories between a removed path and the store root.
+func removeEmptyParents(path string, root string) {
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
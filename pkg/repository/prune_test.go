@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/lockfile"
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+const orphanDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+// newPruneRepository installs the references and adds a stored artifact that none of them link to.
+func newPruneRepository(t *testing.T, refs ...reference.Reference) *Repository {
+	t.Helper()
+
+	r := newTestRepository(t, &fakeDownloader{}, &fakeExtractor{})
+	for _, ref := range refs {
+		if _, _, err := r.getInstallation(context.Background(), ref, testPackage(t), nil, true, false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storePath, err := r.storePath(orphanDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(storePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(storePath, CompleteFileName), []byte(orphanDigest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func prunedPaths(paths []*types.PrunedPath) []string {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, path.Path)
+	}
+
+	return result
+}
+
+func assertPaths(t *testing.T, name string, got []*types.PrunedPath, want ...string) {
+	t.Helper()
+
+	paths := prunedPaths(got)
+	if len(paths) != len(want) {
+		t.Errorf("%s = %v, want %v", name, paths, want)
+		return
+	}
+
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("%s = %v, want %v", name, paths, want)
+			return
+		}
+	}
+}
+
+func assertExists(t *testing.T, path string, exists bool) {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if exists && err != nil {
+		t.Errorf("%s was removed: %v", path, err)
+	}
+
+	if !exists && !os.IsNotExist(err) {
+		t.Errorf("%s was not removed: %v", path, err)
+	}
+}
+
+func TestPruneInstallations(t *testing.T) {
+	kept := reference.Reference("builds/blender/4.2.0")
+	unused := reference.Reference("builds/blender/4.1.0")
+	r := newPruneRepository(t, kept, unused)
+
+	digest := testDigest(t)
+	linkedPath, _ := r.storePath(digest)
+	orphanPath, _ := r.storePath(orphanDigest)
+	unusedPath := filepath.Join(r.installationPath, unused.String())
+
+	result, err := r.PruneInstallations(context.Background(), &types.PruneOpts{
+		Keep: []reference.Reference{kept},
+	})
+	if err != nil {
+		t.Fatalf("PruneInstallations() returned unexpected error: %v", err)
+	}
+
+	assertPaths(t, "removed", result.Removed, unusedPath, orphanPath)
+	assertPaths(t, "skipped", result.Skipped)
+
+	assertExists(t, filepath.Join(r.installationPath, kept.String()), true)
+	assertExists(t, linkedPath, true)
+	assertExists(t, unusedPath, false)
+	assertExists(t, orphanPath, false)
+}
+
+func TestPruneInstallationsDryRun(t *testing.T) {
+	unused := reference.Reference("builds/blender/4.1.0")
+	r := newPruneRepository(t, unused)
+
+	linkedPath, _ := r.storePath(testDigest(t))
+	orphanPath, _ := r.storePath(orphanDigest)
+	unusedPath := filepath.Join(r.installationPath, unused.String())
+
+	result, err := r.PruneInstallations(context.Background(), &types.PruneOpts{DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneInstallations() returned unexpected error: %v", err)
+	}
+
+	// The artifact linked by the installation would be removed along with it.
+	assertPaths(t, "removed", result.Removed, unusedPath, orphanPath, linkedPath)
+	if result.Size == 0 {
+		t.Errorf("PruneInstallations() size = 0, want the size of the removed paths")
+	}
+
+	for _, path := range []string{unusedPath, linkedPath, orphanPath} {
+		assertExists(t, path, true)
+	}
+}
+
+func TestPruneInstallationsSkipsLocked(t *testing.T) {
+	stale := reference.Reference("builds/blender/4.1.0")
+	locked := reference.Reference("builds/blender/4.2.0")
+	r := newPruneRepository(t, stale, locked)
+
+	stalePath := filepath.Join(r.installationPath, stale.String())
+	lockedPath := filepath.Join(r.installationPath, locked.String())
+	orphanPath, _ := r.storePath(orphanDigest)
+
+	// A lock left behind by a process that stopped refreshing it no longer counts as held.
+	staleLock := filepath.Join(stalePath, LockFileName)
+	if err := os.WriteFile(staleLock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	staleTime := time.Now().Add(-2 * lockfile.ExecutionTimeout)
+	if err := os.Chtimes(staleLock, staleTime, staleTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(lockedPath, LockFileName), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := r.PruneInstallations(context.Background(), &types.PruneOpts{})
+	if err != nil {
+		t.Fatalf("PruneInstallations() returned unexpected error: %v", err)
+	}
+
+	assertPaths(t, "removed", result.Removed, stalePath)
+	assertPaths(t, "skipped", result.Skipped, lockedPath)
+
+	assertExists(t, stalePath, false)
+	assertExists(t, lockedPath, true)
+
+	// The locked installation may still be storing an artifact, so the store is left alone.
+	assertExists(t, orphanPath, true)
+}
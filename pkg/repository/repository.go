@@ -3,6 +3,8 @@ package repository
 import (
 	"errors"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/rocketblend/rocketblend/pkg/logger"
 	"github.com/rocketblend/rocketblend/pkg/runtime"
@@ -21,6 +23,10 @@ type (
 
 		Downloader types.Downloader
 		Extractor  types.Extractor
+		Verifier   types.Verifier
+		Sources    map[string]types.PackageSource
+
+		Offline bool
 	}
 
 	Option func(*Options)
@@ -30,9 +36,15 @@ type (
 		validator        types.Validator
 		downloader       types.Downloader
 		extractor        types.Extractor
+		verifier         types.Verifier
+		sources          map[string]types.PackageSource
 		platform         runtime.Platform
 		packagePath      string
 		installationPath string
+		offline          bool
+
+		fetches      map[string]*pendingFetch // Artifacts being downloaded, shared by the installations that need them.
+		fetchesMutex sync.Mutex
 	}
 )
 
@@ -60,6 +72,34 @@ func WithExtractor(extractor types.Extractor) Option {
 	}
 }
 
+// WithVerifier requires package definitions to carry a valid detached signature.
+func WithVerifier(verifier types.Verifier) Option {
+	return func(o *Options) {
+		o.Verifier = verifier
+	}
+}
+
+// WithSource fetches the libraries of references starting with the prefix from the source. The source
+// with the longest matching prefix is used, and the source for the empty prefix is the default. Prefixes are
+// matched regardless of case.
+func WithSource(prefix string, source types.PackageSource) Option {
+	return func(o *Options) {
+		if o.Sources == nil {
+			o.Sources = make(map[string]types.PackageSource)
+		}
+
+		o.Sources[strings.ToLower(strings.Trim(prefix, "/"))] = source
+	}
+}
+
+// WithOffline never clones, pulls or downloads remote files. Anything that is not available locally is
+// reported with a types.OfflineError.
+func WithOffline(offline bool) Option {
+	return func(o *Options) {
+		o.Offline = offline
+	}
+}
+
 func WithPackagePath(packagePath string) Option {
 	return func(o *Options) {
 		o.PackagePath = packagePath
@@ -100,6 +140,10 @@ func New(opts ...Option) (*Repository, error) {
 		return nil, errors.New("extractor is nil")
 	}
 
+	if options.Sources[""] == nil {
+		return nil, errors.New("default package source is nil")
+	}
+
 	if options.PackagePath == "" {
 		return nil, errors.New("storage path is empty")
 	}
@@ -118,6 +162,7 @@ func New(opts ...Option) (*Repository, error) {
 
 	options.Logger.Debug("initialising repository", map[string]interface{}{
 		"packagePath": options.PackagePath,
+		"offline":     options.Offline,
 	})
 
 	return &Repository{
@@ -125,8 +170,12 @@ func New(opts ...Option) (*Repository, error) {
 		validator:        options.Validator,
 		downloader:       options.Downloader,
 		extractor:        options.Extractor,
+		verifier:         options.Verifier,
+		sources:          options.Sources,
 		platform:         options.Platform,
 		packagePath:      options.PackagePath,
 		installationPath: options.InstallationPath,
+		offline:          options.Offline,
+		fetches:          make(map[string]*pendingFetch),
 	}, nil
 }
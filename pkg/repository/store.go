@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+// StoreDirName is the directory below the installation path that holds the content-addressed store. Artifacts
+// are extracted once into <store>/<algorithm>/<hex> and every installation that uses them links to that entry
+// with its artifact record.
+const StoreDirName = ".store"
+
+// CompleteFileName marks a store entry whose artifact was stored and extracted completely. Entries without it
+// were interrupted and are never used.
+const CompleteFileName = ".complete"
+
+// pendingFetch is an artifact being downloaded for one of the installations that need it.
+type pendingFetch struct {
+	done   chan struct{}
+	digest string
+	err    error
+}
+
+// storePath returns the store entry for an artifact digest.
+func (r *Repository) storePath(digest string) (string, error) {
+	algorithm, sum, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || sum == "" || strings.ContainsAny(sum, `/\.`) {
+		return "", fmt.Errorf("invalid artifact digest: %q", digest)
+	}
+
+	return filepath.Join(r.installationPath, StoreDirName, algorithm, sum), nil
+}
+
+// findInstallation returns the resource path and artifact digest of an existing installation, or an empty path
+// if it is not installed. Any stored artifact matching the expected digest, the recorded digest or the digest
+// declared by the source is used, relinking the installation when it changes. Installations made before the
+// store existed are found in the installation directory itself.
+func (r *Repository) findInstallation(installationPath string, source *types.Source, expected string) (string, string, error) {
+	recorded := r.installedDigest(installationPath)
+
+	candidates := []string{expected, recorded}
+	if source.SHA256 != "" {
+		candidates = append(candidates, helpers.DigestAlgorithmSHA256+":"+strings.ToLower(source.SHA256))
+	}
+
+	for _, digest := range candidates {
+		if digest == "" {
+			continue
+		}
+
+		storePath, err := r.storePath(digest)
+		if err != nil {
+			return "", "", err
+		}
+
+		if !isStoreComplete(storePath) {
+			continue
+		}
+
+		resourcePath, err := resolveResource(storePath, source)
+		if err != nil {
+			return "", "", err
+		}
+
+		if _, err := os.Stat(resourcePath); err != nil {
+			continue
+		}
+
+		if digest != recorded {
+			if err := r.linkInstallation(installationPath, source, digest); err != nil {
+				return "", "", err
+			}
+		}
+
+		return resourcePath, digest, nil
+	}
+
+	// Legacy installations removed their download progress once extracted, a remaining one means the
+	// installation was interrupted.
+	if _, err := os.Stat(filepath.Join(installationPath, DownloadProgressFileName)); err == nil {
+		return "", "", nil
+	}
+
+	resourcePath, err := resolveResource(installationPath, source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+
+		return "", "", err
+	}
+
+	if _, err := os.Stat(resourcePath); err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+
+		return "", "", err
+	}
+
+	return resourcePath, recorded, nil
+}
+
+// resolveResource returns the path of the source's resource within the extracted artifact at root. The
+// artifact's single top-level directories are unwrapped first, as many as the source strips, ignoring files
+// and hidden entries next to them. The sub path and resource may not lead outside of the artifact.
+func resolveResource(root string, source *types.Source) (string, error) {
+	for i := 0; i < source.StripComponents; i++ {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return "", err
+		}
+
+		var dirs []string
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				dirs = append(dirs, entry.Name())
+			}
+		}
+
+		if len(dirs) != 1 {
+			return "", fmt.Errorf("unable to strip %d components from artifact, found %d top-level directories in %s", source.StripComponents, len(dirs), root)
+		}
+
+		root = filepath.Join(root, dirs[0])
+	}
+
+	for _, name := range []string{source.SubPath, source.Resource} {
+		if name == "" {
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(root, path); err != nil || filepath.IsAbs(name) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("invalid artifact path: %q", name)
+		}
+
+		root = path
+	}
+
+	return root, nil
+}
+
+// linkInstallation records the stored artifact an installation uses.
+func (r *Repository) linkInstallation(installationPath string, source *types.Source, digest string) error {
+	uri := ""
+	if source.URI != nil {
+		uri = source.URI.String()
+	}
+
+	r.logger.Debug("linking installation", map[string]interface{}{
+		"path":   installationPath,
+		"digest": digest,
+	})
+
+	return helpers.Save(r.validator, filepath.Join(installationPath, ArtifactFileName), &artifact{
+		URI:    uri,
+		Digest: digest,
+	}, true, true)
+}
+
+// fetchArtifact downloads the artifact for an installation and links the installation to it. If another
+// installation is already downloading the same artifact, it waits for that download and links to its result
+// instead. Artifacts are the same if they declare the same sha256 digest, or if neither declares one and they
+// share a URI. The caller holds the installation's lock.
+func (r *Repository) fetchArtifact(ctx context.Context, ref reference.Reference, source *types.Source, packageFilePath string, installationPath string, expected string, progress chan<- types.InstallationProgress) (string, error) {
+	if source.URI == nil {
+		return "", fmt.Errorf("no download URI provided")
+	}
+
+	key := source.URI.String()
+	if source.SHA256 != "" {
+		key = helpers.DigestAlgorithmSHA256 + ":" + strings.ToLower(source.SHA256)
+	}
+
+	for {
+		r.fetchesMutex.Lock()
+		pending, ok := r.fetches[key]
+		if !ok {
+			pending = &pendingFetch{done: make(chan struct{})}
+			r.fetches[key] = pending
+		}
+		r.fetchesMutex.Unlock()
+
+		if !ok {
+			pending.digest, pending.err = r.downloadInstallation(ctx, ref, source, packageFilePath, installationPath, expected, progress)
+
+			r.fetchesMutex.Lock()
+			delete(r.fetches, key)
+			r.fetchesMutex.Unlock()
+			close(pending.done)
+
+			return pending.digest, pending.err
+		}
+
+		r.logger.Debug("waiting for artifact to be downloaded", map[string]interface{}{
+			"reference": ref.String(),
+			"artifact":  key,
+		})
+
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+
+		// The other installation may have failed for reasons of its own, such as a different lock.
+		if pending.err != nil {
+			continue
+		}
+
+		if expected != "" && pending.digest != expected {
+			return "", fmt.Errorf("%w: artifact %s", types.ErrDigestMismatch, source.URI.String())
+		}
+
+		if err := r.linkInstallation(installationPath, source, pending.digest); err != nil {
+			return "", err
+		}
+
+		if progress != nil {
+			select {
+			case progress <- types.InstallationProgress{Reference: ref, Phase: types.InstallationPhaseComplete}:
+			case <-ctx.Done():
+			}
+		}
+
+		return pending.digest, nil
+	}
+}
+
+// storeArtifact moves a downloaded artifact into its store entry and extracts it there, marking the entry
+// complete once done. Archives are removed once extracted, other artifacts are kept as they are. If the entry
+// is already complete, the downloaded artifact is discarded. Extraction progress is sent to the optional channel.
+func (r *Repository) storeArtifact(ctx context.Context, downloadedFilePath string, source *types.Source, digest string, progress chan<- types.ExtractProgress) error {
+	storePath, err := r.storePath(digest)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(storePath, 0755); err != nil {
+		return err
+	}
+
+	// Another installation of the same artifact may be storing it right now, wait for it to finish.
+	cancel, err := r.waitLock(ctx, storePath)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if isStoreComplete(storePath) {
+		r.logger.Debug("artifact already stored", map[string]interface{}{"digest": digest, "path": storePath})
+		return os.Remove(downloadedFilePath)
+	}
+
+	if err := clearStoreEntry(storePath); err != nil {
+		return err
+	}
+
+	storedFilePath := filepath.Join(storePath, filepath.Base(downloadedFilePath))
+	if err := os.Rename(downloadedFilePath, storedFilePath); err != nil {
+		return err
+	}
+
+	if helpers.IsSupportedArchive(storedFilePath) {
+		if err := r.extractor.Extract(ctx, &types.ExtractOpts{
+			Path:         storedFilePath,
+			OutputPath:   storePath,
+			ProgressChan: progress,
+		}); err != nil {
+			return err
+		}
+
+		// Extractors may remove the archive themselves.
+		if err := os.Remove(storedFilePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.WriteFile(filepath.Join(storePath, CompleteFileName), []byte(digest), 0644)
+}
+
+// isStoreComplete reports whether the store entry was completely stored.
+func isStoreComplete(storePath string) bool {
+	_, err := os.Stat(filepath.Join(storePath, CompleteFileName))
+	return err == nil
+}
+
+// clearStoreEntry removes whatever an interrupted attempt left in a store entry, except for its lock.
+func clearStoreEntry(storePath string) error {
+	entries, err := os.ReadDir(storePath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == LockFileName {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(storePath, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findStoreEntries returns the digests and paths of every entry in the store.
+func (r *Repository) findStoreEntries() (map[string]string, error) {
+	storeDir := filepath.Join(r.installationPath, StoreDirName)
+	algorithms, err := os.ReadDir(storeDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	entries := make(map[string]string)
+	for _, algorithm := range algorithms {
+		if !algorithm.IsDir() {
+			continue
+		}
+
+		sums, err := os.ReadDir(filepath.Join(storeDir, algorithm.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sum := range sums {
+			if sum.IsDir() {
+				entries[algorithm.Name()+":"+sum.Name()] = filepath.Join(storeDir, algorithm.Name(), sum.Name())
+			}
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,402 @@
+package repository
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rocketblend/rocketblend/pkg/extractor"
+	"github.com/rocketblend/rocketblend/pkg/helpers"
+	"github.com/rocketblend/rocketblend/pkg/reference"
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+const testArtifact = "blender build"
+
+type (
+	// fakeDownloader writes the same artifact for every URI, reporting its progress once. If release is set,
+	// downloads wait for it to be closed after reporting that they started.
+	fakeDownloader struct {
+		downloads atomic.Int32
+		started   chan struct{}
+		release   chan struct{}
+	}
+
+	// fakeExtractor extracts every archive into a directory holding a single resource, reporting its progress
+	// once.
+	fakeExtractor struct {
+		extractions atomic.Int32
+	}
+
+	fakeSource struct {
+		types.PackageSource
+	}
+)
+
+func (d *fakeDownloader) Download(ctx context.Context, opts *types.DownloadOpts) error {
+	d.downloads.Add(1)
+	if d.release != nil {
+		d.started <- struct{}{}
+		<-d.release
+	}
+
+	if opts.ProgressChan != nil {
+		opts.ProgressChan <- types.Progress{Current: int64(len(testArtifact)), Total: int64(len(testArtifact)), Speed: 1, Attempt: 1}
+	}
+
+	return os.WriteFile(opts.Path, []byte(testArtifact), 0644)
+}
+
+func (e *fakeExtractor) Extract(ctx context.Context, opts *types.ExtractOpts) error {
+	e.extractions.Add(1)
+	if opts.ProgressChan != nil {
+		opts.ProgressChan <- types.ExtractProgress{Entries: 1, Current: 1, Total: 1}
+	}
+
+	if err := os.MkdirAll(filepath.Join(opts.OutputPath, "blender"), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(opts.OutputPath, "blender", "blender"), nil, 0755)
+}
+
+func newTestRepository(t *testing.T, downloader *fakeDownloader, extractor *fakeExtractor) *Repository {
+	t.Helper()
+
+	root := t.TempDir()
+	r, err := New(
+		WithDownloader(downloader),
+		WithExtractor(extractor),
+		WithSource("", &fakeSource{}),
+		WithPackagePath(filepath.Join(root, "packages")),
+		WithInstallationPath(filepath.Join(root, "installations")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func testPackage(t *testing.T) *types.Package {
+	t.Helper()
+
+	uri, err := types.NewURI("https://example.com/blender.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &types.Package{
+		Type:    types.PackageBuild,
+		Sources: []*types.Source{{URI: uri, Resource: "blender/blender"}},
+	}
+}
+
+func testDigest(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "artifact")
+	if err := os.WriteFile(path, []byte(testArtifact), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := helpers.DigestFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digest
+}
+
+func TestStoreArtifact(t *testing.T) {
+	extractor := &fakeExtractor{}
+	r := newTestRepository(t, &fakeDownloader{}, extractor)
+	source := testPackage(t).Sources[0]
+	digest := testDigest(t)
+
+	for i := 0; i < 2; i++ {
+		downloadedFilePath := filepath.Join(t.TempDir(), "blender.zip")
+		if err := os.WriteFile(downloadedFilePath, []byte(testArtifact), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.storeArtifact(context.Background(), downloadedFilePath, source, digest, nil); err != nil {
+			t.Fatalf("storeArtifact() returned unexpected error: %v", err)
+		}
+
+		if _, err := os.Stat(downloadedFilePath); !os.IsNotExist(err) {
+			t.Errorf("storeArtifact() left the downloaded artifact behind: %v", err)
+		}
+	}
+
+	storePath, err := r.storePath(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isStoreComplete(storePath) {
+		t.Errorf("store entry %s is not complete", storePath)
+	}
+
+	if _, err := os.Stat(filepath.Join(storePath, "blender.zip")); !os.IsNotExist(err) {
+		t.Errorf("storeArtifact() kept the extracted archive: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(storePath, LockFileName)); !os.IsNotExist(err) {
+		t.Errorf("storeArtifact() did not release the store entry: %v", err)
+	}
+
+	if got := extractor.extractions.Load(); got != 1 {
+		t.Errorf("artifact was extracted %d times, want 1", got)
+	}
+}
+
+// writeTestArchive writes a zip archive holding the resource of the test package.
+func writeTestArchive(t *testing.T, path string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	entry, err := writer.CreateHeader(&zip.FileHeader{Name: "blender/blender", Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := entry.Write([]byte(testArtifact)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreArtifactExtractorCleanup(t *testing.T) {
+	// The extractor may remove the archive itself before the store does.
+	archiveExtractor, err := extractor.New(extractor.WithCleanup())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	r, err := New(
+		WithDownloader(&fakeDownloader{}),
+		WithExtractor(archiveExtractor),
+		WithSource("", &fakeSource{}),
+		WithPackagePath(filepath.Join(root, "packages")),
+		WithInstallationPath(filepath.Join(root, "installations")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloadedFilePath := filepath.Join(t.TempDir(), "blender.zip")
+	writeTestArchive(t, downloadedFilePath)
+
+	digest, err := helpers.DigestFile(downloadedFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.storeArtifact(context.Background(), downloadedFilePath, testPackage(t).Sources[0], digest, nil); err != nil {
+		t.Fatalf("storeArtifact() returned unexpected error: %v", err)
+	}
+
+	storePath, err := r.storePath(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isStoreComplete(storePath) {
+		t.Errorf("store entry %s is not complete", storePath)
+	}
+
+	if _, err := os.Stat(filepath.Join(storePath, "blender.zip")); !os.IsNotExist(err) {
+		t.Errorf("storeArtifact() kept the extracted archive: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(storePath, "blender", "blender")); err != nil {
+		t.Errorf("storeArtifact() did not extract the resource: %v", err)
+	}
+}
+
+func TestGetInstallationSharesDownloads(t *testing.T) {
+	downloader := &fakeDownloader{started: make(chan struct{}), release: make(chan struct{})}
+	extractor := &fakeExtractor{}
+	r := newTestRepository(t, downloader, extractor)
+
+	// Neither package declares a checksum, so the artifact is only known to be shared by its URI.
+	references := []reference.Reference{"builds/blender/4.2.0", "builds/blender-lts/4.2.0"}
+	paths := make([]string, len(references))
+	digests := make([]string, len(references))
+	errs := make([]error, len(references))
+
+	var wg sync.WaitGroup
+	for i, ref := range references {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			installation, digest, err := r.getInstallation(context.Background(), ref, testPackage(t), nil, true, false, nil)
+			if err == nil {
+				paths[i] = installation.Path
+			}
+
+			digests[i], errs[i] = digest, err
+		}()
+
+		if i == 0 {
+			<-downloader.started
+		}
+	}
+
+	// Give the second installation time to find the download in progress.
+	time.Sleep(100 * time.Millisecond)
+	close(downloader.release)
+	wg.Wait()
+
+	for i, ref := range references {
+		if errs[i] != nil {
+			t.Fatalf("getInstallation(%s) returned unexpected error: %v", ref, errs[i])
+		}
+
+		if recorded := r.installedDigest(filepath.Join(r.installationPath, ref.String())); recorded != digests[i] {
+			t.Errorf("installation %s recorded %q, want %q", ref, recorded, digests[i])
+		}
+	}
+
+	if paths[0] != paths[1] || !strings.HasPrefix(paths[0], filepath.Join(r.installationPath, StoreDirName)) {
+		t.Errorf("installations resolved to %q and %q, want the same stored artifact", paths[0], paths[1])
+	}
+
+	if got := downloader.downloads.Load(); got != 1 {
+		t.Errorf("artifact was downloaded %d times, want 1", got)
+	}
+
+	if got := extractor.extractions.Load(); got != 1 {
+		t.Errorf("artifact was extracted %d times, want 1", got)
+	}
+}
+
+func TestGetInstallationRelinks(t *testing.T) {
+	downloader := &fakeDownloader{}
+	r := newTestRepository(t, downloader, &fakeExtractor{})
+	ref := reference.Reference("builds/blender/4.2.0")
+	digest := testDigest(t)
+
+	storePath, err := r.storePath(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(storePath, "blender"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{filepath.Join("blender", "blender"), CompleteFileName} {
+		if err := os.WriteFile(filepath.Join(storePath, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The installation still links to an artifact that was since pruned from the store.
+	installationPath := filepath.Join(r.installationPath, ref.String())
+	if err := r.linkInstallation(installationPath, testPackage(t).Sources[0], "sha256:0000"); err != nil {
+		t.Fatal(err)
+	}
+
+	installation, got, err := r.getInstallation(context.Background(), ref, testPackage(t), &types.LockedPackage{Artifact: digest}, false, false, nil)
+	if err != nil {
+		t.Fatalf("getInstallation() returned unexpected error: %v", err)
+	}
+
+	if got != digest || installation.Path != filepath.Join(storePath, "blender", "blender") {
+		t.Errorf("getInstallation() = %q, %q, want the stored artifact %q", installation.Path, got, digest)
+	}
+
+	if recorded := r.installedDigest(installationPath); recorded != digest {
+		t.Errorf("installation recorded %q, want it relinked to %q", recorded, digest)
+	}
+
+	if _, err := os.Stat(filepath.Join(installationPath, LockFileName)); !os.IsNotExist(err) {
+		t.Errorf("getInstallation() did not release the installation: %v", err)
+	}
+
+	if got := downloader.downloads.Load(); got != 0 {
+		t.Errorf("artifact was downloaded %d times, want 0", got)
+	}
+}
+
+func TestResolveResource(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"blender-4.2.0-linux-x64/bin", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "blender-4.2.0-linux-x64.tar.xz"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		source  *types.Source
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "resource",
+			source: &types.Source{Resource: "blender-4.2.0-linux-x64/bin/blender"},
+			want:   "blender-4.2.0-linux-x64/bin/blender",
+		},
+		{
+			name:   "strip components",
+			source: &types.Source{StripComponents: 1, Resource: "bin/blender"},
+			want:   "blender-4.2.0-linux-x64/bin/blender",
+		},
+		{
+			name:   "strip components and sub path",
+			source: &types.Source{StripComponents: 1, SubPath: "bin", Resource: "blender"},
+			want:   "blender-4.2.0-linux-x64/bin/blender",
+		},
+		{
+			name:    "strip too many components",
+			source:  &types.Source{StripComponents: 3, Resource: "blender"},
+			wantErr: true,
+		},
+		{
+			name:    "escaping sub path",
+			source:  &types.Source{SubPath: "../..", Resource: "blender"},
+			wantErr: true,
+		},
+		{
+			name:    "escaping resource",
+			source:  &types.Source{StripComponents: 1, Resource: "../../blender"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveResource(root, tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveResource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != filepath.Join(root, filepath.FromSlash(tt.want)) {
+				t.Errorf("resolveResource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	operator string
+
+	comparator struct {
+		op      operator
+		version Version
+	}
+
+	// Constraint is a set of version ranges, any one of which a version must satisfy.
+	//
+	// Ranges are separated by "||" and consist of space separated comparators which must all match,
+	// e.g. "^4.2", "~1.3.2", ">=1.3 <2", "4.x" or "1.2 || >=3.0.0-beta".
+	Constraint struct {
+		raw    string
+		ranges [][]comparator
+	}
+)
+
+const (
+	opEqual          operator = "="
+	opGreater        operator = ">"
+	opGreaterOrEqual operator = ">="
+	opLess           operator = "<"
+	opLessOrEqual    operator = "<="
+)
+
+// ParseConstraint parses a version constraint string.
+func ParseConstraint(s string) (*Constraint, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	constraint := &Constraint{raw: s}
+	for _, group := range strings.Split(s, "||") {
+		tokens := strings.Fields(group)
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("invalid version constraint: %q", s)
+		}
+
+		comparators := make([]comparator, 0, len(tokens))
+		for i := 0; i < len(tokens); i++ {
+			token := tokens[i]
+
+			// Allow a space between the operator and the version, e.g. ">= 1.3".
+			if strings.Trim(token, "<>=^~") == "" && i+1 < len(tokens) {
+				i++
+				token += tokens[i]
+			}
+
+			parsed, err := parseComparator(token)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+
+			comparators = append(comparators, parsed...)
+		}
+
+		constraint.ranges = append(constraint.ranges, comparators)
+	}
+
+	return constraint, nil
+}
+
+// Check reports whether the version satisfies the constraint.
+//
+// Prerelease versions are only matched by a range that explicitly mentions a prerelease of the same
+// MAJOR.MINOR.PATCH, so "^4.2" never selects "4.3.0-alpha".
+func (c *Constraint) Check(v Version) bool {
+	for _, comparators := range c.ranges {
+		if checkRange(comparators, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the constraint as it was parsed.
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+func checkRange(comparators []comparator, v Version) bool {
+	for _, comp := range comparators {
+		if !comp.check(v) {
+			return false
+		}
+	}
+
+	if v.Prerelease == "" {
+		return true
+	}
+
+	for _, comp := range comparators {
+		if comp.version.Prerelease != "" &&
+			comp.version.Major == v.Major &&
+			comp.version.Minor == v.Minor &&
+			comp.version.Patch == v.Patch {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case opEqual:
+		return cmp == 0
+	case opGreater:
+		return cmp > 0
+	case opGreaterOrEqual:
+		return cmp >= 0
+	case opLess:
+		return cmp < 0
+	case opLessOrEqual:
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// parseComparator expands a single comparator, including caret, tilde and partial versions,
+// into primitive bounds.
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			s = strings.TrimPrefix(s, prefix)
+			break
+		}
+	}
+
+	version, parts, err := parsePartial(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if parts == 0 {
+		switch op {
+		case "", "=", ">=", "<=", "^", "~":
+			return nil, nil // Any version.
+		default:
+			return nil, fmt.Errorf("invalid comparator: %q", op+s)
+		}
+	}
+
+	// next returns the first version after the partial version, e.g. 4.2 -> 4.3.0.
+	next := func(parts int) Version {
+		switch parts {
+		case 1:
+			return NewVersion(version.Major+1, 0, 0)
+		case 2:
+			return NewVersion(version.Major, version.Minor+1, 0)
+		default:
+			return NewVersion(version.Major, version.Minor, version.Patch+1)
+		}
+	}
+
+	switch op {
+	case "", "=":
+		if parts == 3 {
+			return []comparator{{opEqual, version}}, nil
+		}
+
+		return []comparator{{opGreaterOrEqual, version}, {opLess, next(parts)}}, nil
+	case ">":
+		if parts == 3 {
+			return []comparator{{opGreater, version}}, nil
+		}
+
+		return []comparator{{opGreaterOrEqual, next(parts)}}, nil
+	case ">=":
+		return []comparator{{opGreaterOrEqual, version}}, nil
+	case "<":
+		return []comparator{{opLess, version}}, nil
+	case "<=":
+		if parts == 3 {
+			return []comparator{{opLessOrEqual, version}}, nil
+		}
+
+		return []comparator{{opLess, next(parts)}}, nil
+	case "~":
+		if parts == 1 {
+			return []comparator{{opGreaterOrEqual, version}, {opLess, next(1)}}, nil
+		}
+
+		return []comparator{{opGreaterOrEqual, version}, {opLess, next(2)}}, nil
+	case "^":
+		// Allow changes that do not modify the left-most non-zero component.
+		upper := next(1)
+		switch {
+		case version.Major == 0 && version.Minor == 0 && parts == 3:
+			upper = next(3)
+		case version.Major == 0 && parts >= 2:
+			upper = next(2)
+		}
+
+		return []comparator{{opGreaterOrEqual, version}, {opLess, upper}}, nil
+	}
+
+	return nil, fmt.Errorf("invalid comparator: %q", op+s)
+}
+
+// parsePartial parses a version where trailing components may be omitted or wildcards ("x", "X" or "*").
+// It returns the version with missing components set to zero and the number of components given.
+func parsePartial(s string) (Version, int, error) {
+	if s == "" {
+		return Version{}, 0, fmt.Errorf("missing version")
+	}
+
+	core, build, hasBuild := strings.Cut(s, "+")
+	if hasBuild {
+		if err := validateIdentifiers(build, false); err != nil {
+			return Version{}, 0, fmt.Errorf("invalid build metadata: %q", s)
+		}
+	}
+
+	core, prerelease, hasPrerelease := strings.Cut(core, "-")
+
+	fields := strings.Split(core, ".")
+	if len(fields) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version: %q", s)
+	}
+
+	numbers := make([]int, 0, 3)
+	wildcard := false
+	for _, field := range fields {
+		isWildcard := field == "x" || field == "X" || field == "*"
+		if wildcard || isWildcard {
+			// Every component after a wildcard must be one too, e.g. 1.x.x but not 1.x.3.
+			if !isWildcard {
+				return Version{}, 0, fmt.Errorf("invalid version, component after wildcard: %q", s)
+			}
+
+			wildcard = true
+			continue
+		}
+
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return Version{}, 0, fmt.Errorf("invalid version: %q", s)
+		}
+
+		numbers = append(numbers, n)
+	}
+
+	if hasPrerelease && len(numbers) != 3 {
+		return Version{}, 0, fmt.Errorf("prerelease requires a full version: %q", s)
+	}
+
+	if hasPrerelease {
+		if err := validateIdentifiers(prerelease, true); err != nil {
+			return Version{}, 0, fmt.Errorf("invalid prerelease: %q", s)
+		}
+	}
+
+	parts := len(numbers)
+	for len(numbers) < 3 {
+		numbers = append(numbers, 0)
+	}
+
+	return Version{
+		Major:      numbers[0],
+		Minor:      numbers[1],
+		Patch:      numbers[2],
+		Prerelease: prerelease,
+		Build:      build,
+	}, parts, nil
+}
@@ -0,0 +1,94 @@
+package semver
+
+import "testing"
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		s   string
+		err bool
+	}{
+		{"^4.2", false},
+		{"~1.3.2", false},
+		{">=1.3 <2", false},
+		{">= 1.3, <2", true},
+		{"4.x", false},
+		{"*", false},
+		{"1.2 || >=3.0.0-beta", false},
+		{"", true},
+		{"||", true},
+		{">x", true},
+		{"^4.2-beta", true},
+		{"1.2.3.4", true},
+		{"latest", true},
+		{"1.x.x", false},
+		{"1.x.3", true},
+		{"*.2", true},
+		{"1.2.3+build.5", false},
+		{"1.2.3+", true},
+		{"1.2.3+build..5", true},
+		{"1.2.3+build_5", true},
+	}
+
+	for _, test := range tests {
+		_, err := ParseConstraint(test.s)
+		if err != nil && !test.err {
+			t.Errorf("ParseConstraint(%q) returned unexpected error: %v", test.s, err)
+		}
+		if err == nil && test.err {
+			t.Errorf("ParseConstraint(%q) did not return an error as expected", test.s)
+		}
+	}
+}
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"^4.2", "4.2.0", true},
+		{"^4.2", "4.9.1", true},
+		{"^4.2", "5.0.0", false},
+		{"^4.2", "4.1.9", false},
+		{"^4.2", "4.3.0-alpha", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.3.2", "1.3.9", true},
+		{"~1.3.2", "1.4.0", false},
+		{"~1", "1.9.0", true},
+		{">=1.3 <2", "1.3.0", true},
+		{">=1.3 <2", "1.99.0", true},
+		{">=1.3 <2", "2.0.0", false},
+		{">=1.3 <2", "2.0.0-rc.1", false},
+		{">= 1.3", "1.4.0", true},
+		{"4.x", "4.5.6", true},
+		{"4.2", "4.2.7", true},
+		{"4.2", "4.3.0", false},
+		{"4.2.1", "4.2.1+lts", true},
+		{">4.2", "4.2.9", false},
+		{">4.2", "4.3.0", true},
+		{"<=4.2", "4.2.9", true},
+		{"<=4.2", "4.3.0", false},
+		{"*", "0.0.1", true},
+		{"1.2 || >=3.0.0-beta", "3.0.0-beta.2", true},
+		{"1.2 || >=3.0.0-beta", "2.0.0", false},
+		{"1.2 || >=3.0.0-beta", "1.2.5", true},
+	}
+
+	for _, test := range tests {
+		c, err := ParseConstraint(test.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q) returned unexpected error: %v", test.constraint, err)
+		}
+
+		v, err := Parse(test.version)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned unexpected error: %v", test.version, err)
+		}
+
+		if got := c.Check(*v); got != test.want {
+			t.Errorf("ParseConstraint(%q).Check(%q) = %v, want %v", test.constraint, test.version, got, test.want)
+		}
+	}
+}
@@ -8,9 +8,11 @@ import (
 )
 
 type Version struct {
-	Major int
-	Minor int
-	Patch int
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
 }
 
 // NewVersion returns a new SemVer with the given major, minor, and patch numbers.
@@ -18,9 +20,23 @@ func NewVersion(major, minor, patch int) Version {
 	return Version{Major: major, Minor: minor, Patch: patch}
 }
 
-// Parse parses a string in the format "MAJOR.MINOR.PATCH" into a SemVer.
+// Parse parses a string in the format "MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]" into a SemVer.
 func Parse(s string) (*Version, error) {
-	parts := strings.Split(s, ".")
+	version, build, _ := strings.Cut(s, "+")
+	if strings.Contains(s, "+") {
+		if err := validateIdentifiers(build, false); err != nil {
+			return nil, fmt.Errorf("invalid SemVer build metadata: %q", build)
+		}
+	}
+
+	version, prerelease, hasPrerelease := strings.Cut(version, "-")
+	if hasPrerelease {
+		if err := validateIdentifiers(prerelease, true); err != nil {
+			return nil, fmt.Errorf("invalid SemVer prerelease: %q", prerelease)
+		}
+	}
+
+	parts := strings.Split(version, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("invalid SemVer string: %q", s)
 	}
@@ -40,12 +56,58 @@ func Parse(s string) (*Version, error) {
 		return nil, fmt.Errorf("invalid SemVer patch version: %q", parts[2])
 	}
 
-	return &Version{Major: major, Minor: minor, Patch: patch}, nil
+	if major < 0 || minor < 0 || patch < 0 {
+		return nil, fmt.Errorf("invalid SemVer string: %q", s)
+	}
+
+	return &Version{Major: major, Minor: minor, Patch: patch, Prerelease: prerelease, Build: build}, nil
 }
 
-// String returns a string representation of the Version in the format "MAJOR.MINOR.PATCH".
+// String returns a string representation of the Version in the format "MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]".
 func (v Version) String() string {
-	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+
+	return s
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is lower than, equal to or greater than o.
+// Build metadata is ignored and a prerelease version has lower precedence than its release.
+func (v Version) Compare(o Version) int {
+	if c := compareInt(v.Major, o.Major); c != 0 {
+		return c
+	}
+
+	if c := compareInt(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+
+	if c := compareInt(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// LessThan reports whether v has lower precedence than o.
+func (v Version) LessThan(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// GreaterThan reports whether v has higher precedence than o.
+func (v Version) GreaterThan(o Version) bool {
+	return v.Compare(o) > 0
+}
+
+// Equal reports whether v and o have the same precedence.
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -70,3 +132,70 @@ func (v *Version) UnmarshalJSON(data []byte) error {
 func (v Version) MarshalJSON() ([]byte, error) {
 	return json.Marshal(v.String())
 }
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = compareInt(aNum, bNum)
+		case aErr == nil:
+			c = -1 // Numeric identifiers have lower precedence than alphanumeric ones.
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(aParts[i], bParts[i])
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return compareInt(len(aParts), len(bParts))
+}
+
+func validateIdentifiers(s string, numeric bool) error {
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return fmt.Errorf("empty identifier")
+		}
+
+		for _, r := range part {
+			if !(r >= '0' && r <= '9') && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && r != '-' {
+				return fmt.Errorf("invalid character %q", r)
+			}
+		}
+
+		if _, err := strconv.Atoi(part); numeric && err == nil && len(part) > 1 && part[0] == '0' {
+			return fmt.Errorf("leading zero in numeric identifier")
+		}
+	}
+
+	return nil
+}
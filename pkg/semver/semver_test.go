@@ -38,6 +38,12 @@ func TestParse(t *testing.T) {
 		{"1.2", "", true},
 		{"1.2.3.4", "", true},
 		{"invalid", "", true},
+		{"4.2.0-beta.1", "4.2.0-beta.1", false},
+		{"4.2.0+lts", "4.2.0+lts", false},
+		{"4.2.0-rc.1+build.5", "4.2.0-rc.1+build.5", false},
+		{"4.2.0-", "", true},
+		{"4.2.0-01", "", true},
+		{"4.2.0+", "", true},
 	}
 
 	for _, test := range tests {
@@ -162,3 +168,38 @@ func TestUnmarshalAndMarshalRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.3.0", "1.2.9", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+	}
+
+	for _, test := range tests {
+		a, err := Parse(test.a)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned unexpected error: %v", test.a, err)
+		}
+
+		b, err := Parse(test.b)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned unexpected error: %v", test.b, err)
+		}
+
+		if got := a.Compare(*b); got != test.want {
+			t.Errorf("%q.Compare(%q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -26,20 +26,61 @@ type (
 	}
 
 	RenderOpts struct {
-		Start   int          `json:"start"`
-		End     int          `json:"end"`
-		Step    int          `json:"step"`
-		Output  string       `json:"output"`
-		Format  string       `json:"format"`
-		Engine  RenderEngine `json:"engine" validate:"omitempty,oneof=cycles eevee workbench"`
-		Threads int          `json:"threads" validate:"omitempty,gte=0,lte=1024"`
+		Start                int             `json:"start"`
+		End                  int             `json:"end"`
+		Step                 int             `json:"step"`
+		Output               string          `json:"output"`
+		Format               string          `json:"format"`
+		Engine               RenderEngine    `json:"engine" validate:"omitempty,oneof=cycles eevee workbench"`
+		Threads              int             `json:"threads" validate:"omitempty,gte=0,lte=1024"`
+		Devices              []RenderDevice  `json:"devices,omitempty" validate:"omitempty,unique,onegpu,dive,oneof=cpu cuda optix hip oneapi metal"` // Cycles devices, the CPU can be combined with one other.
+		ResolutionX          int             `json:"resolutionX,omitempty" validate:"omitempty,gte=4,lte=65536"`
+		ResolutionY          int             `json:"resolutionY,omitempty" validate:"omitempty,gte=4,lte=65536"`
+		ResolutionPercentage int             `json:"resolutionPercentage,omitempty" validate:"omitempty,gte=1,lte=32767"`
+		Samples              int             `json:"samples,omitempty" validate:"omitempty,gte=1,lte=16777216"`
+		Denoiser             Denoiser        `json:"denoiser,omitempty" validate:"omitempty,oneof=off optix openimagedenoise"`
+		Scene                string          `json:"scene,omitempty"`
+		Camera               string          `json:"camera,omitempty"`
+		ViewLayer            string          `json:"viewLayer,omitempty"`
+		OutputSettings       *OutputSettings `json:"outputSettings,omitempty" validate:"omitempty"` // Which settings apply depends on the format.
 		BlenderOpts
 	}
 
+	// OutputSettings configure the files written by a render. Values are named as in Blender, in lower case.
+	OutputSettings struct {
+		ColorDepth    string `json:"colorDepth,omitempty"`    // Bits per channel, 8 or 16 for PNG, 16 or 32 for EXR.
+		Codec         string `json:"codec,omitempty"`         // EXR codec, such as dwaa, or FFMPEG video codec, such as h264.
+		Compression   *int   `json:"compression,omitempty"`   // PNG compression, 0 to 100.
+		ViewTransform string `json:"viewTransform,omitempty"` // Colour management view transform, such as AgX or Standard.
+		Container     string `json:"container,omitempty"`     // FFMPEG container, such as mpeg4 or mkv.
+		Bitrate       int    `json:"bitrate,omitempty"`       // FFMPEG video bitrate in kbit/s.
+	}
+
 	RunOpts struct {
 		BlenderOpts
 	}
 
+	DevicesOpts struct {
+		Build *Installation `json:"build" validate:"required"`
+	}
+
+	// ComputeDevice is a device that Cycles can render on.
+	ComputeDevice struct {
+		ID   string `json:"id" mapstructure:"id"`
+		Name string `json:"name" mapstructure:"name"`
+		Type string `json:"type" mapstructure:"type"`
+	}
+
+	// ComputeBackend is a compute backend compiled into a build, with the devices found for it.
+	ComputeBackend struct {
+		Type    RenderDevice    `json:"type" mapstructure:"type"`
+		Devices []ComputeDevice `json:"devices" mapstructure:"devices"`
+	}
+
+	DevicesResult struct {
+		Backends []ComputeBackend `json:"backends"`
+	}
+
 	CreateOpts struct {
 		BlenderOpts
 		Overwrite bool `json:"overwrite"`
@@ -49,6 +90,7 @@ type (
 		Render(ctx context.Context, opts *RenderOpts) error
 		Run(ctx context.Context, opts *RunOpts) error
 		Create(ctx context.Context, opts *CreateOpts) error
+		Devices(ctx context.Context, opts *DevicesOpts) (*DevicesResult, error)
 	}
 )
 
@@ -17,11 +17,43 @@ type (
 	// QuitEvent represents the "Blender quit" event.
 	QuitEvent struct{}
 
-	// SavedFileEvent represents a file saved by Blender.
+	// ReadyEvent is sent once Blender has registered the handlers that report events, naming them.
+	ReadyEvent struct {
+		Version  string   `mapstructure:"version"`
+		Handlers []string `mapstructure:"handlers"`
+	}
+
+	// DevicesEvent reports the compute backends of a build, sent by the devices probe.
+	DevicesEvent struct {
+		Backends []ComputeBackend `mapstructure:"backends"`
+	}
+
+	// SavedFileEvent represents a rendered frame saved by Blender.
 	SavedFileEvent struct {
+		Path  string `mapstructure:"path"`
+		Frame int    `mapstructure:"frame"`
+	}
+
+	// BlendFileSavedEvent represents the blend file being saved by Blender.
+	BlendFileSavedEvent struct {
 		Path string `mapstructure:"path"`
 	}
 
+	// RenderStartEvent represents Blender starting to render a frame.
+	RenderStartEvent struct {
+		Frame int `mapstructure:"frame"`
+	}
+
+	// RenderCompleteEvent represents Blender finishing rendering a frame.
+	RenderCompleteEvent struct {
+		Frame int `mapstructure:"frame"`
+	}
+
+	// RenderCancelEvent represents a render being cancelled.
+	RenderCancelEvent struct {
+		Frame int `mapstructure:"frame"`
+	}
+
 	// RenderBase represents common fields for all rendering-related Blender events.
 	RenderBase struct {
 		Frame      int    `mapstructure:"frame"`
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/rocketblend/rocketblend/pkg/reference"
 	"github.com/rocketblend/rocketblend/pkg/runtime"
 )
@@ -22,6 +24,36 @@ type (
 		InstallationsPath string              `mapstructure:"installationsPath"`
 		PackagesPath      string              `mapstructure:"packagesPath"`
 		Aliases           map[string]string   `mapstructure:"aliases"`
+		TrustedKeys       []string            `mapstructure:"trustedKeys"`                     // Public key files used to verify package signatures.
+		Offline           bool                `mapstructure:"offline"`                         // Never access the network, only use local caches.
+		Projects          []string            `mapstructure:"projects"`                        // Directories scanned for projects when pruning.
+		Retry             RetryConfig         `mapstructure:"retry"`                           // How failed downloads are retried.
+		DownloadChunks    int                 `mapstructure:"downloadChunks" validate:"gte=1"` // Concurrent range requests per large download.
+
+		// MaxConcurrentDownloads limits how many connections download at once, counting each chunk of a chunked
+		// download, zero for no limit.
+		MaxConcurrentDownloads int `mapstructure:"maxConcurrentDownloads" validate:"gte=0"`
+
+		// MaxDownloadRate caps the combined speed of all downloads, such as "10MB" per second, empty for no limit.
+		MaxDownloadRate string `mapstructure:"maxDownloadRate"`
+
+		// Network sets the proxy, extra certificate authorities and credentials file for downloads and git.
+		Network NetworkConfig `mapstructure:"network"`
+
+		// PackageSources overrides where libraries are fetched from, keyed by reference prefix, e.g.
+		// "studio.internal/pipeline". The longest matching prefix wins, other libraries are cloned with git. Prefixes
+		// are matched regardless of case, as the configuration does not keep the case of keys.
+		PackageSources map[string]*PackageSourceConfig `mapstructure:"packageSources" validate:"omitempty,dive,required"`
+	}
+
+	// RetryConfig controls how failed downloads are retried. The delay before each retry doubles, starting at
+	// Backoff and capped at MaxBackoff, and is randomised by the Jitter fraction.
+	RetryConfig struct {
+		Attempts    int           `mapstructure:"attempts" validate:"gte=1"`
+		Backoff     time.Duration `mapstructure:"backoff" validate:"gte=0"`
+		MaxBackoff  time.Duration `mapstructure:"maxBackoff" validate:"gte=0"`
+		Jitter      float64       `mapstructure:"jitter" validate:"gte=0,lte=1"`
+		StatusCodes []int         `mapstructure:"statusCodes"` // HTTP status codes worth retrying.
 	}
 
 	Configurator interface {
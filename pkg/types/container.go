@@ -6,9 +6,12 @@ type (
 		GetValidator() (Validator, error)
 		GetDownloader() (Downloader, error)
 		GetExtractor() (Extractor, error)
+		GetVerifier() (Verifier, error)
 		GetConfigurator() (Configurator, error)
 		GetRepository() (Repository, error)
 		GetDriver() (Driver, error)
 		GetBlender() (Blender, error)
+		GetCoordinator() (Coordinator, error)
+		GetWorker() (Worker, error)
 	}
 )
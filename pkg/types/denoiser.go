@@ -0,0 +1,12 @@
+package types
+
+type (
+	Denoiser string
+)
+
+const (
+	DenoiserDefault          Denoiser = ""
+	DenoiserOff              Denoiser = "off"
+	DenoiserOptiX            Denoiser = "optix"
+	DenoiserOpenImageDenoise Denoiser = "openimagedenoise"
+)
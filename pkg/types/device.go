@@ -0,0 +1,14 @@
+package types
+
+type (
+	RenderDevice string
+)
+
+const (
+	DeviceCPU    RenderDevice = "cpu"
+	DeviceCUDA   RenderDevice = "cuda"
+	DeviceOptiX  RenderDevice = "optix"
+	DeviceHIP    RenderDevice = "hip"
+	DeviceOneAPI RenderDevice = "oneapi"
+	DeviceMetal  RenderDevice = "metal"
+)
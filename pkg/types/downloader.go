@@ -4,13 +4,16 @@ import "context"
 
 type (
 	Progress struct {
-		Current int64   `json:"current"` // current bytes read
-		Total   int64   `json:"total"`   // total bytes of the file
-		Speed   float64 `json:"speed"`   // bytes per second
+		Current int64   `json:"current"`           // current bytes read
+		Total   int64   `json:"total"`             // total bytes of the file
+		Speed   float64 `json:"speed"`             // bytes per second
+		URI     string  `json:"uri,omitempty"`     // URI being downloaded, which may be a mirror
+		Attempt int     `json:"attempt,omitempty"` // attempt number for the URI, starting at 1
 	}
 
 	DownloadOpts struct {
 		URI          *URI            `json:"uri" validate:"required"`
+		Mirrors      []*URI          `json:"mirrors,omitempty" validate:"omitempty,dive,required"` // Tried in order if the URI fails.
 		Path         string          `json:"path" validate:"required"`
 		ProgressChan chan<- Progress `json:"-"`
 	}
@@ -16,6 +16,7 @@ type (
 
 	ResolveProfilesOpts struct {
 		Profiles []*Profile `json:"profiles" validate:"required,dive,required"`
+		Frozen   bool       `json:"frozen"` // Fail if a profile lock is missing or does not match the profile.
 	}
 
 	ResolveProfilesResult struct {
@@ -28,7 +29,9 @@ type (
 	}
 
 	InstallProfilesOpts struct {
-		Profiles []*Profile `json:"profiles" validate:"required,dive,required"`
+		Profiles []*Profile                  `json:"profiles" validate:"required,dive,required"`
+		Frozen   bool                        `json:"frozen"` // Fail if a profile lock is missing or does not match the profile.
+		Progress chan<- InstallationProgress `json:"-"`      // Optional, receives updates while fetching installations.
 	}
 
 	SaveProfilesOpts struct {
@@ -37,11 +40,22 @@ type (
 		Overwrite   bool                `json:"overwrite"`
 	}
 
+	PruneProfilesOpts struct {
+		Profiles []*Profile `json:"profiles" validate:"required,dive,required"` // Every profile whose dependencies must be kept.
+		DryRun   bool       `json:"dryRun"`
+	}
+
+	PruneProfilesResult struct {
+		Installations *PruneResult `json:"installations"`
+		Packages      *PruneResult `json:"packages"`
+	}
+
 	Driver interface {
 		LoadProfiles(ctx context.Context, opts *LoadProfilesOpts) (*LoadProfilesResult, error)
 		ResolveProfiles(ctx context.Context, opts *ResolveProfilesOpts) (*ResolveProfilesResult, error)
 		TidyProfiles(ctx context.Context, opts *TidyProfilesOpts) error
 		InstallProfiles(ctx context.Context, opts *InstallProfilesOpts) error
 		SaveProfiles(ctx context.Context, opts *SaveProfilesOpts) error
+		PruneProfiles(ctx context.Context, opts *PruneProfilesOpts) (*PruneProfilesResult, error)
 	}
 )
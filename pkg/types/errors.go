@@ -1,10 +1,113 @@
 package types
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+)
 
 var (
 	ErrFileNotFound = errors.New("file not found")
 	ErrFileExists   = errors.New("file already exists")
 
 	ErrMissingBlenderBuild = errors.New("missing blender build")
+
+	ErrMissingProfileLock  = errors.New("missing profile lock")
+	ErrProfileLockMismatch = errors.New("profile lock does not match profile")
+	ErrDigestMismatch      = errors.New("digest mismatch")
+	ErrMissingDigest       = errors.New("missing digest")
+
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	ErrNoMatchingVersion   = errors.New("no matching version")
+	ErrUnresolvedReference = errors.New("unresolved version range")
+
+	ErrDependencyCycle    = errors.New("dependency cycle")
+	ErrDependencyConflict = errors.New("dependency conflict")
+	ErrIncompatibleBuild  = errors.New("incompatible build")
+
+	ErrOffline = errors.New("offline")
 )
+
+// ChecksumError is returned when a downloaded artifact does not match the checksum declared by its source.
+type ChecksumError struct {
+	URI       string
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch for %s: expected %s, got %s", e.Algorithm, e.URI, e.Expected, e.Actual)
+}
+
+func (e *ChecksumError) Unwrap() error {
+	return ErrDigestMismatch
+}
+
+// OfflineError lists the package references and artifacts that are required but not available locally
+// while operating offline.
+type OfflineError struct {
+	References []reference.Reference
+	Artifacts  []string
+}
+
+func (e *OfflineError) Error() string {
+	parts := make([]string, 0, 2)
+	if len(e.References) > 0 {
+		references := make([]string, 0, len(e.References))
+		for _, ref := range e.References {
+			references = append(references, string(ref))
+		}
+
+		parts = append(parts, "missing packages: "+strings.Join(references, ", "))
+	}
+
+	if len(e.Artifacts) > 0 {
+		parts = append(parts, "missing artifacts: "+strings.Join(e.Artifacts, ", "))
+	}
+
+	return fmt.Sprintf("offline, %s", strings.Join(parts, "; "))
+}
+
+func (e *OfflineError) Unwrap() error {
+	return ErrOffline
+}
+
+// Merge adds the missing references and artifacts of another offline error, skipping duplicates.
+func (e *OfflineError) Merge(other *OfflineError) {
+	for _, ref := range other.References {
+		if !containsReference(e.References, ref) {
+			e.References = append(e.References, ref)
+		}
+	}
+
+	for _, artifact := range other.Artifacts {
+		if !containsString(e.Artifacts, artifact) {
+			e.Artifacts = append(e.Artifacts, artifact)
+		}
+	}
+}
+
+func containsReference(refs []reference.Reference, ref reference.Reference) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
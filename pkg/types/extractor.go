@@ -3,9 +3,19 @@ package types
 import "context"
 
 type (
+	// ExtractProgress reports on an archive being extracted. Bytes are counted as they are read from the archive,
+	// so compressed archives are measured against their size on disk. Disk images count the bytes of the files
+	// copied out of them instead.
+	ExtractProgress struct {
+		Entries int64 `json:"entries"` // Entries extracted.
+		Current int64 `json:"current"` // Bytes processed.
+		Total   int64 `json:"total"`   // Total bytes to process.
+	}
+
 	ExtractOpts struct {
-		Path       string `json:"path" validate:"required"`
-		OutputPath string `json:"outputPath" validate:"required"`
+		Path         string                 `json:"path" validate:"required"`
+		OutputPath   string                 `json:"outputPath" validate:"required"`
+		ProgressChan chan<- ExtractProgress `json:"-"` // Optional, receives updates while extracting.
 	}
 
 	Extractor interface {
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	ChunkStatePending   ChunkState = "pending"
+	ChunkStateRendering ChunkState = "rendering"
+	ChunkStateComplete  ChunkState = "complete"
+	ChunkStateFailed    ChunkState = "failed"
+)
+
+type (
+	// ChunkState is the step a chunk of a distributed render is at.
+	ChunkState string
+
+	// Chunk is a part of the frame range of a distributed render, rendered by one worker at a time.
+	Chunk struct {
+		ID       int        `json:"id" mapstructure:"id"`
+		Start    int        `json:"start" mapstructure:"start"`
+		End      int        `json:"end" mapstructure:"end"`
+		State    ChunkState `json:"state" mapstructure:"state"`
+		Worker   string     `json:"worker,omitempty" mapstructure:"worker"` // Worker rendering the chunk, or the last one that did.
+		Attempts int        `json:"attempts" mapstructure:"attempts"`       // Times the chunk was handed to a worker.
+		Error    string     `json:"error,omitempty" mapstructure:"error"`   // Why the last attempt failed.
+	}
+
+	// ChunkAssignment hands a chunk to a worker.
+	ChunkAssignment struct {
+		Chunk             Chunk         `json:"chunk"`
+		Opts              RenderOpts    `json:"opts"`              // Shared by every chunk, the worker sets the frame range and blend file.
+		HeartbeatInterval time.Duration `json:"heartbeatInterval"` // How often the worker reports while rendering.
+	}
+
+	// ChunkEvent is sent by the coordinator when a chunk changes state.
+	ChunkEvent struct {
+		Chunk Chunk `mapstructure:"chunk"`
+	}
+
+	// WorkerEvent is a Blender event reported by a worker while rendering a chunk.
+	WorkerEvent struct {
+		Worker string       `mapstructure:"worker"`
+		Chunk  int          `mapstructure:"chunk"`
+		Event  BlenderEvent `mapstructure:"event"`
+	}
+
+	CoordinateOpts struct {
+		Listener  net.Listener        `json:"-" validate:"required"`
+		Start     int                 `json:"start"`
+		End       int                 `json:"end" validate:"gtefield=Start"`
+		Step      int                 `json:"step" validate:"gte=1"`
+		ChunkSize int                 `json:"chunkSize" validate:"gte=1"` // Frames per chunk.
+		Opts      RenderOpts          `json:"opts"`                       // Render options shared by every chunk.
+		EventChan chan<- BlenderEvent `json:"-"`                          // Optional, receives chunk and worker events.
+	}
+
+	CoordinateResult struct {
+		Chunks []Chunk `json:"chunks"`
+	}
+
+	WorkOpts struct {
+		Name        string              `json:"name" validate:"required"`
+		Coordinator string              `json:"coordinator" validate:"required,url"`
+		BlendFile   *BlendFile          `json:"blendFile" validate:"required"`
+		Devices     []RenderDevice      `json:"devices,omitempty" validate:"omitempty,unique,onegpu,dive,oneof=cpu cuda optix hip oneapi metal"`
+		EventChan   chan<- BlenderEvent `json:"-"` // Optional, receives the events of every chunk rendered.
+	}
+
+	Coordinator interface {
+		// Coordinate hands out chunks of a frame range to workers until every chunk is rendered or has failed.
+		Coordinate(ctx context.Context, opts *CoordinateOpts) (*CoordinateResult, error)
+	}
+
+	Worker interface {
+		// Work renders chunks handed out by a coordinator until none are left.
+		Work(ctx context.Context, opts *WorkOpts) error
+	}
+)
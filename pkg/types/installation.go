@@ -2,12 +2,36 @@ package types
 
 import (
 	"context"
+	"time"
 
 	"github.com/rocketblend/rocketblend/pkg/reference"
 	"github.com/rocketblend/rocketblend/pkg/semver"
 )
 
+const (
+	InstallationPhaseDownloading InstallationPhase = "downloading"
+	InstallationPhaseVerifying   InstallationPhase = "verifying"
+	InstallationPhaseExtracting  InstallationPhase = "extracting"
+	InstallationPhaseComplete    InstallationPhase = "complete"
+)
+
 type (
+	// InstallationPhase is the step an installation that is being fetched is at.
+	InstallationPhase string
+
+	// InstallationProgress reports on an installation that is being fetched. Installations that already exist
+	// are not reported.
+	InstallationProgress struct {
+		Reference reference.Reference `json:"reference"`
+		Phase     InstallationPhase   `json:"phase"`
+		Current   int64               `json:"current"`           // Bytes downloaded, or processed while extracting.
+		Total     int64               `json:"total"`             // Size of the artifact in bytes, -1 if unknown.
+		Speed     float64             `json:"speed"`             // Bytes per second.
+		ETA       time.Duration       `json:"eta"`               // Time left to download, zero if unknown.
+		Attempt   int                 `json:"attempt,omitempty"` // Download attempt, starting at 1.
+		Entries   int64               `json:"entries,omitempty"` // Entries extracted.
+	}
+
 	Installation struct {
 		Path    string          `json:"path" validate:"omitempty,filepath"`
 		Type    PackageType     `json:"type" validate:"required,oneof=build addon"`
@@ -16,13 +40,16 @@ type (
 	}
 
 	GetInstallationsOpts struct {
-		Dependencies []*Dependency `json:"dependencies"`
-		Fetch        bool          `json:"fetch"`
-		// Progress     chan<- Progress `json:"-"`
+		Dependencies   []*Dependency                          `json:"dependencies"`
+		Locks          map[reference.Reference]*LockedPackage `json:"locks,omitempty"` // Pins dependencies to locked packages and artifacts.
+		Fetch          bool                                   `json:"fetch"`
+		RequireDigests bool                                   `json:"requireDigests"` // Refuse to download artifacts whose source declares no digest.
+		Progress       chan<- InstallationProgress            `json:"-"`              // Optional, receives updates while fetching.
 	}
 
 	GetInstallationsResult struct {
-		Installations map[reference.Reference]*Installation `json:"installations"`
+		Installations map[reference.Reference]*Installation  `json:"installations"`
+		Locks         map[reference.Reference]*LockedPackage `json:"locks"`
 	}
 
 	RemoveInstallationsOpts struct {
@@ -32,5 +59,6 @@ type (
 	InstallationRepository interface {
 		GetInstallations(ctx context.Context, opts *GetInstallationsOpts) (*GetInstallationsResult, error)
 		RemoveInstallations(ctx context.Context, opts *RemoveInstallationsOpts) error
+		PruneInstallations(ctx context.Context, opts *PruneOpts) (*PruneResult, error)
 	}
 )
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"context"
+
+	"github.com/rocketblend/rocketblend/pkg/reference"
+)
+
+const (
+	// LibraryIndexFileName lists the packages of a library served by a static file server.
+	LibraryIndexFileName = "index.json"
+
+	PackageSourceGit   PackageSourceType = "git"
+	PackageSourceHTTP  PackageSourceType = "http"
+	PackageSourceLocal PackageSourceType = "local"
+)
+
+type (
+	PackageSourceType string
+
+	// PackageSourceConfig configures where the libraries under a reference prefix are fetched from.
+	PackageSourceConfig struct {
+		Type PackageSourceType `mapstructure:"type" json:"type" validate:"required,oneof=git http local"`
+		URL  string            `mapstructure:"url" json:"url" validate:"required"` // Repository URL, index base URL or directory.
+	}
+
+	// LibraryIndex is served at the root of a static library and lists the package paths it contains,
+	// relative to the root, e.g. "builds/blender/4.2.2".
+	LibraryIndex struct {
+		Packages []string `json:"packages" validate:"omitempty,dive,required"`
+	}
+
+	SyncLibraryOpts struct {
+		Reference reference.Reference `json:"reference" validate:"required"` // Reference that triggered the sync, used for reporting.
+		Library   string              `json:"library" validate:"required"`   // Library the reference belongs to, e.g. "github.com/org/library".
+		Path      string              `json:"path" validate:"required"`      // Local directory holding the library.
+		Update    bool                `json:"update"`                        // Fetch the latest changes even if the library exists locally.
+	}
+
+	SyncLibraryResult struct {
+		Path string `json:"path"` // Directory to read the library from, which is not always the requested path.
+	}
+
+	LibraryRevisionOpts struct {
+		Path string `json:"path" validate:"required"`
+	}
+
+	ReadLibraryFileOpts struct {
+		Reference reference.Reference `json:"reference" validate:"required"`
+		Path      string              `json:"path" validate:"required"`
+		Revision  string              `json:"revision" validate:"required"`
+		File      string              `json:"file" validate:"required"` // Slash separated path relative to the library root.
+	}
+
+	// PackageSource fetches package libraries into the local package directory.
+	PackageSource interface {
+		// Sync makes the library available locally, updating it if requested.
+		Sync(ctx context.Context, opts *SyncLibraryOpts) (*SyncLibraryResult, error)
+		// Revision identifies the current state of a local library.
+		Revision(ctx context.Context, opts *LibraryRevisionOpts) (string, error)
+		// ReadFile reads a file of a library as it was at a revision.
+		ReadFile(ctx context.Context, opts *ReadLibraryFileOpts) ([]byte, error)
+	}
+)
@@ -0,0 +1,21 @@
+package types
+
+import "github.com/rocketblend/rocketblend/pkg/reference"
+
+const ProfileLockFileName = "profile.lock"
+
+type (
+	// LockedPackage pins a dependency to an exact package definition and artifact.
+	LockedPackage struct {
+		Reference reference.Reference `json:"reference" validate:"required"`
+		Type      PackageType         `json:"type" validate:"required,oneof=build addon"`
+		Commit    string              `json:"commit,omitempty"` // Commit of the library repository the definition was read from.
+		Digest    string              `json:"digest" validate:"required"`
+		Artifact  string              `json:"artifact,omitempty"` // Digest of the downloaded artifact, empty for bundled packages.
+	}
+
+	// ProfileLock records the exact package definitions a profile was installed with.
+	ProfileLock struct {
+		Packages []*LockedPackage `json:"packages" validate:"omitempty,dive,required"`
+	}
+)
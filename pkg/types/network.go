@@ -0,0 +1,30 @@
+package types
+
+const CredentialsFileName = "credentials.json"
+
+type (
+	// NetworkConfig controls how rocketblend reaches remote hosts, for both downloads and git.
+	NetworkConfig struct {
+		Proxy           string `mapstructure:"proxy" validate:"omitempty,url"` // Proxy for every request, the HTTP(S)_PROXY environment variables are used when empty.
+		CABundle        string `mapstructure:"caBundle"`                       // PEM file of certificate authorities trusted in addition to the system ones.
+		CredentialsFile string `mapstructure:"credentialsFile"`                // Per host credentials, see Credentials.
+	}
+
+	// Credential authenticates requests to a host. A token is sent as a bearer token, or to git as the password
+	// of basic authentication along with the username if set. Otherwise the username and password are sent with
+	// basic authentication. Secrets can be named by environment variable instead of being
+	// written to the file.
+	Credential struct {
+		Token       string `json:"token,omitempty"`
+		TokenEnv    string `json:"tokenEnv,omitempty"`
+		Username    string `json:"username,omitempty"`
+		Password    string `json:"password,omitempty"`
+		PasswordEnv string `json:"passwordEnv,omitempty"`
+	}
+
+	// Credentials is the content of the credentials file, keyed by host name with an optional port, e.g.
+	// "git.studio.internal" or "files.studio.internal:8443". Credentials are never stored in profiles.
+	Credentials struct {
+		Hosts map[string]*Credential `json:"hosts" validate:"omitempty,dive,required"`
+	}
+)
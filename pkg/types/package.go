@@ -18,26 +18,51 @@ type (
 	PackageType string
 
 	Source struct {
-		Resource string   `json:"resource,omitempty"`
-		URI      *URI     `json:"uri,omitempty"`
-		Platform Platform `json:"platform,omitempty" validate:"omitempty,oneof=any windows linux macos/intel macos/apple"`
+		Resource        string   `json:"resource,omitempty"`
+		StripComponents int      `json:"stripComponents,omitempty" validate:"omitempty,min=0,max=16"` // Single top-level directories of the extracted artifact to unwrap before SubPath and Resource.
+		SubPath         string   `json:"subPath,omitempty"`                                           // Directory of the extracted artifact that Resource is relative to.
+		URI             *URI     `json:"uri,omitempty"`
+		Mirrors         []*URI   `json:"mirrors,omitempty" validate:"omitempty,dive,required"` // Alternative URIs for the same file, tried in order.
+		SHA256          string   `json:"sha256,omitempty" validate:"omitempty,hexadecimal,len=64"`
+		SHA512          string   `json:"sha512,omitempty" validate:"omitempty,hexadecimal,len=128"`
+		Platform        Platform `json:"platform,omitempty" validate:"omitempty,oneof=any windows linux macos/intel macos/apple"`
+	}
+
+	// PackageDependency is a package required by another package. The reference may be a version range.
+	PackageDependency struct {
+		Reference reference.Reference `json:"reference" validate:"required"`
+		Type      PackageType         `json:"type,omitempty" validate:"omitempty,oneof=build addon"`
 	}
 
 	Package struct {
-		Spec    *semver.Version `json:"spec,omitempty"`
-		Type    PackageType     `json:"type" validate:"required,oneof=build addon"`
-		Name    string          `json:"name,omitempty"`
-		Version *semver.Version `json:"version,omitempty"`
-		Sources []*Source       `json:"sources" validate:"omitempty,dive,required"`
+		Spec         *semver.Version      `json:"spec,omitempty"`
+		Type         PackageType          `json:"type" validate:"required,oneof=build addon"`
+		Name         string               `json:"name,omitempty"`
+		Version      *semver.Version      `json:"version,omitempty"`
+		Sources      []*Source            `json:"sources" validate:"omitempty,dive,required"`
+		Dependencies []*PackageDependency `json:"dependencies,omitempty" validate:"omitempty,dive,required"`
+		Builds       string               `json:"builds,omitempty"` // Version range of the builds an addon supports, e.g. ">=4.1 <4.3".
 	}
 
 	GetPackagesOpts struct {
+		References []reference.Reference                  `json:"references" validate:"required"`
+		Locks      map[reference.Reference]*LockedPackage `json:"locks,omitempty"` // Pins references to locked package definitions.
+		Update     bool                                   `json:"update"`
+	}
+
+	GetPackagesResult struct {
+		Packs   map[reference.Reference]*Package       `json:"packs"`
+		Locks   map[reference.Reference]*LockedPackage `json:"locks"`
+		Signers map[reference.Reference]*Signer        `json:"signers,omitempty"` // Only set for verified packages.
+	}
+
+	ResolveReferencesOpts struct {
 		References []reference.Reference `json:"references" validate:"required"`
 		Update     bool                  `json:"update"`
 	}
 
-	GetPackagesResult struct {
-		Packs map[reference.Reference]*Package `json:"packs"`
+	ResolveReferencesResult struct {
+		References map[reference.Reference]reference.Reference `json:"references"` // Maps each reference to a concrete one.
 	}
 
 	RemovePackagesOpts struct {
@@ -50,8 +75,10 @@ type (
 
 	PackageRepository interface {
 		GetPackages(ctx context.Context, opts *GetPackagesOpts) (*GetPackagesResult, error)
+		ResolveReferences(ctx context.Context, opts *ResolveReferencesOpts) (*ResolveReferencesResult, error)
 		RemovePackages(ctx context.Context, opts *RemovePackagesOpts) error
 		InsertPackages(ctx context.Context, opts *InsertPackagesOpts) error
+		PrunePackages(ctx context.Context, opts *PruneOpts) (*PruneResult, error)
 	}
 )
 
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"path"
+
 	"github.com/rocketblend/rocketblend/pkg/reference"
 	"github.com/rocketblend/rocketblend/pkg/semver"
 )
@@ -12,14 +14,17 @@ const (
 
 type (
 	Dependency struct {
-		Reference reference.Reference `json:"reference" validate:"required"`
-		Type      PackageType         `json:"type,omitempty" validate:"omitempty,oneof=build addon"`
+		Reference  reference.Reference `json:"reference" validate:"required"`
+		Type       PackageType         `json:"type,omitempty" validate:"omitempty,oneof=build addon"`
+		Constraint string              `json:"constraint,omitempty"` // Version range the reference was resolved from.
 	}
 
 	Profile struct {
-		Spec         semver.Version `json:"spec,omitempty"`
-		Dependencies []*Dependency  `json:"dependencies,omitempty" validate:"omitempty,dive,required"`
-		Strict       bool           `json:"strict,omitempty"`
+		Spec           semver.Version `json:"spec,omitempty"`
+		Dependencies   []*Dependency  `json:"dependencies,omitempty" validate:"omitempty,dive,required"`
+		Strict         bool           `json:"strict,omitempty"`         // Only load the profile's add-ons and require compatible builds.
+		RequireDigests bool           `json:"requireDigests,omitempty"` // Refuse to download artifacts whose source declares no digest.
+		Lock           *ProfileLock   `json:"-"`                        // Loaded from and saved to the profile lock file.
 		// ARGS         []string       `json:"args,omitempty"`
 	}
 )
@@ -44,13 +49,51 @@ func (p *Profile) AddDependencies(deps ...*Dependency) {
 	p.Dependencies = append(deps, p.Dependencies...)
 }
 
+// RemoveDependencies removes the given dependencies from the profile. A ranged reference removes every
+// dependency whose version satisfies the range.
 func (p *Profile) RemoveDependencies(deps ...*Dependency) {
 	for _, dep := range deps {
-		for i, d := range p.Dependencies {
-			if d.Reference == dep.Reference {
-				p.Dependencies = append(p.Dependencies[:i], p.Dependencies[i+1:]...)
-				break
+		remaining := make([]*Dependency, 0, len(p.Dependencies))
+		for _, d := range p.Dependencies {
+			if !d.matches(dep.Reference) {
+				remaining = append(remaining, d)
+				continue
+			}
+
+			if p.Lock != nil {
+				for i, l := range p.Lock.Packages {
+					if l.Reference == d.Reference {
+						p.Lock.Packages = append(p.Lock.Packages[:i], p.Lock.Packages[i+1:]...)
+						break
+					}
+				}
 			}
 		}
+
+		p.Dependencies = remaining
+	}
+}
+
+// matches reports whether the dependency is the given reference or, for a ranged reference, a version within it.
+func (d *Dependency) matches(ref reference.Reference) bool {
+	if !ref.IsRange() {
+		return d.Reference == ref
 	}
+
+	base, rawConstraint := ref.Range()
+	if d.Reference.Parent() != base {
+		return false
+	}
+
+	constraint, err := semver.ParseConstraint(rawConstraint)
+	if err != nil {
+		return false
+	}
+
+	version, err := semver.Parse(path.Base(string(d.Reference)))
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(*version)
 }
@@ -1,6 +1,24 @@
 package types
 
+import "github.com/rocketblend/rocketblend/pkg/reference"
+
 type (
+	PruneOpts struct {
+		Keep   []reference.Reference `json:"keep"`   // References still used by a project.
+		DryRun bool                  `json:"dryRun"` // Report what would be removed without removing anything.
+	}
+
+	PrunedPath struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"` // Size on disk in bytes.
+	}
+
+	PruneResult struct {
+		Removed []*PrunedPath `json:"removed"`
+		Skipped []*PrunedPath `json:"skipped,omitempty"` // Unused, but locked by another process.
+		Size    int64         `json:"size"`              // Total size of the removed paths in bytes.
+	}
+
 	Repository interface {
 		PackageRepository
 		InstallationRepository
@@ -0,0 +1,27 @@
+package types
+
+import "context"
+
+const SignatureFileExtension = ".sig"
+
+type (
+	// Signer identifies the trusted key that produced a valid signature.
+	Signer struct {
+		Name        string `json:"name"`
+		Algorithm   string `json:"algorithm"`
+		Fingerprint string `json:"fingerprint"`
+	}
+
+	VerifyOpts struct {
+		Data      []byte `json:"-" validate:"required"`
+		Signature []byte `json:"-" validate:"required"` // Base64 encoded detached signature.
+	}
+
+	VerifyResult struct {
+		Signer *Signer `json:"signer"`
+	}
+
+	Verifier interface {
+		Verify(ctx context.Context, opts *VerifyOpts) (*VerifyResult, error)
+	}
+)
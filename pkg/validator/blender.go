@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -36,3 +37,93 @@ func ValidateOneBuild(fl validator.FieldLevel) bool {
 
 	return buildCount == 1
 }
+
+// ValidateOneGPU checks that render devices combine the CPU with at most one other device type
+func ValidateOneGPU(fl validator.FieldLevel) bool {
+	devices, ok := fl.Field().Interface().([]types.RenderDevice)
+	if !ok {
+		return false
+	}
+
+	gpuCount := 0
+	for _, device := range devices {
+		if device != types.DeviceCPU {
+			gpuCount++
+		}
+	}
+
+	return gpuCount <= 1
+}
+
+var (
+	exrColorDepths    = []string{"16", "32"}
+	exrCodecs         = []string{"none", "pxr24", "zip", "piz", "rle", "zips", "b44", "b44a", "dwaa", "dwab"}
+	pngColorDepths    = []string{"8", "16"}
+	ffmpegColorDepths = []string{"8", "10", "12"}
+	ffmpegContainers  = []string{"mpeg1", "mpeg2", "mpeg4", "avi", "quicktime", "dv", "ogg", "mkv", "flash", "webm"}
+	ffmpegCodecs      = []string{"none", "dnxhd", "dv", "ffv1", "flash", "h264", "h265", "huffyuv", "mpeg1", "mpeg2", "mpeg4", "png", "prores", "qtrle", "theora", "webm", "av1"}
+)
+
+// ValidateOutputSettings checks that the output settings of a render apply to its format
+func ValidateOutputSettings(sl validator.StructLevel) {
+	opts := sl.Current().Interface().(types.RenderOpts)
+	settings := opts.OutputSettings
+	if settings == nil {
+		return
+	}
+
+	format := strings.ToUpper(opts.Format)
+	report := func(field interface{}, name string, tag string) {
+		sl.ReportError(field, "OutputSettings."+name, name, tag, format)
+	}
+
+	check := func(field string, name string, allowed []string) {
+		if field == "" {
+			return
+		}
+
+		if allowed == nil {
+			report(field, name, "unsupportedformat")
+			return
+		}
+
+		if !slices.Contains(allowed, strings.ToLower(field)) {
+			report(field, name, "oneof")
+		}
+	}
+
+	switch format {
+	case "OPEN_EXR", "OPEN_EXR_MULTILAYER":
+		check(settings.ColorDepth, "ColorDepth", exrColorDepths)
+		check(settings.Codec, "Codec", exrCodecs)
+		check(settings.Container, "Container", nil)
+	case "PNG":
+		check(settings.ColorDepth, "ColorDepth", pngColorDepths)
+		check(settings.Codec, "Codec", nil)
+		check(settings.Container, "Container", nil)
+	case "FFMPEG":
+		check(settings.ColorDepth, "ColorDepth", ffmpegColorDepths)
+		check(settings.Codec, "Codec", ffmpegCodecs)
+		check(settings.Container, "Container", ffmpegContainers)
+	default:
+		check(settings.ColorDepth, "ColorDepth", nil)
+		check(settings.Codec, "Codec", nil)
+		check(settings.Container, "Container", nil)
+	}
+
+	if settings.Compression != nil {
+		if format != "PNG" {
+			report(*settings.Compression, "Compression", "unsupportedformat")
+		} else if *settings.Compression < 0 || *settings.Compression > 100 {
+			report(*settings.Compression, "Compression", "range")
+		}
+	}
+
+	if settings.Bitrate != 0 {
+		if format != "FFMPEG" {
+			report(settings.Bitrate, "Bitrate", "unsupportedformat")
+		} else if settings.Bitrate < 0 {
+			report(settings.Bitrate, "Bitrate", "gte")
+		}
+	}
+}
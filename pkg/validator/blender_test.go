@@ -0,0 +1,77 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func TestValidateOutputSettings(t *testing.T) {
+	compression := func(value int) *int {
+		return &value
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		settings *types.OutputSettings
+		err      bool
+	}{
+		{"none", "PNG", nil, false},
+		{"multilayer exr", "OPEN_EXR_MULTILAYER", &types.OutputSettings{ColorDepth: "32", Codec: "dwaa", ViewTransform: "AgX"}, false},
+		{"exr depth", "OPEN_EXR", &types.OutputSettings{ColorDepth: "8"}, true},
+		{"exr codec", "OPEN_EXR", &types.OutputSettings{Codec: "h264"}, true},
+		{"exr compression", "OPEN_EXR", &types.OutputSettings{Compression: compression(15)}, true},
+		{"png", "png", &types.OutputSettings{ColorDepth: "16", Compression: compression(0)}, false},
+		{"png compression", "PNG", &types.OutputSettings{Compression: compression(101)}, true},
+		{"png codec", "PNG", &types.OutputSettings{Codec: "dwaa"}, true},
+		{"ffmpeg", "FFMPEG", &types.OutputSettings{Container: "mpeg4", Codec: "h264", Bitrate: 12000}, false},
+		{"ffmpeg container", "FFMPEG", &types.OutputSettings{Container: "mp4"}, true},
+		{"ffmpeg bitrate", "FFMPEG", &types.OutputSettings{Bitrate: -1}, true},
+		{"png bitrate", "PNG", &types.OutputSettings{Bitrate: 12000}, true},
+		{"jpeg view transform", "JPEG", &types.OutputSettings{ViewTransform: "Standard"}, false},
+		{"jpeg depth", "JPEG", &types.OutputSettings{ColorDepth: "8"}, true},
+	}
+
+	v := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := v.Validate(&types.RenderOpts{
+				Format:         test.format,
+				OutputSettings: test.settings,
+			})
+			if err != nil && !test.err {
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			}
+
+			if err == nil && test.err {
+				t.Errorf("Validate() did not return an error as expected")
+			}
+		})
+	}
+}
+
+func TestValidateRenderDevices(t *testing.T) {
+	tests := []struct {
+		devices []types.RenderDevice
+		err     bool
+	}{
+		{[]types.RenderDevice{types.DeviceCPU}, false},
+		{[]types.RenderDevice{types.DeviceOptiX, types.DeviceCPU}, false},
+		{[]types.RenderDevice{types.DeviceOptiX, types.DeviceCUDA}, true},
+		{[]types.RenderDevice{types.DeviceCPU, types.DeviceCPU}, true},
+		{[]types.RenderDevice{"vulkan"}, true},
+	}
+
+	v := New()
+	for _, test := range tests {
+		err := v.Validate(&types.RenderOpts{Devices: test.devices})
+		if err != nil && !test.err {
+			t.Errorf("Validate(%v) returned unexpected error: %v", test.devices, err)
+		}
+
+		if err == nil && test.err {
+			t.Errorf("Validate(%v) did not return an error as expected", test.devices)
+		}
+	}
+}
@@ -18,8 +18,10 @@ func New() *Validator {
 
 	validate.RegisterValidation("blendfile", ValidateBlendFile)
 	validate.RegisterValidation("onebuild", ValidateOneBuild)
+	validate.RegisterValidation("onegpu", ValidateOneGPU)
 
 	validate.RegisterStructValidation(ValidateUniquePlatforms, types.Package{})
+	validate.RegisterStructValidation(ValidateOutputSettings, types.RenderOpts{})
 
 	return &Validator{
 		validator: validate,
@@ -0,0 +1,208 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rocketblend/rocketblend/pkg/logger"
+	"github.com/rocketblend/rocketblend/pkg/types"
+	"github.com/rocketblend/rocketblend/pkg/validator"
+)
+
+const (
+	AlgorithmECDSA   = "ecdsa"
+	AlgorithmED25519 = "ed25519"
+)
+
+type (
+	// PublicKey is a trusted key used to verify detached signatures.
+	PublicKey struct {
+		Name        string
+		Algorithm   string
+		Fingerprint string
+		key         interface{}
+	}
+
+	Options struct {
+		Logger    types.Logger
+		Validator types.Validator
+		Keys      []*PublicKey
+		KeyFiles  []string
+	}
+
+	Option func(*Options)
+
+	// Verifier verifies cosign-style detached signatures against a set of trusted keys, fully offline.
+	Verifier struct {
+		logger    types.Logger
+		validator types.Validator
+		keys      []*PublicKey
+	}
+)
+
+func WithLogger(logger types.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithValidator(validator types.Validator) Option {
+	return func(o *Options) {
+		o.Validator = validator
+	}
+}
+
+// WithKeys adds already parsed trusted keys.
+func WithKeys(keys ...*PublicKey) Option {
+	return func(o *Options) {
+		o.Keys = append(o.Keys, keys...)
+	}
+}
+
+// WithKeyFiles adds trusted keys from PEM encoded public key files.
+func WithKeyFiles(paths ...string) Option {
+	return func(o *Options) {
+		o.KeyFiles = append(o.KeyFiles, paths...)
+	}
+}
+
+func New(opts ...Option) (*Verifier, error) {
+	options := &Options{
+		Logger:    logger.NoOp(),
+		Validator: validator.New(),
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.Validator == nil {
+		return nil, errors.New("validator is nil")
+	}
+
+	keys := append([]*PublicKey{}, options.Keys...)
+	for _, path := range options.KeyFiles {
+		key, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	options.Logger.Debug("initialising verifier", map[string]interface{}{
+		"keys": len(keys),
+	})
+
+	return &Verifier{
+		logger:    options.Logger,
+		validator: options.Validator,
+		keys:      keys,
+	}, nil
+}
+
+// Verify checks the signature against each trusted key and returns the first matching signer.
+func (v *Verifier) Verify(ctx context.Context, opts *types.VerifyOpts) (*types.VerifyResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := v.validator.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("%w: no trusted keys configured", types.ErrInvalidSignature)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(opts.Signature)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64 encoded", types.ErrInvalidSignature)
+	}
+
+	for _, key := range v.keys {
+		if key.verify(opts.Data, signature) {
+			v.logger.Debug("signature verified", map[string]interface{}{
+				"key":         key.Name,
+				"fingerprint": key.Fingerprint,
+			})
+
+			return &types.VerifyResult{
+				Signer: &types.Signer{
+					Name:        key.Name,
+					Algorithm:   key.Algorithm,
+					Fingerprint: key.Fingerprint,
+				},
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no trusted key matches", types.ErrInvalidSignature)
+}
+
+// LoadPublicKey reads a PEM encoded public key, naming it after the file.
+func LoadPublicKey(path string) (*PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ParsePublicKey(name, data)
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key. Only ECDSA and Ed25519 keys are supported.
+func ParsePublicKey(name string, data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key %q: no PEM data found", name)
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %q: %w", name, err)
+	}
+
+	var algorithm string
+	switch parsed.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = AlgorithmECDSA
+	case ed25519.PublicKey:
+		algorithm = AlgorithmED25519
+	default:
+		return nil, fmt.Errorf("invalid public key %q: unsupported key type %T", name, parsed)
+	}
+
+	sum := sha256.Sum256(block.Bytes)
+	return &PublicKey{
+		Name:        name,
+		Algorithm:   algorithm,
+		Fingerprint: "sha256:" + hex.EncodeToString(sum[:]),
+		key:         parsed,
+	}, nil
+}
+
+// verify checks a raw signature. ECDSA signatures are ASN.1 encoded over the sha256 digest of the data,
+// matching cosign sign-blob; Ed25519 signatures are over the data itself.
+func (k *PublicKey) verify(data []byte, signature []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/rocketblend/rocketblend/pkg/types"
+)
+
+func newTestKey(t *testing.T, name string, public crypto.PublicKey) *PublicKey {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	key, err := ParsePublicKey(name, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+
+	return key
+}
+
+func TestVerify(t *testing.T) {
+	data := []byte(`{"spec":"v1","type":"build"}`)
+
+	ecdsaPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256(data)
+	ecdsaSignature, err := ecdsa.SignASN1(rand.Reader, ecdsaPrivate, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Public, ed25519Private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Signature := ed25519.Sign(ed25519Private, data)
+
+	verifier, err := New(WithKeys(
+		newTestKey(t, "ecdsa", &ecdsaPrivate.PublicKey),
+		newTestKey(t, "ed25519", ed25519Public),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		signature []byte
+		want      string
+		err       error
+	}{
+		{"ecdsa", data, ecdsaSignature, "ecdsa", nil},
+		{"ed25519", data, ed25519Signature, "ed25519", nil},
+		{"tampered", []byte(`{"spec":"v1","type":"addon"}`), ecdsaSignature, "", types.ErrInvalidSignature},
+	}
+
+	for _, test := range tests {
+		result, err := verifier.Verify(context.Background(), &types.VerifyOpts{
+			Data:      test.data,
+			Signature: []byte(base64.StdEncoding.EncodeToString(test.signature) + "\n"),
+		})
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: Verify() error = %v, want %v", test.name, err, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Verify() unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if result.Signer.Name != test.want {
+			t.Errorf("%s: Verify() signer = %q, want %q", test.name, result.Signer.Name, test.want)
+		}
+	}
+}